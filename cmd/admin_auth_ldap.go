@@ -45,10 +45,18 @@ var (
 			Name:  "host",
 			Usage: "The address where the LDAP server can be reached.",
 		},
+		cli.StringSliceFlag{
+			Name:  "failover-host",
+			Usage: "An additional address of the LDAP server tried in order when the host cannot be reached, may be given multiple times.",
+		},
 		cli.IntFlag{
 			Name:  "port",
 			Usage: "The port to use when connecting to the LDAP server.",
 		},
+		cli.IntFlag{
+			Name:  "timeout",
+			Usage: "Seconds to wait for the LDAP server before giving up.",
+		},
 		cli.StringFlag{
 			Name:  "user-search-base",
 			Usage: "The LDAP base at which user accounts will be searched for.",
@@ -61,6 +69,38 @@ var (
 			Name:  "admin-filter",
 			Usage: "An LDAP filter specifying if a user should be given administrator privileges.",
 		},
+		cli.StringSliceFlag{
+			Name:  "additional-admin-filter",
+			Usage: "An additional LDAP filter specifying if a user should be given administrator privileges, may be given multiple times.",
+		},
+		cli.StringFlag{
+			Name:  "group-attribute-name",
+			Usage: "The attribute of the groups referenced by the admin filters containing their display name.",
+		},
+		cli.IntFlag{
+			Name:  "search-retries",
+			Usage: "Number of times a search failing with a transient error is retried.",
+		},
+		cli.DurationFlag{
+			Name:  "search-cache-ttl",
+			Usage: "Time the results of identical searches are reused, e.g. 30s. 0 disables caching.",
+		},
+		cli.StringFlag{
+			Name:  "member-group-filter",
+			Usage: "An LDAP filter specifying if a user is a member of the group allowed to sign in.",
+		},
+		cli.BoolFlag{
+			Name:  "require-group-membership",
+			Usage: "Reject users not matching the member group filter with an explicit error.",
+		},
+		cli.BoolFlag{
+			Name:  "nested-group-search",
+			Usage: "Also match users in groups nested in a group of the member group filter, following the memberOf attributes of group entries.",
+		},
+		cli.IntFlag{
+			Name:  "nested-group-max-depth",
+			Usage: "Maximum number of group levels followed by the nested group search, 5 if not set.",
+		},
 		cli.StringFlag{
 			Name:  "username-attribute",
 			Usage: "The attribute of the user’s LDAP record containing the user name.",
@@ -81,6 +121,14 @@ var (
 			Name:  "public-ssh-key-attribute",
 			Usage: "The attribute of the user’s LDAP record containing the user’s public ssh key.",
 		},
+		cli.StringFlag{
+			Name:  "language-attribute",
+			Usage: "The attribute of the user’s LDAP record containing the user’s preferred language.",
+		},
+		cli.StringFlag{
+			Name:  "external-id-attribute",
+			Usage: "The attribute of the user’s LDAP record containing a unique identifier which never changes.",
+		},
 	}
 
 	ldapBindDnCLIFlags = append(commonLdapCLIFlags,
@@ -100,9 +148,29 @@ var (
 			Name:  "synchronize-users",
 			Usage: "Enable user synchronization.",
 		},
+		cli.StringFlag{
+			Name:  "changed-attribute",
+			Usage: "The attribute of the user’s LDAP record containing its last modification, used to only synchronize changed users.",
+		},
+		cli.IntFlag{
+			Name:  "full-sync-interval",
+			Usage: "Number of incremental synchronizations after which a full synchronization runs.",
+		},
 		cli.UintFlag{
 			Name:  "page-size",
 			Usage: "Search page size.",
+		},
+		cli.BoolFlag{
+			Name:  "sort-results",
+			Usage: "Ask the LDAP server to sort search results by user name.",
+		},
+		cli.IntFlag{
+			Name:  "pool-size",
+			Usage: "Number of idle bind DN connections kept for reuse.",
+		},
+		cli.StringSliceFlag{
+			Name:  "additional-user-search-base",
+			Usage: "An additional LDAP base searched in order for the user when the user search base has no single match, may be given multiple times.",
 		})
 
 	ldapSimpleAuthCLIFlags = append(commonLdapCLIFlags,
@@ -179,9 +247,15 @@ func parseLdapConfig(c *cli.Context, config *models.LDAPConfig) error {
 	if c.IsSet("host") {
 		config.Source.Host = c.String("host")
 	}
+	if c.IsSet("failover-host") {
+		config.Source.FailoverHosts = c.StringSlice("failover-host")
+	}
 	if c.IsSet("port") {
 		config.Source.Port = c.Int("port")
 	}
+	if c.IsSet("timeout") {
+		config.Source.Timeout = c.Int("timeout")
+	}
 	if c.IsSet("security-protocol") {
 		p, ok := findLdapSecurityProtocolByName(c.String("security-protocol"))
 		if !ok {
@@ -222,15 +296,60 @@ func parseLdapConfig(c *cli.Context, config *models.LDAPConfig) error {
 	if c.IsSet("public-ssh-key-attribute") {
 		config.Source.AttributeSSHPublicKey = c.String("public-ssh-key-attribute")
 	}
+	if c.IsSet("language-attribute") {
+		config.Source.AttributeLanguage = c.String("language-attribute")
+	}
+	if c.IsSet("external-id-attribute") {
+		config.Source.AttributeExternalID = c.String("external-id-attribute")
+	}
+	if c.IsSet("changed-attribute") {
+		config.Source.AttributeChanged = c.String("changed-attribute")
+	}
+	if c.IsSet("full-sync-interval") {
+		config.Source.FullSyncInterval = c.Int("full-sync-interval")
+	}
 	if c.IsSet("page-size") {
 		config.Source.SearchPageSize = uint32(c.Uint("page-size"))
 	}
+	if c.IsSet("sort-results") {
+		config.Source.SortResults = c.Bool("sort-results")
+	}
+	if c.IsSet("pool-size") {
+		config.Source.PoolSize = c.Int("pool-size")
+	}
+	if c.IsSet("additional-user-search-base") {
+		config.Source.UserBases = c.StringSlice("additional-user-search-base")
+	}
 	if c.IsSet("user-filter") {
 		config.Source.Filter = c.String("user-filter")
 	}
 	if c.IsSet("admin-filter") {
 		config.Source.AdminFilter = c.String("admin-filter")
 	}
+	if c.IsSet("additional-admin-filter") {
+		config.Source.AdminFilters = c.StringSlice("additional-admin-filter")
+	}
+	if c.IsSet("group-attribute-name") {
+		config.Source.GroupAttributeName = c.String("group-attribute-name")
+	}
+	if c.IsSet("search-retries") {
+		config.Source.SearchRetries = c.Int("search-retries")
+	}
+	if c.IsSet("search-cache-ttl") {
+		config.Source.SearchCacheTTL = c.Duration("search-cache-ttl")
+	}
+	if c.IsSet("member-group-filter") {
+		config.Source.MemberGroupFilter = c.String("member-group-filter")
+	}
+	if c.IsSet("require-group-membership") {
+		config.Source.RequireGroupMembership = c.Bool("require-group-membership")
+	}
+	if c.IsSet("nested-group-search") {
+		config.Source.NestedGroupSearch = c.Bool("nested-group-search")
+	}
+	if c.IsSet("nested-group-max-depth") {
+		config.Source.NestedGroupMaxDepth = c.Int("nested-group-max-depth")
+	}
 	return nil
 }
 
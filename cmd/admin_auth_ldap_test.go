@@ -35,10 +35,14 @@ func TestAddLdapBindDn(t *testing.T) {
 				"--security-protocol", "ldaps",
 				"--skip-tls-verify",
 				"--host", "ldap-bind-server full",
+				"--failover-host", "ldap-bind-server failover",
 				"--port", "9876",
 				"--user-search-base", "ou=Users,dc=full-domain-bind,dc=org",
+				"--additional-user-search-base", "ou=Contractors,dc=full-domain-bind,dc=org",
 				"--user-filter", "(memberOf=cn=user-group,ou=example,dc=full-domain-bind,dc=org)",
 				"--admin-filter", "(memberOf=cn=admin-group,ou=example,dc=full-domain-bind,dc=org)",
+				"--additional-admin-filter", "(memberOf=cn=git-admins,ou=example,dc=full-domain-bind,dc=org)",
+				"--additional-admin-filter", "(memberOf=cn=domain-admins,ou=example,dc=full-domain-bind,dc=org)",
 				"--username-attribute", "uid-bind full",
 				"--firstname-attribute", "givenName-bind full",
 				"--surname-attribute", "sn-bind full",
@@ -49,6 +53,8 @@ func TestAddLdapBindDn(t *testing.T) {
 				"--attributes-in-bind",
 				"--synchronize-users",
 				"--page-size", "99",
+				"--nested-group-search",
+				"--nested-group-max-depth", "3",
 			},
 			loginSource: &models.LoginSource{
 				Type:          models.LoginLDAP,
@@ -74,7 +80,15 @@ func TestAddLdapBindDn(t *testing.T) {
 						SearchPageSize:        99,
 						Filter:                "(memberOf=cn=user-group,ou=example,dc=full-domain-bind,dc=org)",
 						AdminFilter:           "(memberOf=cn=admin-group,ou=example,dc=full-domain-bind,dc=org)",
-						Enabled:               true,
+						AdminFilters: []string{
+							"(memberOf=cn=git-admins,ou=example,dc=full-domain-bind,dc=org)",
+							"(memberOf=cn=domain-admins,ou=example,dc=full-domain-bind,dc=org)",
+						},
+						UserBases:           []string{"ou=Contractors,dc=full-domain-bind,dc=org"},
+						FailoverHosts:       []string{"ldap-bind-server failover"},
+						NestedGroupSearch:   true,
+						NestedGroupMaxDepth: 3,
+						Enabled:             true,
 					},
 				},
 			},
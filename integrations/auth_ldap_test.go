@@ -187,6 +187,53 @@ func TestLDAPUserSync(t *testing.T) {
 	}
 }
 
+func TestLDAPUserSyncDeactivatesRemovedUsers(t *testing.T) {
+	if skipLDAPTests() {
+		t.Skip()
+		return
+	}
+	defer prepareTestEnv(t)()
+	addAuthSourceLDAP(t, "")
+
+	source := getLDAPSource(t)
+	source.LDAP().AttributeChanged = "modifyTimestamp"
+	source.LDAP().FullSyncInterval = 1
+	assert.NoError(t, models.UpdateSource(source))
+
+	// the first synchronization is a full one
+	models.SyncExternalUsers(context.Background())
+	u := gitLDAPUsers[0]
+	models.AssertExistsAndLoadBean(t, &models.User{Name: u.UserName, IsActive: true})
+
+	// move the user out of the filter, as if it was removed from the directory
+	source = getLDAPSource(t)
+	assert.True(t, source.LDAP().IsIncrementalSync())
+	source.LDAP().Filter = "(&(objectClass=inetOrgPerson)(memberOf=cn=git,ou=people,dc=planetexpress,dc=com)(!(uid=" + u.UserName + "))(uid=%s))"
+	assert.NoError(t, models.UpdateSource(source))
+
+	// an incremental synchronization cannot tell the user is gone
+	models.SyncExternalUsers(context.Background())
+	models.AssertExistsAndLoadBean(t, &models.User{Name: u.UserName, IsActive: true})
+
+	// but the following full synchronization deactivates it
+	assert.False(t, getLDAPSource(t).LDAP().IsIncrementalSync())
+	models.SyncExternalUsers(context.Background())
+	user := models.AssertExistsAndLoadBean(t, &models.User{Name: u.UserName}).(*models.User)
+	assert.False(t, user.IsActive)
+}
+
+func getLDAPSource(t *testing.T) *models.LoginSource {
+	sources, err := models.LoginSources()
+	assert.NoError(t, err)
+	for _, source := range sources {
+		if source.Name == "ldap" {
+			return source
+		}
+	}
+	assert.FailNow(t, "LDAP source not found")
+	return nil
+}
+
 func TestLDAPUserSigninFailed(t *testing.T) {
 	if skipLDAPTests() {
 		t.Skip()
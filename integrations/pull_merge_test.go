@@ -17,12 +17,14 @@ import (
 	"time"
 
 	"code.gitea.io/gitea/models"
+	"code.gitea.io/gitea/modules/auth"
 	"code.gitea.io/gitea/modules/git"
 	api "code.gitea.io/gitea/modules/structs"
 	"code.gitea.io/gitea/modules/test"
 	"code.gitea.io/gitea/services/pull"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/unknwon/com"
 	"github.com/unknwon/i18n"
 )
 
@@ -152,6 +154,112 @@ func TestPullSquash(t *testing.T) {
 	})
 }
 
+func TestAPIPullSquashIdentity(t *testing.T) {
+	onGiteaRun(t, func(t *testing.T, giteaURL *url.URL) {
+		session := loginUser(t, "user1")
+		testRepoFork(t, session, "user2", "repo1", "user1", "repo1")
+		testEditFile(t, session, "user1", "repo1", "master", "README.md", "Hello, World (Edited)\n")
+
+		resp := testPullCreate(t, session, "user1", "repo1", "master", "This is a pull title")
+		elem := strings.Split(test.RedirectURL(resp), "/")
+		assert.EqualValues(t, "pulls", elem[3])
+
+		baseRepo := models.AssertExistsAndLoadBean(t, &models.Repository{OwnerName: "user2", Name: "repo1"}).(*models.Repository)
+		pr := models.AssertExistsAndLoadBean(t, &models.PullRequest{BaseRepoID: baseRepo.ID, Index: com.StrTo(elem[4]).MustInt64()}).(*models.PullRequest)
+
+		ownerSession := loginUser(t, "user2")
+		token := getTokenForLoggedInUser(t, ownerSession)
+		req := NewRequestWithJSON(t, http.MethodPost, fmt.Sprintf("/api/v1/repos/user2/repo1/pulls/%d/merge?token=%s", pr.Index, token), map[string]string{
+			"Do":                     string(models.MergeStyleSquash),
+			"squash_author_name":     "Squash Author",
+			"squash_author_email":    "squash-author@example.com",
+			"squash_committer_name":  "Someone Else",
+			"squash_committer_email": "someone-else@example.com",
+		})
+		ownerSession.MakeRequest(t, req, http.StatusOK)
+
+		gitRepo, err := git.OpenRepository(baseRepo.RepoPath())
+		assert.NoError(t, err)
+		defer gitRepo.Close()
+		commit, err := gitRepo.GetBranchCommit("master")
+		assert.NoError(t, err)
+
+		assert.Equal(t, "Squash Author", commit.Author.Name)
+		assert.Equal(t, "squash-author@example.com", commit.Author.Email)
+		assert.Equal(t, "Someone Else", commit.Committer.Name)
+		assert.Equal(t, "someone-else@example.com", commit.Committer.Email)
+	})
+}
+
+func TestAPIPullMergeIntoTargetBranch(t *testing.T) {
+	onGiteaRun(t, func(t *testing.T, giteaURL *url.URL) {
+		session := loginUser(t, "user1")
+		testRepoFork(t, session, "user2", "repo1", "user1", "repo1")
+		testEditFile(t, session, "user1", "repo1", "master", "README.md", "Hello, World (Edited)\n")
+
+		resp := testPullCreate(t, session, "user1", "repo1", "master", "This is a pull title")
+		elem := strings.Split(test.RedirectURL(resp), "/")
+		assert.EqualValues(t, "pulls", elem[3])
+
+		baseRepo := models.AssertExistsAndLoadBean(t, &models.Repository{OwnerName: "user2", Name: "repo1"}).(*models.Repository)
+		pr := models.AssertExistsAndLoadBean(t, &models.PullRequest{BaseRepoID: baseRepo.ID, Index: com.StrTo(elem[4]).MustInt64()}).(*models.PullRequest)
+
+		ownerSession := loginUser(t, "user2")
+		token := getTokenForLoggedInUser(t, ownerSession)
+		urlStr := fmt.Sprintf("/api/v1/repos/user2/repo1/pulls/%d/merge?token=%s", pr.Index, token)
+
+		req := NewRequestWithJSON(t, http.MethodPost, urlStr, &auth.MergePullRequestForm{
+			Do:           string(models.MergeStyleMerge),
+			TargetBranch: "not-a-branch",
+		})
+		ownerSession.MakeRequest(t, req, http.StatusUnprocessableEntity)
+
+		gitRepo, err := git.OpenRepository(baseRepo.RepoPath())
+		assert.NoError(t, err)
+		defer gitRepo.Close()
+		masterCommitID, err := gitRepo.GetBranchCommitID("master")
+		assert.NoError(t, err)
+
+		// the protection of the target branch applies, not the one of the recorded base branch
+		csrf := GetCSRF(t, ownerSession, "/user2/repo1/settings/branches")
+		req = NewRequestWithValues(t, "POST", "/user2/repo1/settings/branches/develop", map[string]string{
+			"_csrf":              csrf,
+			"protected":          "on",
+			"required_approvals": "1",
+		})
+		ownerSession.MakeRequest(t, req, http.StatusFound)
+		req = NewRequestWithJSON(t, http.MethodPost, urlStr, &auth.MergePullRequestForm{
+			Do:           string(models.MergeStyleMerge),
+			TargetBranch: "develop",
+		})
+		ownerSession.MakeRequest(t, req, http.StatusMethodNotAllowed)
+		pr = models.AssertExistsAndLoadBean(t, &models.PullRequest{ID: pr.ID}).(*models.PullRequest)
+		assert.False(t, pr.HasMerged)
+
+		req = NewRequestWithValues(t, "POST", "/user2/repo1/settings/branches/develop", map[string]string{
+			"_csrf": csrf,
+		})
+		ownerSession.MakeRequest(t, req, http.StatusFound)
+		req = NewRequestWithJSON(t, http.MethodPost, urlStr, &auth.MergePullRequestForm{
+			Do:           string(models.MergeStyleMerge),
+			TargetBranch: "develop",
+		})
+		ownerSession.MakeRequest(t, req, http.StatusOK)
+
+		// the merge went into develop, the recorded base branch is left unchanged
+		pr = models.AssertExistsAndLoadBean(t, &models.PullRequest{ID: pr.ID}).(*models.PullRequest)
+		assert.True(t, pr.HasMerged)
+		assert.Equal(t, "master", pr.BaseBranch)
+		assert.Equal(t, "develop", pr.MergedBranch)
+		developCommitID, err := gitRepo.GetBranchCommitID("develop")
+		assert.NoError(t, err)
+		assert.Equal(t, pr.MergedCommitID, developCommitID)
+		newMasterCommitID, err := gitRepo.GetBranchCommitID("master")
+		assert.NoError(t, err)
+		assert.Equal(t, masterCommitID, newMasterCommitID)
+	})
+}
+
 func TestPullCleanUpAfterMerge(t *testing.T) {
 	onGiteaRun(t, func(t *testing.T, giteaURL *url.URL) {
 		defer prepareTestEnv(t)()
@@ -246,11 +354,11 @@ func TestCantMergeConflict(t *testing.T) {
 		gitRepo, err := git.OpenRepository(models.RepoPath(user1.Name, repo1.Name))
 		assert.NoError(t, err)
 
-		err = pull.Merge(pr, user1, gitRepo, models.MergeStyleMerge, "CONFLICT")
+		err = pull.Merge(pr, user1, gitRepo, models.MergeStyleMerge, "CONFLICT", nil)
 		assert.Error(t, err, "Merge should return an error due to conflict")
 		assert.True(t, models.IsErrMergeConflicts(err), "Merge error is not a conflict error")
 
-		err = pull.Merge(pr, user1, gitRepo, models.MergeStyleRebase, "CONFLICT")
+		err = pull.Merge(pr, user1, gitRepo, models.MergeStyleRebase, "CONFLICT", nil)
 		assert.Error(t, err, "Merge should return an error due to conflict")
 		assert.True(t, models.IsErrRebaseConflicts(err), "Merge error is not a conflict error")
 	})
@@ -334,7 +442,7 @@ func TestCantMergeUnrelated(t *testing.T) {
 			BaseBranch: "base",
 		}).(*models.PullRequest)
 
-		err = pull.Merge(pr, user1, gitRepo, models.MergeStyleMerge, "UNRELATED")
+		err = pull.Merge(pr, user1, gitRepo, models.MergeStyleMerge, "UNRELATED", nil)
 		assert.Error(t, err, "Merge should return an error due to unrelated")
 		assert.True(t, models.IsErrMergeUnrelatedHistories(err), "Merge error is not a unrelated histories error")
 	})
@@ -27,26 +27,27 @@ const (
 
 // ProtectedBranch struct
 type ProtectedBranch struct {
-	ID                        int64  `xorm:"pk autoincr"`
-	RepoID                    int64  `xorm:"UNIQUE(s)"`
-	BranchName                string `xorm:"UNIQUE(s)"`
-	CanPush                   bool   `xorm:"NOT NULL DEFAULT false"`
-	EnableWhitelist           bool
-	WhitelistUserIDs          []int64  `xorm:"JSON TEXT"`
-	WhitelistTeamIDs          []int64  `xorm:"JSON TEXT"`
-	EnableMergeWhitelist      bool     `xorm:"NOT NULL DEFAULT false"`
-	WhitelistDeployKeys       bool     `xorm:"NOT NULL DEFAULT false"`
-	MergeWhitelistUserIDs     []int64  `xorm:"JSON TEXT"`
-	MergeWhitelistTeamIDs     []int64  `xorm:"JSON TEXT"`
-	EnableStatusCheck         bool     `xorm:"NOT NULL DEFAULT false"`
-	StatusCheckContexts       []string `xorm:"JSON TEXT"`
-	EnableApprovalsWhitelist  bool     `xorm:"NOT NULL DEFAULT false"`
-	ApprovalsWhitelistUserIDs []int64  `xorm:"JSON TEXT"`
-	ApprovalsWhitelistTeamIDs []int64  `xorm:"JSON TEXT"`
-	RequiredApprovals         int64    `xorm:"NOT NULL DEFAULT 0"`
-	BlockOnRejectedReviews    bool     `xorm:"NOT NULL DEFAULT false"`
-	DismissStaleApprovals     bool     `xorm:"NOT NULL DEFAULT false"`
-	RequireSignedCommits      bool     `xorm:"NOT NULL DEFAULT false"`
+	ID                             int64  `xorm:"pk autoincr"`
+	RepoID                         int64  `xorm:"UNIQUE(s)"`
+	BranchName                     string `xorm:"UNIQUE(s)"`
+	CanPush                        bool   `xorm:"NOT NULL DEFAULT false"`
+	EnableWhitelist                bool
+	WhitelistUserIDs               []int64  `xorm:"JSON TEXT"`
+	WhitelistTeamIDs               []int64  `xorm:"JSON TEXT"`
+	EnableMergeWhitelist           bool     `xorm:"NOT NULL DEFAULT false"`
+	WhitelistDeployKeys            bool     `xorm:"NOT NULL DEFAULT false"`
+	MergeWhitelistUserIDs          []int64  `xorm:"JSON TEXT"`
+	MergeWhitelistTeamIDs          []int64  `xorm:"JSON TEXT"`
+	EnableStatusCheck              bool     `xorm:"NOT NULL DEFAULT false"`
+	StatusCheckContexts            []string `xorm:"JSON TEXT"`
+	EnableApprovalsWhitelist       bool     `xorm:"NOT NULL DEFAULT false"`
+	ApprovalsWhitelistUserIDs      []int64  `xorm:"JSON TEXT"`
+	ApprovalsWhitelistTeamIDs      []int64  `xorm:"JSON TEXT"`
+	RequiredApprovals              int64    `xorm:"NOT NULL DEFAULT 0"`
+	BlockOnRejectedReviews         bool     `xorm:"NOT NULL DEFAULT false"`
+	DismissStaleApprovals          bool     `xorm:"NOT NULL DEFAULT false"`
+	RequireSignedCommits           bool     `xorm:"NOT NULL DEFAULT false"`
+	BlockOnUnresolvedConversations bool     `xorm:"NOT NULL DEFAULT false"`
 
 	CreatedUnix timeutil.TimeStamp `xorm:"created"`
 	UpdatedUnix timeutil.TimeStamp `xorm:"updated"`
@@ -173,6 +174,32 @@ func (protectBranch *ProtectedBranch) GetGrantedApprovalsCount(pr *PullRequest)
 	return approvals
 }
 
+// GetGrantedApprovers returns the authors of the granted approvals for pr, ordered by the time they approved.
+func (protectBranch *ProtectedBranch) GetGrantedApprovers(pr *PullRequest) ([]*User, error) {
+	sess := x.Where("issue_id = ?", pr.IssueID).
+		And("type = ?", ReviewTypeApprove).
+		And("official = ?", true)
+	if protectBranch.DismissStaleApprovals {
+		sess = sess.And("stale = ?", false)
+	}
+	reviews := make([]*Review, 0, protectBranch.RequiredApprovals)
+	if err := sess.Asc("updated_unix").Find(&reviews); err != nil {
+		return nil, err
+	}
+
+	approvers := make([]*User, 0, len(reviews))
+	for _, review := range reviews {
+		if err := review.loadReviewer(x); err != nil {
+			if IsErrUserNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		approvers = append(approvers, review.Reviewer)
+	}
+	return approvers, nil
+}
+
 // MergeBlockedByRejectedReview returns true if merge is blocked by rejected reviews
 func (protectBranch *ProtectedBranch) MergeBlockedByRejectedReview(pr *PullRequest) bool {
 	if !protectBranch.BlockOnRejectedReviews {
@@ -78,3 +78,27 @@ func getDeletedBranch(t *testing.T, branch *DeletedBranch) *DeletedBranch {
 
 	return deletedBranch
 }
+
+func TestProtectedBranch_GetGrantedApprovalsCount(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+	pr := AssertExistsAndLoadBean(t, &PullRequest{ID: 2}).(*PullRequest)
+
+	_, err := x.ID(8).Cols("official", "commit_id").Update(&Review{Official: true, CommitID: "985f0301dba5e7b34be866819cd15ad3d8f508ee"})
+	assert.NoError(t, err)
+	_, err = x.ID(9).Cols("type", "official", "commit_id").Update(&Review{Type: ReviewTypeApprove, Official: true, CommitID: "5c050d3b6d2db231ab1f64e324f1b6b9a0b181c2"})
+	assert.NoError(t, err)
+
+	// a push changing the content makes the reviews not given on the new head stale
+	assert.NoError(t, MarkReviewsAsStale(pr.IssueID))
+	assert.NoError(t, MarkReviewsAsNotStale(pr.IssueID, "985f0301dba5e7b34be866819cd15ad3d8f508ee"))
+
+	protectBranch := &ProtectedBranch{}
+	assert.EqualValues(t, 2, protectBranch.GetGrantedApprovalsCount(pr))
+	protectBranch.DismissStaleApprovals = true
+	assert.EqualValues(t, 1, protectBranch.GetGrantedApprovalsCount(pr))
+
+	// stale reviews are retained, only no longer granted
+	review := AssertExistsAndLoadBean(t, &Review{ID: 9}).(*Review)
+	assert.True(t, review.Official)
+	assert.True(t, review.Stale)
+}
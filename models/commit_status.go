@@ -290,6 +290,17 @@ func NewCommitStatus(opts NewCommitStatusOptions) error {
 		return fmt.Errorf("Insert CommitStatus[%s, %s]: %v", repoPath, opts.SHA, err)
 	}
 
+	// Expire the cached status of pull requests whose head is this commit
+	if _, err = sess.Where("base_repo_id = ? AND head_commit_id = ?", opts.Repo.ID, opts.SHA).
+		Cols("last_commit_status_updated").
+		NoAutoTime().
+		Update(&PullRequest{LastCommitStatusUpdated: 0}); err != nil {
+		if err := sess.Rollback(); err != nil {
+			log.Error("Expire cached commit status: sess.Rollback: %v", err)
+		}
+		return fmt.Errorf("Expire cached commit status[%s, %s]: %v", repoPath, opts.SHA, err)
+	}
+
 	return sess.Commit()
 }
 
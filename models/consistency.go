@@ -110,6 +110,10 @@ func (repo *Repository) checkForConsistency(t *testing.T) {
 	assert.EqualValues(t, repo.NumClosedPulls, actual,
 		"Unexpected number of closed pulls for repo %+v", repo)
 
+	actual = getCount(t, x.Where("has_merged=?", true), &PullRequest{BaseRepoID: repo.ID})
+	assert.EqualValues(t, repo.NumMergedPulls, actual,
+		"Unexpected number of merged pulls for repo %+v", repo)
+
 	actual = getCount(t, x.Where("is_closed=?", true), &Milestone{RepoID: repo.ID})
 	assert.EqualValues(t, repo.NumClosedMilestones, actual,
 		"Unexpected number of closed milestones for repo %+v", repo)
@@ -7,6 +7,7 @@ package models
 
 import (
 	"fmt"
+	"strings"
 
 	"code.gitea.io/gitea/modules/git"
 )
@@ -1000,6 +1001,70 @@ func (err ErrNotAllowedToMerge) Error() string {
 	return fmt.Sprintf("not allowed to merge [reason: %s]", err.Reason)
 }
 
+// ErrUnsignedCommits represents an error that a pull request has commits
+// which are unsigned while the base branch requires signed commits.
+type ErrUnsignedCommits struct {
+	SHAs []string
+}
+
+// IsErrUnsignedCommits checks if an error is an ErrUnsignedCommits.
+func IsErrUnsignedCommits(err error) bool {
+	_, ok := err.(ErrUnsignedCommits)
+	return ok
+}
+
+func (err ErrUnsignedCommits) Error() string {
+	return fmt.Sprintf("pull request has unsigned commits [shas: %s]", strings.Join(err.SHAs, ", "))
+}
+
+// ErrMissingSignoff represents an error that a pull request has commits without
+// a Signed-off-by line of their author while the repository requires it.
+type ErrMissingSignoff struct {
+	SHAs []string
+}
+
+// IsErrMissingSignoff checks if an error is an ErrMissingSignoff.
+func IsErrMissingSignoff(err error) bool {
+	_, ok := err.(ErrMissingSignoff)
+	return ok
+}
+
+func (err ErrMissingSignoff) Error() string {
+	return fmt.Sprintf("pull request has commits without sign-off [shas: %s]", strings.Join(err.SHAs, ", "))
+}
+
+// ErrBinaryFilesChanged represents an error that a pull request adds or modifies
+// binary files while the repository blocks merging them.
+type ErrBinaryFilesChanged struct {
+	Files []string
+}
+
+// IsErrBinaryFilesChanged checks if an error is an ErrBinaryFilesChanged.
+func IsErrBinaryFilesChanged(err error) bool {
+	_, ok := err.(ErrBinaryFilesChanged)
+	return ok
+}
+
+func (err ErrBinaryFilesChanged) Error() string {
+	return fmt.Sprintf("pull request adds or modifies binary files [files: %s]", strings.Join(err.Files, ", "))
+}
+
+// ErrUnresolvedConversations represents an error that a pull request has unresolved
+// conversations while the base branch blocks merging on them.
+type ErrUnresolvedConversations struct {
+	ID int64
+}
+
+// IsErrUnresolvedConversations checks if an error is an ErrUnresolvedConversations.
+func IsErrUnresolvedConversations(err error) bool {
+	_, ok := err.(ErrUnresolvedConversations)
+	return ok
+}
+
+func (err ErrUnresolvedConversations) Error() string {
+	return fmt.Sprintf("pull request has unresolved conversations [id: %d]", err.ID)
+}
+
 // ErrTagAlreadyExists represents an error that tag with such name already exists.
 type ErrTagAlreadyExists struct {
 	TagName string
@@ -1282,6 +1347,22 @@ func (err ErrPullRequestAlreadyExists) Error() string {
 		err.ID, err.IssueID, err.HeadRepoID, err.BaseRepoID, err.HeadBranch, err.BaseBranch)
 }
 
+// ErrPullRequestSameRef represents an error when the head and base of a pull request are the same branch
+type ErrPullRequestSameRef struct {
+	RepoID int64
+	Branch string
+}
+
+// IsErrPullRequestSameRef checks if an error is a ErrPullRequestSameRef.
+func IsErrPullRequestSameRef(err error) bool {
+	_, ok := err.(ErrPullRequestSameRef)
+	return ok
+}
+
+func (err ErrPullRequestSameRef) Error() string {
+	return fmt.Sprintf("head and base of the pull request are the same branch [repo_id: %d, branch: %s]", err.RepoID, err.Branch)
+}
+
 // ErrPullRequestHeadRepoMissing represents a "ErrPullRequestHeadRepoMissing" error
 type ErrPullRequestHeadRepoMissing struct {
 	ID         int64
@@ -1300,6 +1381,68 @@ func (err ErrPullRequestHeadRepoMissing) Error() string {
 		err.ID, err.HeadRepoID)
 }
 
+// ErrPullRequestHasNoCommits represents an error when a pull request has no commits or its head is gone
+type ErrPullRequestHasNoCommits struct {
+	ID int64
+}
+
+// IsErrPullRequestHasNoCommits checks if an error is a ErrPullRequestHasNoCommits.
+func IsErrPullRequestHasNoCommits(err error) bool {
+	_, ok := err.(ErrPullRequestHasNoCommits)
+	return ok
+}
+
+func (err ErrPullRequestHasNoCommits) Error() string {
+	return fmt.Sprintf("pull request has no commits [id: %d]", err.ID)
+}
+
+// ErrPullRequestNotMerged represents an error when a pull request has not been merged
+type ErrPullRequestNotMerged struct {
+	ID int64
+}
+
+// IsErrPullRequestNotMerged checks if an error is a ErrPullRequestNotMerged.
+func IsErrPullRequestNotMerged(err error) bool {
+	_, ok := err.(ErrPullRequestNotMerged)
+	return ok
+}
+
+func (err ErrPullRequestNotMerged) Error() string {
+	return fmt.Sprintf("pull request has not been merged [id: %d]", err.ID)
+}
+
+// ErrMergedCommitNotExist represents an error when the merged commit of a pull request does not exist in its base repository
+type ErrMergedCommitNotExist struct {
+	ID       int64
+	CommitID string
+}
+
+// IsErrMergedCommitNotExist checks if an error is a ErrMergedCommitNotExist.
+func IsErrMergedCommitNotExist(err error) bool {
+	_, ok := err.(ErrMergedCommitNotExist)
+	return ok
+}
+
+func (err ErrMergedCommitNotExist) Error() string {
+	return fmt.Sprintf("merged commit of pull request does not exist [id: %d, commit: %s]", err.ID, err.CommitID)
+}
+
+// ErrPullRequestInvalidDiffBase represents an error when a commit can not be used as base of the diff of a pull request
+type ErrPullRequestInvalidDiffBase struct {
+	ID     int64
+	Commit string
+}
+
+// IsErrPullRequestInvalidDiffBase checks if an error is a ErrPullRequestInvalidDiffBase.
+func IsErrPullRequestInvalidDiffBase(err error) bool {
+	_, ok := err.(ErrPullRequestInvalidDiffBase)
+	return ok
+}
+
+func (err ErrPullRequestInvalidDiffBase) Error() string {
+	return fmt.Sprintf("commit is not an ancestor of the pull request head [id: %d, commit: %s]", err.ID, err.Commit)
+}
+
 // ErrInvalidMergeStyle represents an error if merging with disabled merge strategy
 type ErrInvalidMergeStyle struct {
 	ID    int64
@@ -1319,10 +1462,11 @@ func (err ErrInvalidMergeStyle) Error() string {
 
 // ErrMergeConflicts represents an error if merging fails with a conflict
 type ErrMergeConflicts struct {
-	Style  MergeStyle
-	StdOut string
-	StdErr string
-	Err    error
+	Style           MergeStyle
+	ConflictedFiles []string
+	StdOut          string
+	StdErr          string
+	Err             error
 }
 
 // IsErrMergeConflicts checks if an error is a ErrMergeConflicts.
@@ -1335,6 +1479,58 @@ func (err ErrMergeConflicts) Error() string {
 	return fmt.Sprintf("Merge Conflict Error: %v: %s\n%s", err.Err, err.StdErr, err.StdOut)
 }
 
+// ErrPatchTooLarge represents an error if the patch of a pull request exceeds the configured maximum size
+type ErrPatchTooLarge struct {
+	ID      int64
+	MaxSize int64
+}
+
+// IsErrPatchTooLarge checks if an error is a ErrPatchTooLarge.
+func IsErrPatchTooLarge(err error) bool {
+	_, ok := err.(ErrPatchTooLarge)
+	return ok
+}
+
+func (err ErrPatchTooLarge) Error() string {
+	return fmt.Sprintf("patch of pull request is too large [id: %d, max_size: %d]", err.ID, err.MaxSize)
+}
+
+// ErrPatchSeriesTooLarge represents an error if a pull request has too many commits
+// to be exported as a series of patches
+type ErrPatchSeriesTooLarge struct {
+	ID         int64
+	Commits    int
+	MaxCommits int
+}
+
+// IsErrPatchSeriesTooLarge checks if an error is a ErrPatchSeriesTooLarge.
+func IsErrPatchSeriesTooLarge(err error) bool {
+	_, ok := err.(ErrPatchSeriesTooLarge)
+	return ok
+}
+
+func (err ErrPatchSeriesTooLarge) Error() string {
+	return fmt.Sprintf("pull request has too many commits for a patch series [id: %d, commits: %d, max_commits: %d]", err.ID, err.Commits, err.MaxCommits)
+}
+
+// ErrConflictResolutionMismatch represents an error if the files of a conflict resolution
+// do not match the files a merge conflicts in
+type ErrConflictResolutionMismatch struct {
+	Missing    []string
+	Unexpected []string
+}
+
+// IsErrConflictResolutionMismatch checks if an error is a ErrConflictResolutionMismatch.
+func IsErrConflictResolutionMismatch(err error) bool {
+	_, ok := err.(ErrConflictResolutionMismatch)
+	return ok
+}
+
+func (err ErrConflictResolutionMismatch) Error() string {
+	return fmt.Sprintf("conflict resolution does not match the conflicted files [missing: %v, unexpected: %v]",
+		err.Missing, err.Unexpected)
+}
+
 // ErrMergeUnrelatedHistories represents an error if merging fails due to unrelated histories
 type ErrMergeUnrelatedHistories struct {
 	Style  MergeStyle
@@ -1353,6 +1549,24 @@ func (err ErrMergeUnrelatedHistories) Error() string {
 	return fmt.Sprintf("Merge UnrelatedHistories Error: %v: %s\n%s", err.Err, err.StdErr, err.StdOut)
 }
 
+// ErrMergeNotFastForward represents an error if the base branch can not be fast-forwarded to the head
+type ErrMergeNotFastForward struct {
+	Style  MergeStyle
+	StdOut string
+	StdErr string
+	Err    error
+}
+
+// IsErrMergeNotFastForward checks if an error is a ErrMergeNotFastForward.
+func IsErrMergeNotFastForward(err error) bool {
+	_, ok := err.(ErrMergeNotFastForward)
+	return ok
+}
+
+func (err ErrMergeNotFastForward) Error() string {
+	return fmt.Sprintf("Merge NotFastForward Error: %v: %s\n%s", err.Err, err.StdErr, err.StdOut)
+}
+
 // ErrMergePushOutOfDate represents an error if merging fails due to unrelated histories
 type ErrMergePushOutOfDate struct {
 	Style  MergeStyle
@@ -1600,6 +1814,22 @@ func (err ErrLoginSourceInUse) Error() string {
 	return fmt.Sprintf("login source is still used by some users [id: %d]", err.ID)
 }
 
+// ErrLDAPNotInRequiredGroup represents a "LDAPNotInRequiredGroup" kind of error.
+type ErrLDAPNotInRequiredGroup struct {
+	Name      string
+	LoginName string
+}
+
+// IsErrLDAPNotInRequiredGroup checks if an error is a ErrLDAPNotInRequiredGroup.
+func IsErrLDAPNotInRequiredGroup(err error) bool {
+	_, ok := err.(ErrLDAPNotInRequiredGroup)
+	return ok
+}
+
+func (err ErrLDAPNotInRequiredGroup) Error() string {
+	return fmt.Sprintf("user is not a member of the group required by the login source [login_source: %s, login_name: %s]", err.Name, err.LoginName)
+}
+
 // ___________
 // \__    ___/___ _____    _____
 //   |    |_/ __ \\__  \  /     \
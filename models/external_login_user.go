@@ -86,6 +86,19 @@ func removeAllAccountLinks(e Engine, user *User) error {
 	return err
 }
 
+// getExternalLoginUserIDs returns the IDs of the users linked to the login source by their external ID
+func getExternalLoginUserIDs(loginSourceID int64) (map[string]int64, error) {
+	links := make([]*ExternalLoginUser, 0, 10)
+	if err := x.Where("login_source_id=?", loginSourceID).Find(&links); err != nil {
+		return nil, err
+	}
+	ids := make(map[string]int64, len(links))
+	for _, link := range links {
+		ids[link.ExternalID] = link.UserID
+	}
+	return ids, nil
+}
+
 // GetUserIDByExternalUserID get user id according to provider and userID
 func GetUserIDByExternalUserID(provider string, userID string) (int64, error) {
 	var id int64
@@ -66,6 +66,8 @@ type Issue struct {
 	// IsLocked limits commenting abilities to users on an issue
 	// with write access
 	IsLocked bool `xorm:"NOT NULL DEFAULT false"`
+	// IsPinned marks the issue as pinned to the top of the repository
+	IsPinned bool `xorm:"NOT NULL DEFAULT false"`
 }
 
 var (
@@ -751,6 +753,15 @@ func (issue *Issue) ChangeTitle(doer *User, oldTitle string) (err error) {
 	return sess.Commit()
 }
 
+// ChangePinned pins or unpins this issue.
+func (issue *Issue) ChangePinned(pinned bool) error {
+	if issue.IsPinned == pinned {
+		return nil
+	}
+	issue.IsPinned = pinned
+	return updateIssueCols(x, issue, "is_pinned")
+}
+
 // AddDeletePRBranchComment adds delete branch comment for pull request issue
 func AddDeletePRBranchComment(doer *User, repo *Repository, issueID int64, branchName string) error {
 	issue, err := getIssueByID(x, issueID)
@@ -86,6 +86,8 @@ const (
 	CommentTypeChangeTargetBranch
 	// Delete time manual for time tracking
 	CommentTypeDeleteTimeManual
+	// Pull request closed automatically for inactivity, the content holds the number of days
+	CommentTypeCloseStale
 )
 
 // CommentTag defines comment tag type
@@ -149,6 +151,7 @@ type Comment struct {
 	Review      *Review `xorm:"-"`
 	ReviewID    int64   `xorm:"index"`
 	Invalidated bool
+	IsResolved  bool `xorm:"NOT NULL DEFAULT false"`
 
 	// Reference an issue or pull from another comment, issue or PR
 	// All information is about the origin of the reference
@@ -177,7 +180,15 @@ func (c *Comment) loadIssue(e Engine) (err error) {
 }
 
 func (c *Comment) loadPoster(e Engine) (err error) {
-	if c.PosterID <= 0 || c.Poster != nil {
+	if c.Poster != nil {
+		return nil
+	}
+	if c.PosterID == -1 {
+		// posted by an automated action, e.g. the auto-close of stale pull requests
+		c.Poster = NewGhostUser()
+		return nil
+	}
+	if c.PosterID <= 0 {
 		return nil
 	}
 
@@ -951,6 +962,40 @@ func fetchCodeCommentsByReview(e Engine, issue *Issue, currentUser *User, review
 	return pathToLineToComment, nil
 }
 
+// MarkConversation marks the conversation the given code comment belongs to as resolved or unresolved.
+func MarkConversation(comment *Comment, isResolved bool) error {
+	if comment.Type != CommentTypeCode {
+		return nil
+	}
+	_, err := x.Where("issue_id = ? AND type = ? AND tree_path = ? AND line = ?",
+		comment.IssueID, CommentTypeCode, comment.TreePath, comment.Line).
+		Cols("is_resolved").
+		Update(&Comment{IsResolved: isResolved})
+	return err
+}
+
+// CanMarkConversation returns if the user may mark the conversations of the pull request as resolved or
+// unresolved, which the poster of the pull request, writers of pull requests and official reviewers can.
+func CanMarkConversation(issue *Issue, doer *User) (bool, error) {
+	if doer == nil || issue == nil {
+		return false, nil
+	}
+	if doer.ID == issue.PosterID {
+		return true, nil
+	}
+	if err := issue.LoadRepo(); err != nil {
+		return false, err
+	}
+	perm, err := GetUserRepoPermission(issue.Repo, doer)
+	if err != nil {
+		return false, err
+	}
+	if perm.CanWriteIssuesOrPulls(true) {
+		return true, nil
+	}
+	return IsOfficialReviewer(issue, doer)
+}
+
 // FetchCodeComments will return a 2d-map: ["Path"]["Line"] = Comments at line
 func FetchCodeComments(issue *Issue, currentUser *User) (CodeComments, error) {
 	return fetchCodeComments(x, issue, currentUser)
@@ -57,3 +57,42 @@ func TestFetchCodeComments(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Len(t, res, 1)
 }
+
+func TestCanMarkConversation(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	issue := AssertExistsAndLoadBean(t, &Issue{ID: 2}).(*Issue)
+	poster := AssertExistsAndLoadBean(t, &User{ID: 1}).(*User)
+	owner := AssertExistsAndLoadBean(t, &User{ID: 2}).(*User)
+	reader := AssertExistsAndLoadBean(t, &User{ID: 4}).(*User)
+
+	for _, kase := range []struct {
+		doer     *User
+		expected bool
+	}{
+		{poster, true},
+		{owner, true},
+		{reader, false},
+		{nil, false},
+	} {
+		canMark, err := CanMarkConversation(issue, kase.doer)
+		assert.NoError(t, err)
+		assert.Equal(t, kase.expected, canMark)
+	}
+}
+
+func TestMarkConversation(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	comment := AssertExistsAndLoadBean(t, &Comment{ID: 5}).(*Comment)
+	assert.NoError(t, MarkConversation(comment, true))
+	AssertExistsAndLoadBean(t, &Comment{ID: 5, IsResolved: true})
+
+	assert.NoError(t, MarkConversation(comment, false))
+	AssertExistsAndLoadBean(t, &Comment{ID: 5}, Cond("is_resolved = ?", false))
+
+	// only code comments belong to conversations
+	comment = AssertExistsAndLoadBean(t, &Comment{ID: 1}).(*Comment)
+	assert.NoError(t, MarkConversation(comment, true))
+	AssertExistsAndLoadBean(t, &Comment{ID: 1}, Cond("is_resolved = ?", false))
+}
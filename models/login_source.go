@@ -405,6 +405,28 @@ func UpdateSource(source *LoginSource) error {
 	return err
 }
 
+// updateSyncState saves the most recent last modification value among the synchronized
+// entries of an LDAP source, the next synchronization only fetches entries changed since then.
+// It also counts the incremental synchronizations since the last full one.
+func (source *LoginSource) updateSyncState(entries []*ldap.SearchResult, incremental bool) error {
+	cfg := source.LDAP()
+	if len(cfg.AttributeChanged) == 0 {
+		return nil
+	}
+	mark := ldap.LatestChange(entries, cfg.LastSyncChanged)
+	incrementalSyncs := 0
+	if incremental {
+		incrementalSyncs = cfg.IncrementalSyncs + 1
+	}
+	if mark == cfg.LastSyncChanged && incrementalSyncs == cfg.IncrementalSyncs {
+		return nil
+	}
+	cfg.LastSyncChanged = mark
+	cfg.IncrementalSyncs = incrementalSyncs
+	_, err := x.ID(source.ID).Cols("cfg").Update(source)
+	return err
+}
+
 // DeleteSource deletes a LoginSource record in DB.
 func DeleteSource(source *LoginSource) error {
 	count, err := x.Count(&User{LoginSource: source.ID})
@@ -467,6 +489,12 @@ func LoginViaLDAP(user *User, login, password string, source *LoginSource, autoR
 		// User not in LDAP, do nothing
 		return nil, ErrUserNotExist{0, login, 0}
 	}
+	if !sr.IsMember {
+		if source.LDAP().RequireGroupMembership {
+			return nil, ErrLDAPNotInRequiredGroup{source.Name, login}
+		}
+		return nil, ErrUserNotExist{0, login, 0}
+	}
 
 	var isAttributeSSHPublicKeySet = len(strings.TrimSpace(source.LDAP().AttributeSSHPublicKey)) > 0
 
@@ -474,16 +502,20 @@ func LoginViaLDAP(user *User, login, password string, source *LoginSource, autoR
 	if isExist, err := IsUserExist(0, sr.Username); err != nil {
 		return nil, err
 	} else if isExist &&
-		!user.ProhibitLogin && len(source.LDAP().AdminFilter) > 0 && user.IsAdmin != sr.IsAdmin {
+		!user.ProhibitLogin && source.LDAP().HasAdminFilter() && user.IsAdmin != sr.IsAdmin {
 		// Change existing admin flag only if AdminFilter option is set
 		user.IsAdmin = sr.IsAdmin
 		err = UpdateUserCols(user, "is_admin")
 		if err != nil {
 			return nil, err
 		}
+		if user.IsAdmin {
+			logLDAPAdminGrant(source, user.Name, sr.AdminGroups)
+		}
 	}
 
 	if !autoRegister {
+		user.PasswordExpiresIn = sr.PasswordExpiresIn
 		if isAttributeSSHPublicKeySet && synchronizeLdapSSHPublicKeys(user, source, sr.SSHPublicKey) {
 			return user, RewriteAllPublicKeys()
 		}
@@ -500,6 +532,25 @@ func LoginViaLDAP(user *User, login, password string, source *LoginSource, autoR
 		return nil, fmt.Errorf("Invalid pattern for attribute 'username' [%s]: must be valid alpha or numeric or dash(-_) or dot characters", sr.Username)
 	}
 
+	// Users renamed in LDAP are found by their external ID instead of being registered again
+	if len(sr.ExternalID) > 0 {
+		link := &ExternalLoginUser{ExternalID: sr.ExternalID, LoginSourceID: source.ID}
+		if has, err := GetExternalLogin(link); err != nil {
+			return nil, err
+		} else if has {
+			if user, err = GetUserByID(link.UserID); err != nil {
+				return nil, err
+			}
+			if user.LowerName != strings.ToLower(sr.Username) {
+				if err = renameLdapUser(user, sr.Username, login); err != nil {
+					return nil, err
+				}
+			}
+			user.PasswordExpiresIn = sr.PasswordExpiresIn
+			return user, nil
+		}
+	}
+
 	if len(sr.Mail) == 0 {
 		sr.Mail = fmt.Sprintf("%s@localhost", sr.Username)
 	}
@@ -514,17 +565,37 @@ func LoginViaLDAP(user *User, login, password string, source *LoginSource, autoR
 		LoginName:   login,
 		IsActive:    true,
 		IsAdmin:     sr.IsAdmin,
+		Language:    sr.Language,
+
+		PasswordExpiresIn: sr.PasswordExpiresIn,
 	}
 
 	err := CreateUser(user)
+	if err == nil && user.IsAdmin {
+		logLDAPAdminGrant(source, user.Name, sr.AdminGroups)
+	}
 
 	if err == nil && isAttributeSSHPublicKeySet && addLdapSSHPublicKeys(user, source, sr.SSHPublicKey) {
 		err = RewriteAllPublicKeys()
 	}
 
+	if err == nil && len(sr.ExternalID) > 0 {
+		err = linkLdapExternalID(user, source, sr.ExternalID)
+	}
+
 	return user, err
 }
 
+// logLDAPAdminGrant logs that the LDAP source granted administrator privileges to a user,
+// naming the groups they were granted through if the source looks them up.
+func logLDAPAdminGrant(source *LoginSource, username string, groups []string) {
+	if len(groups) == 0 {
+		log.Info("LDAP source %s grants administrator privileges to %s", source.Name, username)
+		return
+	}
+	log.Info("LDAP source %s grants administrator privileges to %s via group(s): %s", source.Name, username, strings.Join(groups, ", "))
+}
+
 //   _________   __________________________
 //  /   _____/  /     \__    ___/\______   \
 //  \_____  \  /  \ /  \|    |    |     ___/
@@ -784,6 +855,7 @@ func UserSignIn(username, password string) (*User, error) {
 		return nil, err
 	}
 
+	var denied error
 	for _, source := range sources {
 		if source.IsOAuth2() || source.IsSSPI() {
 			// don't try to authenticate against OAuth2 and SSPI sources here
@@ -793,9 +865,15 @@ func UserSignIn(username, password string) (*User, error) {
 		if err == nil {
 			return authUser, nil
 		}
+		if IsErrLDAPNotInRequiredGroup(err) {
+			denied = err
+		}
 
 		log.Warn("Failed to login '%s' via '%s': %v", username, source.Name, err)
 	}
 
+	if denied != nil {
+		return nil, denied
+	}
 	return nil, ErrUserNotExist{user.ID, user.Name, 0}
 }
@@ -0,0 +1,61 @@
+// Copyright 2020 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"testing"
+
+	"code.gitea.io/gitea/modules/auth/ldap"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoginSource_UpdateSyncState(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	source := &LoginSource{
+		Type:      LoginLDAP,
+		Name:      "incremental",
+		IsActived: true,
+		Cfg: &LDAPConfig{Source: &ldap.Source{
+			Name:             "incremental",
+			AttributeChanged: "modifyTimestamp",
+			FullSyncInterval: 2,
+		}},
+	}
+	assert.NoError(t, CreateLoginSource(source))
+
+	entries := []*ldap.SearchResult{
+		{Username: "user1", Changed: "20200102000000Z"},
+		{Username: "user2", Changed: "20200103000000Z"},
+		{Username: "user3"},
+	}
+	assert.NoError(t, source.updateSyncState(entries, false))
+
+	loaded, err := GetLoginSourceByID(source.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "20200103000000Z", loaded.LDAP().LastSyncChanged)
+	assert.True(t, loaded.LDAP().IsIncrementalSync())
+
+	// older changes never move the mark back
+	assert.NoError(t, loaded.updateSyncState(entries[:1], true))
+	loaded, err = GetLoginSourceByID(source.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "20200103000000Z", loaded.LDAP().LastSyncChanged)
+	assert.Equal(t, 1, loaded.LDAP().IncrementalSyncs)
+	assert.True(t, loaded.LDAP().IsIncrementalSync())
+
+	// a full synchronization is due after FullSyncInterval incremental ones
+	assert.NoError(t, loaded.updateSyncState(nil, true))
+	loaded, err = GetLoginSourceByID(source.ID)
+	assert.NoError(t, err)
+	assert.False(t, loaded.LDAP().IsIncrementalSync())
+
+	assert.NoError(t, loaded.updateSyncState(nil, false))
+	loaded, err = GetLoginSourceByID(source.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, loaded.LDAP().IncrementalSyncs)
+	assert.True(t, loaded.LDAP().IsIncrementalSync())
+}
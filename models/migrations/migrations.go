@@ -302,6 +302,28 @@ var migrations = []Migration{
 	NewMigration("Add Require Signed Commits to ProtectedBranch", addRequireSignedCommits),
 	// v123 -> v124
 	NewMigration("Add original informations for reactions", addReactionOriginals),
+	// v124 -> v125
+	NewMigration("Add resolved flag to comments and block on unresolved conversations to ProtectedBranch", addConversationResolution),
+	// v125 -> v126
+	NewMigration("Add head commit id to pull requests", addPullRequestHeadCommitID),
+	// v126 -> v127
+	NewMigration("Add merged reviewers to pull requests", addPullRequestMergedReviewers),
+	// v127 -> v128
+	NewMigration("Add merged pull request count to repositories", addRepositoryNumMergedPulls),
+	// v128 -> v129
+	NewMigration("Add is_pinned column to issues", addIssueIsPinned),
+	// v129 -> v130
+	NewMigration("Add merged branch to pull requests", addPullRequestMergedBranch),
+	// v130 -> v131
+	NewMigration("Add reverted by commit and pull request to pull requests", addPullRequestRevertedBy),
+	// v131 -> v132
+	NewMigration("Add viewed files of pull requests", addPullViewedFile),
+	// v132 -> v133
+	NewMigration("Add conflicted hunks to pull requests", addPullRequestConflictedHunks),
+	// v133 -> v134
+	NewMigration("Add cached last commit status to pull requests", addPullRequestLastCommitStatus),
+	// v134 -> v135
+	NewMigration("Add body signature to hook tasks", addHookTaskBodySignature),
 }
 
 // Migrate database to current version
@@ -0,0 +1,27 @@
+// Copyright 2020 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package migrations
+
+import (
+	"xorm.io/xorm"
+)
+
+func addConversationResolution(x *xorm.Engine) error {
+	type Comment struct {
+		IsResolved bool `xorm:"NOT NULL DEFAULT false"`
+	}
+
+	type ProtectedBranch struct {
+		BlockOnUnresolvedConversations bool `xorm:"NOT NULL DEFAULT false"`
+	}
+
+	if err := x.Sync2(new(Comment), new(ProtectedBranch)); err != nil {
+		return err
+	}
+
+	// Conversations started before they could be resolved must not block merging
+	_, err := x.Exec("UPDATE comment SET is_resolved = ?", true)
+	return err
+}
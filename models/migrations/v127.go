@@ -0,0 +1,22 @@
+// Copyright 2020 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package migrations
+
+import (
+	"xorm.io/xorm"
+)
+
+func addRepositoryNumMergedPulls(x *xorm.Engine) error {
+	type Repository struct {
+		NumMergedPulls int `xorm:"NOT NULL DEFAULT 0"`
+	}
+
+	if err := x.Sync2(new(Repository)); err != nil {
+		return err
+	}
+
+	_, err := x.Exec("UPDATE `repository` SET num_merged_pulls=(SELECT COUNT(*) FROM `pull_request` WHERE base_repo_id=`repository`.id AND has_merged=?)", true)
+	return err
+}
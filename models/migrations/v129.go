@@ -0,0 +1,22 @@
+// Copyright 2020 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package migrations
+
+import (
+	"xorm.io/xorm"
+)
+
+func addPullRequestMergedBranch(x *xorm.Engine) error {
+	type PullRequest struct {
+		MergedBranch string
+	}
+
+	if err := x.Sync2(new(PullRequest)); err != nil {
+		return err
+	}
+
+	_, err := x.Exec("UPDATE `pull_request` SET merged_branch=base_branch WHERE has_merged=?", true)
+	return err
+}
@@ -0,0 +1,18 @@
+// Copyright 2020 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package migrations
+
+import (
+	"xorm.io/xorm"
+)
+
+func addPullRequestRevertedBy(x *xorm.Engine) error {
+	type PullRequest struct {
+		RevertedByCommitID string `xorm:"VARCHAR(40)"`
+		RevertedByPullID   int64
+	}
+
+	return x.Sync2(new(PullRequest))
+}
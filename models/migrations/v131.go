@@ -0,0 +1,24 @@
+// Copyright 2020 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package migrations
+
+import (
+	"code.gitea.io/gitea/modules/timeutil"
+
+	"xorm.io/xorm"
+)
+
+func addPullViewedFile(x *xorm.Engine) error {
+	type PullViewedFile struct {
+		ID          int64              `xorm:"pk autoincr"`
+		IssueID     int64              `xorm:"INDEX NOT NULL"`
+		UserID      int64              `xorm:"INDEX NOT NULL"`
+		TreePath    string             `xorm:"VARCHAR(4000) NOT NULL"`
+		CommitID    string             `xorm:"VARCHAR(40) NOT NULL"`
+		UpdatedUnix timeutil.TimeStamp `xorm:"updated"`
+	}
+
+	return x.Sync2(new(PullViewedFile))
+}
@@ -0,0 +1,17 @@
+// Copyright 2020 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package migrations
+
+import (
+	"xorm.io/xorm"
+)
+
+func addPullRequestConflictedHunks(x *xorm.Engine) error {
+	type PullRequest struct {
+		ConflictedHunks map[string][]string `xorm:"TEXT JSON"`
+	}
+
+	return x.Sync2(new(PullRequest))
+}
@@ -0,0 +1,20 @@
+// Copyright 2020 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package migrations
+
+import (
+	"code.gitea.io/gitea/modules/timeutil"
+
+	"xorm.io/xorm"
+)
+
+func addPullRequestLastCommitStatus(x *xorm.Engine) error {
+	type PullRequest struct {
+		LastCommitStatusState   string `xorm:"VARCHAR(7)"`
+		LastCommitStatusUpdated timeutil.TimeStamp
+	}
+
+	return x.Sync2(new(PullRequest))
+}
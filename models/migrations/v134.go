@@ -0,0 +1,17 @@
+// Copyright 2020 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package migrations
+
+import (
+	"xorm.io/xorm"
+)
+
+func addHookTaskBodySignature(x *xorm.Engine) error {
+	type HookTask struct {
+		BodySignature string `xorm:"TEXT"`
+	}
+
+	return x.Sync2(new(HookTask))
+}
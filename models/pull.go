@@ -8,12 +8,15 @@ package models
 import (
 	"fmt"
 	"io"
+	"strconv"
 	"strings"
 
 	"code.gitea.io/gitea/modules/git"
 	"code.gitea.io/gitea/modules/log"
 	"code.gitea.io/gitea/modules/setting"
 	"code.gitea.io/gitea/modules/timeutil"
+
+	"xorm.io/builder"
 )
 
 // PullRequestType defines pull request type
@@ -35,6 +38,7 @@ const (
 	PullRequestStatusMergeable
 	PullRequestStatusManuallyMerged
 	PullRequestStatusError
+	PullRequestStatusPatchTooLarge
 )
 
 // PullRequest represents relation between pull request and repositories.
@@ -43,6 +47,8 @@ type PullRequest struct {
 	Type            PullRequestType
 	Status          PullRequestStatus
 	ConflictedFiles []string `xorm:"TEXT JSON"`
+	// the line numbers of the hunks which failed to apply, by conflicted file
+	ConflictedHunks map[string][]string `xorm:"TEXT JSON"`
 
 	IssueID int64  `xorm:"INDEX"`
 	Issue   *Issue `xorm:"-"`
@@ -56,12 +62,24 @@ type PullRequest struct {
 	BaseBranch      string
 	ProtectedBranch *ProtectedBranch `xorm:"-"`
 	MergeBase       string           `xorm:"VARCHAR(40)"`
+	HeadCommitID    string           `xorm:"VARCHAR(40)"` // head commit of the last push to the head branch
+
+	// Combined commit status of the head, cached by UpdateCachedCommitStatus on every push to the
+	// head branch and expired when a new status is created for HeadCommitID. Added by migration v133;
+	// rows from before it have LastCommitStatusUpdated 0 and are always recomputed.
+	LastCommitStatusState   CommitStatusState `xorm:"VARCHAR(7)"`
+	LastCommitStatusUpdated timeutil.TimeStamp
 
-	HasMerged      bool               `xorm:"INDEX"`
-	MergedCommitID string             `xorm:"VARCHAR(40)"`
-	MergerID       int64              `xorm:"INDEX"`
-	Merger         *User              `xorm:"-"`
-	MergedUnix     timeutil.TimeStamp `xorm:"updated INDEX"`
+	HasMerged       bool               `xorm:"INDEX"`
+	MergedCommitID  string             `xorm:"VARCHAR(40)"`
+	MergerID        int64              `xorm:"INDEX"`
+	Merger          *User              `xorm:"-"`
+	MergedUnix      timeutil.TimeStamp `xorm:"updated INDEX"`
+	MergedReviewers []int64            `xorm:"JSON TEXT"` // users whose latest review was a current approval at merge time
+	MergedBranch    string             // branch the pull request was merged into, usually the base branch
+
+	RevertedByCommitID string `xorm:"VARCHAR(40)"` // commit reverting the merged pull request
+	RevertedByPullID   int64  // pull request which merged the reverting commit, 0 if none
 }
 
 // MustHeadUserName returns the HeadRepo's username if failed return blank
@@ -146,6 +164,17 @@ func (pr *PullRequest) loadIssue(e Engine) (err error) {
 	return err
 }
 
+// GetLabels loads the issue of the pull request and its labels if needed and returns the labels
+func (pr *PullRequest) GetLabels() ([]*Label, error) {
+	if err := pr.loadIssue(x); err != nil {
+		return nil, err
+	}
+	if err := pr.Issue.loadLabels(x); err != nil {
+		return nil, err
+	}
+	return pr.Issue.Labels, nil
+}
+
 // LoadProtectedBranch loads the protected branch of the base branch
 func (pr *PullRequest) LoadProtectedBranch() (err error) {
 	return pr.loadProtectedBranch(x)
@@ -400,6 +429,75 @@ func (pr *PullRequest) GetDefaultSquashMessage() string {
 	return fmt.Sprintf("%s (#%d)", pr.Issue.Title, pr.Issue.Index)
 }
 
+// GetDefaultSquashMessageWithCoAuthors returns the default squash message followed by a
+// Co-authored-by trailer for every distinct author of the commits of the pull request,
+// in the order they contributed. The poster of the pull request and bots are left out.
+func (pr *PullRequest) GetDefaultSquashMessageWithCoAuthors() (string, error) {
+	if err := pr.LoadIssue(); err != nil {
+		return "", err
+	}
+	if err := pr.LoadBaseRepo(); err != nil {
+		return "", err
+	}
+	message := pr.GetDefaultSquashMessage()
+
+	if err := pr.Issue.LoadPoster(); err != nil {
+		return "", err
+	}
+	if err := pr.GetHeadRepo(); err != nil {
+		return "", err
+	} else if pr.HeadRepo == nil {
+		return "", ErrRepoNotExist{ID: pr.HeadRepoID}
+	}
+
+	gitRepo, err := git.OpenRepository(pr.HeadRepo.RepoPath())
+	if err != nil {
+		return "", err
+	}
+	defer gitRepo.Close()
+
+	headCommit, err := gitRepo.GetBranchCommit(pr.HeadBranch)
+	if err != nil {
+		return "", fmt.Errorf("GetBranchCommit: %v", err)
+	}
+	mergeBase, err := gitRepo.GetCommit(pr.MergeBase)
+	if err != nil {
+		return "", fmt.Errorf("GetCommit: %v", err)
+	}
+	commits, err := gitRepo.CommitsBetween(headCommit, mergeBase)
+	if err != nil {
+		return "", fmt.Errorf("CommitsBetween: %v", err)
+	}
+
+	seen := map[string]bool{strings.ToLower(pr.Issue.Poster.Email): true}
+	trailers := strings.Builder{}
+	// commits are listed newest first
+	for element := commits.Back(); element != nil; element = element.Prev() {
+		author := element.Value.(*git.Commit).Author
+		email := strings.ToLower(author.Email)
+		if seen[email] || isBotSignature(author) {
+			continue
+		}
+		seen[email] = true
+		trailers.WriteString("Co-authored-by: " + author.String() + "\n")
+	}
+
+	if trailers.Len() == 0 {
+		return message, nil
+	}
+	return message + "\n\n" + trailers.String(), nil
+}
+
+// isBotSignature returns whether the signature is one of a bot, which by convention have
+// a name or email user ending in "[bot]".
+func isBotSignature(sig *git.Signature) bool {
+	emailUser := sig.Email
+	if i := strings.IndexByte(emailUser, '@'); i >= 0 {
+		emailUser = emailUser[:i]
+	}
+	return strings.HasSuffix(sig.Name, "[bot]") || strings.HasSuffix(emailUser, "[bot]")
+}
+
 // GetGitRefName returns git ref for hidden pull request branch
 func (pr *PullRequest) GetGitRefName() string {
 	return fmt.Sprintf("refs/pull/%d/head", pr.Index)
@@ -436,38 +534,80 @@ func (pr *PullRequest) IsChecking() bool {
 	return pr.Status == PullRequestStatusChecking
 }
 
+// IsPatchTooLarge returns true if the patch of this pull request exceeds the maximum size
+// and it has therefore not been checked for conflicts.
+func (pr *PullRequest) IsPatchTooLarge() bool {
+	return pr.Status == PullRequestStatusPatchTooLarge
+}
+
+// IsSameRef returns true if the head and base of this pull request are the same branch of the same repository.
+func (pr *PullRequest) IsSameRef() bool {
+	return pr.HeadRepoID == pr.BaseRepoID && pr.HeadBranch == pr.BaseBranch
+}
+
 // CanAutoMerge returns true if this pull request can be merged automatically.
 func (pr *PullRequest) CanAutoMerge() bool {
 	return pr.Status == PullRequestStatusMergeable
 }
 
-// GetLastCommitStatus returns the last commit status for this pull request.
-func (pr *PullRequest) GetLastCommitStatus() (status *CommitStatus, err error) {
-	if err = pr.GetHeadRepo(); err != nil {
-		return nil, err
+// CloseWithComment posts the given comment and closes the pull request in one transaction.
+// If the pull request is already closed only the comment is posted and the returned close
+// comment is nil.
+func (pr *PullRequest) CloseWithComment(doer *User, content string) (comment, closeComment *Comment, err error) {
+	return pr.closeWithComment(doer, CommentTypeComment, content)
+}
+
+// CloseAsStale is like CloseWithComment, but posts a comment telling the pull request was
+// closed automatically because it had no activity for the given number of days.
+func (pr *PullRequest) CloseAsStale(doer *User, days int64) (comment, closeComment *Comment, err error) {
+	return pr.closeWithComment(doer, CommentTypeCloseStale, strconv.FormatInt(days, 10))
+}
+
+func (pr *PullRequest) closeWithComment(doer *User, commentType CommentType, content string) (comment, closeComment *Comment, err error) {
+	sess := x.NewSession()
+	defer sess.Close()
+	if err = sess.Begin(); err != nil {
+		return nil, nil, err
 	}
 
-	if pr.HeadRepo == nil {
-		return nil, ErrPullRequestHeadRepoMissing{pr.ID, pr.HeadRepoID}
+	if err = pr.loadIssue(sess); err != nil {
+		return nil, nil, err
+	}
+	if err = pr.Issue.loadRepo(sess); err != nil {
+		return nil, nil, err
+	}
+	if err = pr.Issue.loadPoster(sess); err != nil {
+		return nil, nil, err
 	}
 
-	headGitRepo, err := git.OpenRepository(pr.HeadRepo.RepoPath())
+	comment, err = createComment(sess, &CreateCommentOptions{
+		Type:    commentType,
+		Doer:    doer,
+		Repo:    pr.Issue.Repo,
+		Issue:   pr.Issue,
+		Content: content,
+	})
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	defer headGitRepo.Close()
 
-	lastCommitID, err := headGitRepo.GetBranchCommitID(pr.HeadBranch)
+	closeComment, err = pr.Issue.changeStatus(sess, doer, true)
 	if err != nil {
-		return nil, err
+		if !IsErrPullWasClosed(err) {
+			return nil, nil, err
+		}
+		closeComment = nil
 	}
 
-	err = pr.LoadBaseRepo()
-	if err != nil {
-		return nil, err
+	if err = sess.Commit(); err != nil {
+		return nil, nil, fmt.Errorf("Commit: %v", err)
 	}
+	return comment, closeComment, nil
+}
 
-	statusList, err := GetLatestCommitStatus(pr.BaseRepo, lastCommitID, 0)
+// GetLastCommitStatus returns the last commit status for this pull request.
+func (pr *PullRequest) GetLastCommitStatus() (status *CommitStatus, err error) {
+	statusList, err := pr.GetCommitStatuses()
 	if err != nil {
 		return nil, err
 	}
@@ -486,6 +626,8 @@ const (
 	MergeStyleRebaseMerge MergeStyle = "rebase-merge"
 	// MergeStyleSquash squash commits into single commit before merging
 	MergeStyleSquash MergeStyle = "squash"
+	// MergeStyleFastForwardOnly fast-forward the base branch to the head, never creating a merge commit (--ff-only)
+	MergeStyleFastForwardOnly MergeStyle = "fast-forward-only"
 )
 
 // SetMerged sets a pull request to merged and closes the corresponding issue
@@ -498,6 +640,9 @@ func (pr *PullRequest) SetMerged() (err error) {
 	}
 
 	pr.HasMerged = true
+	if pr.MergedBranch == "" {
+		pr.MergedBranch = pr.BaseBranch
+	}
 
 	sess := x.NewSession()
 	defer sess.Close()
@@ -516,12 +661,19 @@ func (pr *PullRequest) SetMerged() (err error) {
 		return err
 	}
 
+	if pr.MergedReviewers, err = pr.getCurrentApproverIDs(sess); err != nil {
+		return fmt.Errorf("getCurrentApproverIDs: %v", err)
+	}
+
 	if _, err = pr.Issue.changeStatus(sess, pr.Merger, true); err != nil {
 		return fmt.Errorf("Issue.changeStatus: %v", err)
 	}
-	if _, err = sess.ID(pr.ID).Cols("has_merged, status, merged_commit_id, merger_id, merged_unix").Update(pr); err != nil {
+	if _, err = sess.ID(pr.ID).Cols("has_merged, status, merged_commit_id, merger_id, merged_unix, merged_reviewers, merged_branch").Update(pr); err != nil {
 		return fmt.Errorf("update pull request: %v", err)
 	}
+	if _, err = sess.Exec("UPDATE `repository` SET num_merged_pulls=num_merged_pulls+1 WHERE id=?", pr.BaseRepoID); err != nil {
+		return fmt.Errorf("update repository merged pulls count: %v", err)
+	}
 
 	if err = sess.Commit(); err != nil {
 		return fmt.Errorf("Commit: %v", err)
@@ -529,6 +681,31 @@ func (pr *PullRequest) SetMerged() (err error) {
 	return nil
 }
 
+// getCurrentApproverIDs returns the ids of the users whose latest review of the pull request
+// is an approval which has not become stale.
+func (pr *PullRequest) getCurrentApproverIDs(e Engine) ([]int64, error) {
+	reviews := make([]*Review, 0, 10)
+	if err := e.Where("issue_id = ?", pr.IssueID).
+		In("type", ReviewTypeApprove, ReviewTypeReject).
+		Asc("id").
+		Find(&reviews); err != nil {
+		return nil, err
+	}
+
+	latest := make(map[int64]*Review, len(reviews))
+	for _, review := range reviews {
+		latest[review.ReviewerID] = review
+	}
+
+	approverIDs := make([]int64, 0, len(latest))
+	for _, review := range reviews {
+		if latest[review.ReviewerID] == review && review.Type == ReviewTypeApprove && !review.Stale {
+			approverIDs = append(approverIDs, review.ReviewerID)
+		}
+	}
+	return approverIDs, nil
+}
+
 // NewPullRequest creates new pull request with labels for repository.
 func NewPullRequest(repo *Repository, pull *Issue, labelIDs []int64, uuids []string, pr *PullRequest) (err error) {
 	// Retry several times in case INSERT fails due to duplicate key for (repo_id, index); see #7887
@@ -673,6 +850,27 @@ func GetPullRequestByIssueID(issueID int64) (*PullRequest, error) {
 	return getPullRequestByIssueID(x, issueID)
 }
 
+// GetPullRequestsByIssueIDs returns the pull requests of the given issue IDs, keyed by issue ID.
+// Issues without a pull request are absent from the map.
+func GetPullRequestsByIssueIDs(issueIDs []int64) (map[int64]*PullRequest, error) {
+	prMap := make(map[int64]*PullRequest, len(issueIDs))
+	if len(issueIDs) == 0 {
+		return prMap, nil
+	}
+
+	prs := make([]*PullRequest, 0, len(issueIDs))
+	if err := x.In("issue_id", issueIDs).Find(&prs); err != nil {
+		return nil, err
+	}
+	for _, pr := range prs {
+		if err := pr.loadAttributes(x); err != nil {
+			return nil, err
+		}
+		prMap[pr.IssueID] = pr
+	}
+	return prMap, nil
+}
+
 // Update updates all fields of pull request.
 func (pr *PullRequest) Update() error {
 	_, err := x.ID(pr.ID).AllCols().Update(pr)
@@ -687,17 +885,35 @@ func (pr *PullRequest) UpdateCols(cols ...string) error {
 
 // IsWorkInProgress determine if the Pull Request is a Work In Progress by its title
 func (pr *PullRequest) IsWorkInProgress() bool {
-	if err := pr.LoadIssue(); err != nil {
-		log.Error("LoadIssue: %v", err)
-		return false
+	return pr.GetWorkInProgressPrefix() != ""
+}
+
+// GetWorkInProgressPrefixes returns the title prefixes marking pull requests of the repository
+// as a work in progress, falling back to the global setting when it defines none.
+func (repo *Repository) GetWorkInProgressPrefixes() []string {
+	unit, err := repo.GetUnit(UnitTypePullRequests)
+	if err != nil {
+		return setting.Repository.PullRequest.WorkInProgressPrefixes
 	}
+	return unit.PullRequestsConfig().GetWorkInProgressPrefixes()
+}
 
-	for _, prefix := range setting.Repository.PullRequest.WorkInProgressPrefixes {
-		if strings.HasPrefix(strings.ToUpper(pr.Issue.Title), prefix) {
-			return true
+// HasWorkInProgressPrefix determines if the given pull request title marks it as a work in progress
+func (repo *Repository) HasWorkInProgressPrefix(title string) bool {
+	return workInProgressPrefix(repo.GetWorkInProgressPrefixes(), title) != ""
+}
+
+// workInProgressPrefix returns the part of title matching one of prefixes, ignoring case.
+func workInProgressPrefix(prefixes []string, title string) string {
+	titleRunes := []rune(title)
+	for _, prefix := range prefixes {
+		// compare runes as the case mappings of a character may differ in length
+		n := len([]rune(prefix))
+		if n > 0 && n <= len(titleRunes) && strings.EqualFold(string(titleRunes[:n]), prefix) {
+			return string(titleRunes[:n])
 		}
 	}
-	return false
+	return ""
 }
 
 // IsFilesConflicted determines if the  Pull Request has changes conflicting with the target branch.
@@ -712,25 +928,21 @@ func (pr *PullRequest) GetWorkInProgressPrefix() string {
 		log.Error("LoadIssue: %v", err)
 		return ""
 	}
-
-	for _, prefix := range setting.Repository.PullRequest.WorkInProgressPrefixes {
-		if strings.HasPrefix(strings.ToUpper(pr.Issue.Title), prefix) {
-			return pr.Issue.Title[0:len(prefix)]
-		}
+	if err := pr.LoadBaseRepo(); err != nil {
+		log.Error("LoadBaseRepo: %v", err)
+		return ""
 	}
-	return ""
+
+	return workInProgressPrefix(pr.BaseRepo.GetWorkInProgressPrefixes(), pr.Issue.Title)
 }
 
 // IsHeadEqualWithBranch returns if the commits of branchName are available in pull request head
 func (pr *PullRequest) IsHeadEqualWithBranch(branchName string) (bool, error) {
-	var err error
-	if err = pr.GetBaseRepo(); err != nil {
-		return false, err
-	}
-	baseGitRepo, err := git.OpenRepository(pr.BaseRepo.RepoPath())
+	baseGitRepo, err := pr.baseGitRepo()
 	if err != nil {
 		return false, err
 	}
+	defer baseGitRepo.Close()
 	baseCommit, err := baseGitRepo.GetBranchCommit(branchName)
 	if err != nil {
 		return false, err
@@ -750,6 +962,38 @@ func (pr *PullRequest) IsHeadEqualWithBranch(branchName string) (bool, error) {
 	return baseCommit.HasPreviousCommit(headCommit.ID)
 }
 
+// UnresolvedReviewThreadCount returns the number of conversations of the pull request which are still
+// valid and have not been marked as resolved. A conversation consists of the published code comments
+// on the same line of the same file.
+func (pr *PullRequest) UnresolvedReviewThreadCount() (int64, error) {
+	threads := builder.Select("comment.tree_path, comment.line").From("comment").
+		LeftJoin("review", "review.id = comment.review_id").
+		Where(builder.Eq{
+			"comment.issue_id":    pr.IssueID,
+			"comment.type":        CommentTypeCode,
+			"comment.invalidated": false,
+			"comment.is_resolved": false,
+		}).
+		And(builder.Or(builder.IsNull{"review.id"}, builder.Neq{"review.type": ReviewTypePending})).
+		GroupBy("comment.tree_path, comment.line")
+
+	var count int64
+	if _, err := x.SQL(builder.Select("COUNT(*)").From(threads, "threads")).Get(&count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// HasUnresolvedConversations returns whether the pull request has conversations which are still
+// valid and have not been marked as resolved.
+func (pr *PullRequest) HasUnresolvedConversations() (bool, error) {
+	count, err := pr.UnresolvedReviewThreadCount()
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
 // IsSameRepo returns true if base repo and head repo is the same
 func (pr *PullRequest) IsSameRepo() bool {
 	return pr.BaseRepoID == pr.HeadRepoID
@@ -0,0 +1,235 @@
+// Copyright 2020 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"strings"
+
+	"code.gitea.io/gitea/modules/git"
+	"code.gitea.io/gitea/modules/log"
+)
+
+// codeOwnersPaths are the paths a CODEOWNERS file is looked up at, in order
+var codeOwnersPaths = []string{".gitea/CODEOWNERS", "CODEOWNERS", "docs/CODEOWNERS"}
+
+// maxCodeOwnersSize is the maximum size of a CODEOWNERS file which is read
+const maxCodeOwnersSize = 3 * 1024 * 1024
+
+// codeOwnersRule is a line of a CODEOWNERS file, the owners of the files matching the pattern
+type codeOwnersRule struct {
+	pattern *regexp.Regexp
+	owners  []string
+}
+
+// parseCodeOwners parses the rules of a CODEOWNERS file, invalid lines are skipped.
+func parseCodeOwners(r io.Reader) ([]*codeOwnersRule, error) {
+	var rules []*codeOwnersRule
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		pattern, err := codeOwnersPatternToRegexp(fields[0])
+		if err != nil {
+			log.Debug("Skipping invalid CODEOWNERS pattern %q: %v", fields[0], err)
+			continue
+		}
+		rules = append(rules, &codeOwnersRule{pattern: pattern, owners: fields[1:]})
+	}
+	return rules, scanner.Err()
+}
+
+// codeOwnersPatternToRegexp converts a gitignore style pattern of a CODEOWNERS file to a regular
+// expression matching the paths of the files it applies to.
+func codeOwnersPatternToRegexp(pattern string) (*regexp.Regexp, error) {
+	// a pattern containing a slash anywhere but at its end is relative to the root
+	anchored := strings.Contains(strings.TrimSuffix(pattern, "/"), "/")
+	pattern = strings.TrimPrefix(pattern, "/")
+	dirOnly := strings.HasSuffix(pattern, "/")
+	pattern = strings.TrimSuffix(pattern, "/")
+	if pattern == "" {
+		return nil, fmt.Errorf("empty pattern")
+	}
+
+	var re strings.Builder
+	re.WriteString("^")
+	if !anchored {
+		re.WriteString("(?:.*/)?")
+	}
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '*':
+			if i+1 < len(pattern) && pattern[i+1] == '*' {
+				if i+2 < len(pattern) && pattern[i+2] == '/' {
+					// "**/" matches zero or more directories
+					re.WriteString("(?:.*/)?")
+					i += 2
+				} else {
+					re.WriteString(".*")
+					i++
+				}
+			} else {
+				re.WriteString("[^/]*")
+			}
+		case '?':
+			re.WriteString("[^/]")
+		default:
+			re.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+
+	lastSegment := pattern[strings.LastIndexByte(pattern, '/')+1:]
+	if dirOnly {
+		re.WriteString("/.*")
+	} else if !strings.ContainsAny(lastSegment, "*?") {
+		// a plain name matches a file as well as everything inside a directory of that name,
+		// while e.g. "docs/*" only matches the files directly inside docs
+		re.WriteString("(?:/.*)?")
+	}
+	re.WriteString("$")
+	return regexp.Compile(re.String())
+}
+
+// ownersOf returns the owners of the file at path: those of the last matching rule.
+func ownersOf(rules []*codeOwnersRule, path string) []string {
+	for i := len(rules) - 1; i >= 0; i-- {
+		if rules[i].pattern.MatchString(path) {
+			return rules[i].owners
+		}
+	}
+	return nil
+}
+
+// readCodeOwners returns the rules of the CODEOWNERS file of the given commit, nil if there is none.
+func readCodeOwners(commit *git.Commit) ([]*codeOwnersRule, error) {
+	for _, path := range codeOwnersPaths {
+		blob, err := commit.GetBlobByPath(path)
+		if err != nil {
+			if git.IsErrNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		rc, err := blob.DataAsync()
+		if err != nil {
+			return nil, err
+		}
+		content, err := ioutil.ReadAll(io.LimitReader(rc, maxCodeOwnersSize))
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+		return parseCodeOwners(bytes.NewReader(content))
+	}
+	return nil, nil
+}
+
+// GetCodeownerReviewers returns the owners of the files changed by the pull request according to
+// the CODEOWNERS file of its base branch, the last matching pattern of a file deciding its owners.
+// Owners are given as @user, @org/team or e-mail address. Teams are returned as they are, callers
+// wanting their members can load them with Team.GetMembers. Unknown owners, teams of other
+// organizations than the repository owner and the poster of the pull request are skipped.
+func (pr *PullRequest) GetCodeownerReviewers() (users []*User, teams []*Team, err error) {
+	if pr.MergeBase == "" {
+		return nil, nil, fmt.Errorf("pull request %d has no merge base", pr.ID)
+	}
+	if err = pr.LoadIssue(); err != nil {
+		return nil, nil, err
+	}
+	if err = pr.GetBaseRepo(); err != nil {
+		return nil, nil, err
+	}
+	if err = pr.BaseRepo.GetOwner(); err != nil {
+		return nil, nil, err
+	}
+
+	gitRepo, err := pr.baseGitRepo()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer gitRepo.Close()
+
+	commit, err := gitRepo.GetBranchCommit(pr.BaseBranch)
+	if err != nil {
+		return nil, nil, fmt.Errorf("GetBranchCommit(%s): %v", pr.BaseBranch, err)
+	}
+	rules, err := readCodeOwners(commit)
+	if err != nil {
+		return nil, nil, fmt.Errorf("readCodeOwners: %v", err)
+	}
+	if len(rules) == 0 {
+		return nil, nil, nil
+	}
+
+	stdout, err := git.NewCommand("diff", "--name-only", "-z", "--no-renames", pr.MergeBase, pr.GetGitRefName()).
+		RunInDir(pr.BaseRepo.RepoPath())
+	if err != nil {
+		return nil, nil, fmt.Errorf("diff --name-only: %v", err)
+	}
+
+	seen := make(map[string]bool)
+	for _, path := range strings.Split(stdout, "\x00") {
+		if path == "" {
+			continue
+		}
+		for _, owner := range ownersOf(rules, path) {
+			if seen[owner] {
+				continue
+			}
+			seen[owner] = true
+
+			user, team, err := pr.resolveCodeOwner(owner)
+			if err != nil {
+				return nil, nil, err
+			}
+			if user != nil && user.ID != pr.Issue.PosterID {
+				users = append(users, user)
+			} else if team != nil {
+				teams = append(teams, team)
+			}
+		}
+	}
+	return users, teams, nil
+}
+
+// resolveCodeOwner returns the user or team an owner of a CODEOWNERS file refers to, neither if it is unknown.
+func (pr *PullRequest) resolveCodeOwner(owner string) (*User, *Team, error) {
+	if !strings.HasPrefix(owner, "@") {
+		user, err := GetUserByEmail(owner)
+		if IsErrUserNotExist(err) {
+			return nil, nil, nil
+		}
+		return user, nil, err
+	}
+
+	owner = owner[1:]
+	if i := strings.IndexByte(owner, '/'); i >= 0 {
+		if !strings.EqualFold(owner[:i], pr.BaseRepo.Owner.Name) || !pr.BaseRepo.Owner.IsOrganization() {
+			return nil, nil, nil
+		}
+		team, err := GetTeam(pr.BaseRepo.OwnerID, owner[i+1:])
+		if IsErrTeamNotExist(err) {
+			return nil, nil, nil
+		}
+		return nil, team, err
+	}
+
+	user, err := GetUserByName(owner)
+	if IsErrUserNotExist(err) || (err == nil && user.IsOrganization()) {
+		return nil, nil, nil
+	}
+	return user, nil, err
+}
@@ -0,0 +1,91 @@
+// Copyright 2020 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCodeOwnersPatternToRegexp(t *testing.T) {
+	kases := []struct {
+		pattern    string
+		matches    []string
+		notMatches []string
+	}{
+		{"*", []string{"README.md", "docs/index.md"}, nil},
+		{"*.js", []string{"app.js", "web_src/js/index.js"}, []string{"app.jsx"}},
+		{"/build/logs/", []string{"build/logs/1.log", "build/logs/a/b.log"}, []string{"build/logs", "a/build/logs/1.log"}},
+		{"docs/*", []string{"docs/index.md"}, []string{"docs/usage/index.md", "a/docs/index.md"}},
+		{"apps/", []string{"apps/a.go", "cmd/apps/a.go"}, []string{"apps"}},
+		{"/docs", []string{"docs", "docs/index.md"}, []string{"a/docs/index.md"}},
+		{"**/logs", []string{"logs", "build/logs", "build/logs/1.log"}, []string{"build/logs.txt"}},
+		{"docs/**/*.md", []string{"docs/index.md", "docs/a/b/index.md"}, []string{"docs/index.txt"}},
+		{"file?.txt", []string{"file1.txt"}, []string{"file10.txt", "file/.txt"}},
+	}
+	for _, kase := range kases {
+		re, err := codeOwnersPatternToRegexp(kase.pattern)
+		assert.NoError(t, err)
+		for _, path := range kase.matches {
+			assert.True(t, re.MatchString(path), "%q should match %q", kase.pattern, path)
+		}
+		for _, path := range kase.notMatches {
+			assert.False(t, re.MatchString(path), "%q should not match %q", kase.pattern, path)
+		}
+	}
+}
+
+func TestParseCodeOwners(t *testing.T) {
+	rules, err := parseCodeOwners(strings.NewReader(`# default owners
+*       @user2 @user3/team1
+
+*.go    user5@example.com # go code
+/docs/
+`))
+	assert.NoError(t, err)
+	assert.Len(t, rules, 3)
+
+	// the last matching rule wins, even without owners
+	assert.Equal(t, []string{"@user2", "@user3/team1"}, ownersOf(rules, "README.md"))
+	assert.Equal(t, []string{"user5@example.com"}, ownersOf(rules, "models/pull.go"))
+	assert.Empty(t, ownersOf(rules, "docs/index.md"))
+}
+
+func TestPullRequest_resolveCodeOwner(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+	pr := &PullRequest{BaseRepoID: 3}
+	assert.NoError(t, pr.GetBaseRepo())
+	assert.NoError(t, pr.BaseRepo.GetOwner())
+
+	user, team, err := pr.resolveCodeOwner("@user2")
+	assert.NoError(t, err)
+	assert.Nil(t, team)
+	if assert.NotNil(t, user) {
+		assert.EqualValues(t, 2, user.ID)
+	}
+
+	user, team, err = pr.resolveCodeOwner("user5@example.com")
+	assert.NoError(t, err)
+	assert.Nil(t, team)
+	if assert.NotNil(t, user) {
+		assert.EqualValues(t, 5, user.ID)
+	}
+
+	user, team, err = pr.resolveCodeOwner("@user3/team1")
+	assert.NoError(t, err)
+	assert.Nil(t, user)
+	if assert.NotNil(t, team) {
+		assert.EqualValues(t, 2, team.ID)
+	}
+
+	for _, owner := range []string{"@user3", "@user2/team1", "@user3/missing", "@missing", "missing@example.com"} {
+		user, team, err = pr.resolveCodeOwner(owner)
+		assert.NoError(t, err)
+		assert.Nil(t, user, owner)
+		assert.Nil(t, team, owner)
+	}
+}
@@ -0,0 +1,170 @@
+// Copyright 2020 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"time"
+
+	"code.gitea.io/gitea/modules/git"
+	"code.gitea.io/gitea/modules/timeutil"
+)
+
+// UpdateCachedCommitStatus recomputes the combined commit status of the head and caches its state
+// on the pull request.
+func (pr *PullRequest) UpdateCachedCommitStatus() (*CommitStatus, error) {
+	status, err := pr.GetLastCommitStatus()
+	if err != nil {
+		return nil, err
+	}
+
+	pr.LastCommitStatusState = status.State
+	pr.LastCommitStatusUpdated = timeutil.TimeStampNow()
+	_, err = x.ID(pr.ID).Cols("last_commit_status_state", "last_commit_status_updated").NoAutoTime().Update(pr)
+	return status, err
+}
+
+// GetCachedCommitStatus returns the cached combined commit status of the head if it is younger than
+// maxAge and recomputes it otherwise. A cached status only carries its state.
+func (pr *PullRequest) GetCachedCommitStatus(maxAge time.Duration) (*CommitStatus, error) {
+	if pr.LastCommitStatusUpdated > 0 && time.Since(pr.LastCommitStatusUpdated.AsTime()) < maxAge {
+		return &CommitStatus{
+			RepoID: pr.BaseRepoID,
+			SHA:    pr.HeadCommitID,
+			State:  pr.LastCommitStatusState,
+		}, nil
+	}
+	return pr.UpdateCachedCommitStatus()
+}
+
+// GetCommitStatuses returns the latest commit status of every context for the head commit
+// of this pull request.
+func (pr *PullRequest) GetCommitStatuses() ([]*CommitStatus, error) {
+	if err := pr.GetHeadRepo(); err != nil {
+		return nil, err
+	}
+
+	if pr.HeadRepo == nil {
+		return nil, ErrPullRequestHeadRepoMissing{pr.ID, pr.HeadRepoID}
+	}
+
+	headGitRepo, err := git.OpenRepository(pr.HeadRepo.RepoPath())
+	if err != nil {
+		return nil, err
+	}
+	defer headGitRepo.Close()
+
+	lastCommitID, err := headGitRepo.GetBranchCommitID(pr.HeadBranch)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = pr.LoadBaseRepo(); err != nil {
+		return nil, err
+	}
+
+	var statuses []*CommitStatus
+	for page := 0; ; page++ {
+		statusList, err := GetLatestCommitStatus(pr.BaseRepo, lastCommitID, page)
+		if err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, statusList...)
+		if len(statusList) < 10 {
+			return statuses, nil
+		}
+	}
+}
+
+// GetCheckRunSummary counts the latest commit statuses of the head commit of this pull
+// request by state. Warnings count as passed. Status check contexts required by the
+// protected base branch which have not been reported yet count as pending.
+func (pr *PullRequest) GetCheckRunSummary() (passed, failed, pending, errored int, err error) {
+	statuses, err := pr.GetCommitStatuses()
+	if err != nil {
+		return 0, 0, 0, 0, err
+	}
+	if err = pr.LoadProtectedBranch(); err != nil {
+		return 0, 0, 0, 0, err
+	}
+
+	var requiredContexts []string
+	if pr.ProtectedBranch != nil && pr.ProtectedBranch.EnableStatusCheck {
+		requiredContexts = pr.ProtectedBranch.StatusCheckContexts
+	}
+	passed, failed, pending, errored = summarizeCheckRuns(statuses, requiredContexts)
+	return passed, failed, pending, errored, nil
+}
+
+// summarizeCheckRuns counts the given statuses by state, required contexts which have not
+// been reported count as pending.
+func summarizeCheckRuns(statuses []*CommitStatus, requiredContexts []string) (passed, failed, pending, errored int) {
+	reported := make(map[string]bool, len(statuses))
+	for _, status := range statuses {
+		reported[status.Context] = true
+		switch status.State {
+		case CommitStatusSuccess, CommitStatusWarning:
+			passed++
+		case CommitStatusFailure:
+			failed++
+		case CommitStatusError:
+			errored++
+		default:
+			pending++
+		}
+	}
+
+	for _, context := range requiredContexts {
+		if !reported[context] {
+			pending++
+		}
+	}
+	return passed, failed, pending, errored
+}
+
+// RequiredStatusChecksState evaluates the latest commit statuses of the head commit of this
+// pull request against the status check contexts required by the protected base branch.
+// Contexts which are not required are ignored. Required contexts which have not been
+// reported yet are returned as missing, those reported with any state other than success
+// as failing. If status checks are not enabled on the base branch all checks pass, and if
+// no specific context is required the combined status of the head commit must be a success.
+func (pr *PullRequest) RequiredStatusChecksState() (allPassed bool, missing, failing []string, err error) {
+	if err = pr.LoadProtectedBranch(); err != nil {
+		return false, nil, nil, err
+	}
+	if pr.ProtectedBranch == nil || !pr.ProtectedBranch.EnableStatusCheck {
+		return true, nil, nil, nil
+	}
+
+	statuses, err := pr.GetCommitStatuses()
+	if err != nil {
+		return false, nil, nil, err
+	}
+
+	missing, failing = evaluateRequiredStatusChecks(statuses, pr.ProtectedBranch.StatusCheckContexts)
+	if len(pr.ProtectedBranch.StatusCheckContexts) == 0 {
+		status := CalcCommitStatus(statuses)
+		return status != nil && status.State == CommitStatusSuccess, nil, nil, nil
+	}
+	return len(missing) == 0 && len(failing) == 0, missing, failing, nil
+}
+
+// evaluateRequiredStatusChecks returns the required contexts which have not been reported
+// by the given statuses and those which have been reported with a state other than success.
+func evaluateRequiredStatusChecks(statuses []*CommitStatus, requiredContexts []string) (missing, failing []string) {
+	states := make(map[string]CommitStatusState, len(statuses))
+	for _, status := range statuses {
+		states[status.Context] = status.State
+	}
+
+	for _, context := range requiredContexts {
+		state, ok := states[context]
+		if !ok {
+			missing = append(missing, context)
+		} else if state != CommitStatusSuccess {
+			failing = append(failing, context)
+		}
+	}
+	return missing, failing
+}
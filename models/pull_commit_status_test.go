@@ -0,0 +1,133 @@
+// Copyright 2020 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"testing"
+	"time"
+
+	"code.gitea.io/gitea/modules/timeutil"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPullRequest_GetCachedCommitStatus(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+	pr := AssertExistsAndLoadBean(t, &PullRequest{ID: 2}).(*PullRequest)
+	pr.HeadCommitID = "985f0301dba5e7b34be866819cd15ad3d8f508ee"
+	pr.LastCommitStatusState = CommitStatusSuccess
+	pr.LastCommitStatusUpdated = timeutil.TimeStampNow()
+	assert.NoError(t, pr.UpdateCols("head_commit_id", "last_commit_status_state", "last_commit_status_updated"))
+
+	// a fresh cache is returned as is
+	status, err := pr.GetCachedCommitStatus(time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, CommitStatusSuccess, status.State)
+
+	// an expired cache is recomputed, the head has no statuses yet
+	pr.LastCommitStatusUpdated = timeutil.TimeStamp(time.Now().Add(-2 * time.Hour).Unix())
+	status, err = pr.GetCachedCommitStatus(time.Hour)
+	assert.NoError(t, err)
+	assert.Empty(t, status.State)
+	pr = AssertExistsAndLoadBean(t, &PullRequest{ID: 2}).(*PullRequest)
+	assert.Empty(t, pr.LastCommitStatusState)
+	assert.True(t, time.Since(pr.LastCommitStatusUpdated.AsTime()) < time.Hour)
+
+	// a new status of the head expires the cache
+	assert.NoError(t, NewCommitStatus(NewCommitStatusOptions{
+		Repo:         AssertExistsAndLoadBean(t, &Repository{ID: 1}).(*Repository),
+		Creator:      AssertExistsAndLoadBean(t, &User{ID: 2}).(*User),
+		SHA:          pr.HeadCommitID,
+		CommitStatus: &CommitStatus{State: CommitStatusFailure, Context: "ci"},
+	}))
+	pr = AssertExistsAndLoadBean(t, &PullRequest{ID: 2}).(*PullRequest)
+	assert.EqualValues(t, 0, pr.LastCommitStatusUpdated)
+	status, err = pr.GetCachedCommitStatus(time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, CommitStatusFailure, status.State)
+}
+
+func TestEvaluateRequiredStatusChecks(t *testing.T) {
+	statuses := []*CommitStatus{
+		{Context: "ci/build", State: CommitStatusSuccess},
+		{Context: "ci/test", State: CommitStatusFailure},
+		{Context: "ci/lint", State: CommitStatusPending},
+		{Context: "unrelated", State: CommitStatusError},
+	}
+
+	missing, failing := evaluateRequiredStatusChecks(statuses, []string{"ci/build", "ci/lint", "ci/deploy"})
+	assert.EqualValues(t, []string{"ci/deploy"}, missing)
+	assert.EqualValues(t, []string{"ci/lint"}, failing)
+
+	missing, failing = evaluateRequiredStatusChecks(statuses, []string{"ci/build"})
+	assert.Empty(t, missing)
+	assert.Empty(t, failing)
+}
+
+func TestSummarizeCheckRuns(t *testing.T) {
+	statuses := []*CommitStatus{
+		{Context: "ci/build", State: CommitStatusSuccess},
+		{Context: "ci/style", State: CommitStatusWarning},
+		{Context: "ci/test", State: CommitStatusFailure},
+		{Context: "ci/lint", State: CommitStatusPending},
+		{Context: "ci/docs", State: CommitStatusError},
+	}
+
+	kases := []struct {
+		requiredContexts                 []string
+		passed, failed, pending, errored int
+	}{
+		{nil, 2, 1, 1, 1},
+		{[]string{"ci/build", "ci/test"}, 2, 1, 1, 1},
+		{[]string{"ci/build", "ci/deploy", "ci/release"}, 2, 1, 3, 1},
+	}
+	for _, kase := range kases {
+		passed, failed, pending, errored := summarizeCheckRuns(statuses, kase.requiredContexts)
+		assert.Equal(t, kase.passed, passed)
+		assert.Equal(t, kase.failed, failed)
+		assert.Equal(t, kase.pending, pending)
+		assert.Equal(t, kase.errored, errored)
+	}
+}
+
+func TestPullRequest_GetCheckRunSummary(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+	pr := AssertExistsAndLoadBean(t, &PullRequest{ID: 2}).(*PullRequest)
+	repo := AssertExistsAndLoadBean(t, &Repository{ID: 1}).(*Repository)
+	user := AssertExistsAndLoadBean(t, &User{ID: 2}).(*User)
+
+	// the head of branch2 has no statuses yet
+	passed, failed, pending, errored, err := pr.GetCheckRunSummary()
+	assert.NoError(t, err)
+	assert.Equal(t, [4]int{0, 0, 0, 0}, [4]int{passed, failed, pending, errored})
+
+	for _, status := range []*CommitStatus{
+		{State: CommitStatusSuccess, Context: "ci/build"},
+		{State: CommitStatusFailure, Context: "ci/test"},
+	} {
+		assert.NoError(t, NewCommitStatus(NewCommitStatusOptions{
+			Repo:         repo,
+			Creator:      user,
+			SHA:          "985f0301dba5e7b34be866819cd15ad3d8f508ee",
+			CommitStatus: status,
+		}))
+	}
+	passed, failed, pending, errored, err = pr.GetCheckRunSummary()
+	assert.NoError(t, err)
+	assert.Equal(t, [4]int{1, 1, 0, 0}, [4]int{passed, failed, pending, errored})
+
+	// required contexts not reported yet are pending
+	_, err = x.Insert(&ProtectedBranch{
+		RepoID:              pr.BaseRepoID,
+		BranchName:          pr.BaseBranch,
+		EnableStatusCheck:   true,
+		StatusCheckContexts: []string{"ci/build", "ci/deploy"},
+	})
+	assert.NoError(t, err)
+	pr.ProtectedBranch = nil
+	passed, failed, pending, errored, err = pr.GetCheckRunSummary()
+	assert.NoError(t, err)
+	assert.Equal(t, [4]int{1, 1, 1, 0}, [4]int{passed, failed, pending, errored})
+}
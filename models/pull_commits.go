@@ -0,0 +1,269 @@
+// Copyright 2020 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"container/list"
+	"fmt"
+	"strings"
+	"time"
+
+	"code.gitea.io/gitea/modules/git"
+	"code.gitea.io/gitea/modules/references"
+)
+
+// baseGitRepo opens the git repository of the base repository of the pull request,
+// the caller must close it.
+func (pr *PullRequest) baseGitRepo() (*git.Repository, error) {
+	if err := pr.GetBaseRepo(); err != nil {
+		return nil, err
+	}
+	return git.OpenRepository(pr.BaseRepo.RepoPath())
+}
+
+// commits returns the commits of the pull request between its merge base and its head,
+// newest first.
+func (pr *PullRequest) commits() (*list.List, error) {
+	if pr.MergeBase == "" {
+		return nil, fmt.Errorf("pull request %d has no merge base", pr.ID)
+	}
+	gitRepo, err := pr.baseGitRepo()
+	if err != nil {
+		return nil, err
+	}
+	defer gitRepo.Close()
+
+	return gitRepo.CommitsBetweenIDs(pr.GetGitRefName(), pr.MergeBase)
+}
+
+// HasUnsignedCommits returns whether the pull request contains commits between its merge base
+// and head which do not carry a verified signature, together with the SHAs of these commits.
+func (pr *PullRequest) HasUnsignedCommits() (bool, []string, error) {
+	commits, err := pr.commits()
+	if err != nil {
+		return false, nil, err
+	}
+
+	var unsigned []string
+	for e := commits.Front(); e != nil; e = e.Next() {
+		commit := e.Value.(*git.Commit)
+		if verification := ParseCommitWithSignature(commit); !verification.Verified {
+			unsigned = append(unsigned, commit.ID.String())
+		}
+	}
+	return len(unsigned) > 0, unsigned, nil
+}
+
+// MissingSignoffCommits returns the commits of the pull request lacking a
+// Signed-off-by trailer of their author, as required by the Developer Certificate of Origin.
+func (pr *PullRequest) MissingSignoffCommits() ([]string, error) {
+	commits, err := pr.commits()
+	if err != nil {
+		return nil, err
+	}
+
+	var missing []string
+	for e := commits.Front(); e != nil; e = e.Next() {
+		commit := e.Value.(*git.Commit)
+		if !isSignedOffBy(commit.CommitMessage, commit.Author) {
+			missing = append(missing, commit.ID.String())
+		}
+	}
+	return missing, nil
+}
+
+// isSignedOffBy returns whether the commit message has a Signed-off-by trailer with the email of author
+func isSignedOffBy(message string, author *git.Signature) bool {
+	if author == nil {
+		return false
+	}
+	for _, line := range strings.Split(message, "\n") {
+		i := strings.IndexByte(line, ':')
+		if i < 0 || !strings.EqualFold(strings.TrimSpace(line[:i]), "Signed-off-by") {
+			continue
+		}
+		value := strings.TrimSpace(line[i+1:])
+		start, end := strings.LastIndexByte(value, '<'), strings.LastIndexByte(value, '>')
+		if start >= 0 && end > start && strings.EqualFold(strings.TrimSpace(value[start+1:end]), author.Email) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetContributors returns the distinct authors of the commits of the pull request.
+// Authors whose email belongs to a user are returned as users, all others as
+// their raw signatures.
+func (pr *PullRequest) GetContributors() ([]*User, []*git.Signature, error) {
+	commits, err := pr.commits()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var users []*User
+	var unmatched []*git.Signature
+	seenEmails := make(map[string]bool)
+	seenUsers := make(map[int64]bool)
+	for e := commits.Front(); e != nil; e = e.Next() {
+		author := e.Value.(*git.Commit).Author
+		if author == nil {
+			continue
+		}
+		email := strings.ToLower(author.Email)
+		if seenEmails[email] {
+			continue
+		}
+		seenEmails[email] = true
+
+		user, err := GetUserByEmail(author.Email)
+		if err != nil {
+			if !IsErrUserNotExist(err) {
+				return nil, nil, err
+			}
+			unmatched = append(unmatched, author)
+			continue
+		}
+		// users may have authored commits with several of their emails
+		if !seenUsers[user.ID] {
+			seenUsers[user.ID] = true
+			users = append(users, user)
+		}
+	}
+	return users, unmatched, nil
+}
+
+// GetDuplicateCommits returns the IDs of the commits of the pull request, oldest first,
+// whose changes already exist on the base branch since the merge base, e.g. because they
+// were cherry-picked or rebased onto it.
+func (pr *PullRequest) GetDuplicateCommits() ([]string, error) {
+	gitRepo, err := pr.baseGitRepo()
+	if err != nil {
+		return nil, err
+	}
+	defer gitRepo.Close()
+
+	return gitRepo.GetCherryPickedCommits(git.BranchPrefix+pr.BaseBranch, pr.GetGitRefName())
+}
+
+// WasHeadForcePushed returns whether updating the head branch from oldHead to newHead rewrote
+// its history, see IsForcePush.
+func (pr *PullRequest) WasHeadForcePushed(oldHead, newHead string) (bool, error) {
+	if err := pr.GetHeadRepo(); err != nil {
+		return false, err
+	}
+	if pr.HeadRepo == nil {
+		return false, ErrPullRequestHeadRepoMissing{pr.ID, pr.HeadRepoID}
+	}
+	return IsForcePush(pr.HeadRepo.RepoPath(), oldHead, newHead)
+}
+
+// IsForcePush returns whether updating a branch of the repository at repoPath from oldCommitID
+// to newCommitID rewrote its history, i.e. oldCommitID is not an ancestor of newCommitID.
+// An old commit which does not exist anymore, e.g. because it was garbage collected after
+// an earlier force push, is not an ancestor either.
+func IsForcePush(repoPath, oldCommitID, newCommitID string) (bool, error) {
+	if oldCommitID == "" || oldCommitID == git.EmptySHA || newCommitID == "" || newCommitID == git.EmptySHA || oldCommitID == newCommitID {
+		return false, nil
+	}
+
+	if _, err := git.NewCommand("cat-file", "-e", oldCommitID+"^{commit}").RunInDir(repoPath); err != nil {
+		return true, nil
+	}
+	output, err := git.NewCommand("rev-list", "--max-count=1", oldCommitID, "^"+newCommitID).RunInDir(repoPath)
+	if err != nil {
+		return false, fmt.Errorf("rev-list %s ^%s: %v", oldCommitID, newCommitID, err)
+	}
+	return len(strings.TrimSpace(output)) > 0, nil
+}
+
+// CommitDateRange returns the author dates of the oldest and newest commits of the pull request,
+// i.e. the commits between its merge base and its head. ErrPullRequestHasNoCommits is returned
+// if there are no such commits or the head is gone.
+func (pr *PullRequest) CommitDateRange() (first, last time.Time, err error) {
+	if pr.MergeBase == "" {
+		return first, last, ErrPullRequestHasNoCommits{pr.ID}
+	}
+	gitRepo, err := pr.baseGitRepo()
+	if err != nil {
+		return first, last, err
+	}
+	defer gitRepo.Close()
+
+	headCommitID, err := gitRepo.GetRefCommitID(pr.GetGitRefName())
+	if err != nil {
+		if git.IsErrNotExist(err) {
+			return first, last, ErrPullRequestHasNoCommits{pr.ID}
+		}
+		return first, last, fmt.Errorf("GetRefCommitID(%s): %v", pr.GetGitRefName(), err)
+	}
+
+	commits, err := gitRepo.CommitsBetweenIDs(headCommitID, pr.MergeBase)
+	if err != nil {
+		return first, last, err
+	}
+	if commits.Len() == 0 {
+		return first, last, ErrPullRequestHasNoCommits{pr.ID}
+	}
+
+	for e := commits.Front(); e != nil; e = e.Next() {
+		when := e.Value.(*git.Commit).Author.When
+		if first.IsZero() || when.Before(first) {
+			first = when
+		}
+		if last.IsZero() || when.After(last) {
+			last = when
+		}
+	}
+	return first, last, nil
+}
+
+// GetClosingIssues returns the issues of the base repository which the pull request closes
+// through closing keywords in its description or in the messages of its commits.
+func (pr *PullRequest) GetClosingIssues() ([]*Issue, error) {
+	if err := pr.LoadIssue(); err != nil {
+		return nil, err
+	}
+	if err := pr.GetBaseRepo(); err != nil {
+		return nil, err
+	}
+
+	contents := []string{pr.Issue.Content}
+	if pr.MergeBase != "" {
+		commits, err := pr.commits()
+		if err != nil {
+			return nil, err
+		}
+		// commits are listed newest first
+		for e := commits.Back(); e != nil; e = e.Prev() {
+			contents = append(contents, e.Value.(*git.Commit).Message())
+		}
+	}
+
+	seen := make(map[int64]bool)
+	issues := make([]*Issue, 0, 5)
+	for _, content := range contents {
+		for _, ref := range references.FindAllIssueReferences(content) {
+			if ref.Action != references.XRefActionCloses || ref.Index == pr.Issue.Index || seen[ref.Index] {
+				continue
+			}
+			// only issues of the base repository can be closed by merging the pull request
+			if len(ref.Owner) > 0 && (!strings.EqualFold(ref.Owner, pr.BaseRepo.OwnerName) ||
+				!strings.EqualFold(ref.Name, pr.BaseRepo.Name)) {
+				continue
+			}
+			seen[ref.Index] = true
+
+			issue, err := GetIssueByIndex(pr.BaseRepoID, ref.Index)
+			if err != nil {
+				if IsErrIssueNotExist(err) {
+					continue
+				}
+				return nil, err
+			}
+			issues = append(issues, issue)
+		}
+	}
+	return issues, nil
+}
@@ -0,0 +1,124 @@
+// Copyright 2020 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"testing"
+
+	"code.gitea.io/gitea/modules/git"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsSignedOffBy(t *testing.T) {
+	author := &git.Signature{Name: "User Two", Email: "user2@example.com"}
+	assert.True(t, isSignedOffBy("fix bug\n\nSigned-off-by: User Two <user2@example.com>\n", author))
+	assert.True(t, isSignedOffBy("fix bug\n\nsigned-off-by: User Two <USER2@example.com>", author))
+	assert.False(t, isSignedOffBy("fix bug\n\nSigned-off-by: User Three <user3@example.com>", author))
+	assert.False(t, isSignedOffBy("fix bug\n\nReviewed-by: User Two <user2@example.com>", author))
+	assert.False(t, isSignedOffBy("fix bug", author))
+}
+
+func TestPullRequest_GetContributors(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+	pr := AssertExistsAndLoadBean(t, &PullRequest{ID: 2}).(*PullRequest)
+	pr.MergeBase = "65f1bf27bc3bf70f64657658635e66094edbcb4d"
+
+	// the author email of the commit belongs to no user
+	users, unmatched, err := pr.GetContributors()
+	assert.NoError(t, err)
+	assert.Empty(t, users)
+	if assert.Len(t, unmatched, 1) {
+		assert.Equal(t, "user1", unmatched[0].Name)
+		assert.Equal(t, "address1@example.com", unmatched[0].Email)
+	}
+
+	// secondary emails of users are matched too
+	_, err = x.Insert(&EmailAddress{UID: 1, Email: "address1@example.com", IsActivated: true})
+	assert.NoError(t, err)
+	users, unmatched, err = pr.GetContributors()
+	assert.NoError(t, err)
+	assert.Empty(t, unmatched)
+	if assert.Len(t, users, 1) {
+		assert.EqualValues(t, 1, users[0].ID)
+	}
+
+	pr.MergeBase = ""
+	_, _, err = pr.GetContributors()
+	assert.Error(t, err)
+}
+
+func TestPullRequest_GetClosingIssues(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+	pr := AssertExistsAndLoadBean(t, &PullRequest{ID: 2}).(*PullRequest)
+	assert.NoError(t, pr.LoadIssue())
+	pr.MergeBase = "65f1bf27bc3bf70f64657658635e66094edbcb4d"
+
+	// references without a keyword, to the pull request itself, to other repositories
+	// and to missing issues are ignored
+	pr.Issue.Content = "Fixes #1, fixes #1 again, closes #3, see #2, closes user3/repo3#1, closes #999"
+	issues, err := pr.GetClosingIssues()
+	assert.NoError(t, err)
+	if assert.Len(t, issues, 1) {
+		assert.EqualValues(t, 1, issues[0].ID)
+	}
+
+	pr.Issue.Content = "Resolves user2/repo1#1"
+	issues, err = pr.GetClosingIssues()
+	assert.NoError(t, err)
+	assert.Len(t, issues, 1)
+
+	pr.Issue.Content = ""
+	issues, err = pr.GetClosingIssues()
+	assert.NoError(t, err)
+	assert.Empty(t, issues)
+}
+
+func TestPullRequest_GetDuplicateCommits(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+	pr := AssertExistsAndLoadBean(t, &PullRequest{ID: 2}).(*PullRequest)
+
+	commitIDs, err := pr.GetDuplicateCommits()
+	assert.NoError(t, err)
+	assert.Empty(t, commitIDs)
+}
+
+func TestPullRequest_CommitDateRange(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+	pr := AssertExistsAndLoadBean(t, &PullRequest{ID: 2}).(*PullRequest)
+	pr.MergeBase = "65f1bf27bc3bf70f64657658635e66094edbcb4d"
+
+	first, last, err := pr.CommitDateRange()
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1578347943, first.Unix())
+	assert.EqualValues(t, 1578347943, last.Unix())
+
+	pr.MergeBase = ""
+	_, _, err = pr.CommitDateRange()
+	assert.True(t, IsErrPullRequestHasNoCommits(err))
+}
+
+func TestPullRequest_WasHeadForcePushed(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+	pr := AssertExistsAndLoadBean(t, &PullRequest{ID: 2}).(*PullRequest)
+
+	kases := []struct {
+		oldHead, newHead string
+		expected         bool
+	}{
+		{"", "985f0301dba5e7b34be866819cd15ad3d8f508ee", false},
+		{git.EmptySHA, "985f0301dba5e7b34be866819cd15ad3d8f508ee", false},
+		{"985f0301dba5e7b34be866819cd15ad3d8f508ee", "985f0301dba5e7b34be866819cd15ad3d8f508ee", false},
+		{"5c050d3b6d2db231ab1f64e324f1b6b9a0b181c2", "985f0301dba5e7b34be866819cd15ad3d8f508ee", false},
+		{"4a357436d925b5c974181ff12a994538ddc5a269", "985f0301dba5e7b34be866819cd15ad3d8f508ee", true},
+		// the old head was garbage collected after it got rewritten
+		{"0123456789abcdef0123456789abcdef01234567", "985f0301dba5e7b34be866819cd15ad3d8f508ee", true},
+	}
+	for _, kase := range kases {
+		forcePushed, err := pr.WasHeadForcePushed(kase.oldHead, kase.newHead)
+		assert.NoError(t, err)
+		assert.Equal(t, kase.expected, forcePushed, "%s..%s", kase.oldHead, kase.newHead)
+	}
+}
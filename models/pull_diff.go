@@ -0,0 +1,213 @@
+// Copyright 2020 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"code.gitea.io/gitea/modules/git"
+	"code.gitea.io/gitea/modules/setting"
+
+	"xorm.io/builder"
+)
+
+// GetPatchSeries writes the commits of the pull request between its merge base and its head to w as
+// git format-patch output, one mail per commit, e.g. to apply them with git am. ErrPatchSeriesTooLarge
+// is returned before anything is written if there are more than
+// setting.Repository.PullRequest.MaxPatchSeriesCommits commits.
+func (pr *PullRequest) GetPatchSeries(w io.Writer) error {
+	if pr.MergeBase == "" {
+		return fmt.Errorf("pull request %d has no merge base", pr.ID)
+	}
+	if err := pr.GetBaseRepo(); err != nil {
+		return err
+	}
+	repoPath := pr.BaseRepo.RepoPath()
+	revRange := pr.MergeBase + ".." + pr.GetGitRefName()
+
+	if maxCommits := setting.Repository.PullRequest.MaxPatchSeriesCommits; maxCommits > 0 {
+		stdout, err := git.NewCommand("rev-list", "--count", revRange).RunInDir(repoPath)
+		if err != nil {
+			return fmt.Errorf("rev-list --count: %v", err)
+		}
+		commits, err := strconv.Atoi(strings.TrimSpace(stdout))
+		if err != nil {
+			return fmt.Errorf("rev-list --count: %v", err)
+		}
+		if commits > maxCommits {
+			return ErrPatchSeriesTooLarge{ID: pr.ID, Commits: commits, MaxCommits: maxCommits}
+		}
+	}
+
+	stderr := new(bytes.Buffer)
+	if err := git.NewCommand("format-patch", "--binary", "--stdout", revRange).RunInDirPipeline(repoPath, w, stderr); err != nil {
+		return fmt.Errorf("format-patch: %v - %s", err, stderr)
+	}
+	return nil
+}
+
+// GetBinaryChangedFiles returns the paths of the binary files the pull request adds or modifies,
+// as reported by git diff --numstat. ErrPatchTooLarge is returned if the patch of the pull request
+// is too large to be checked.
+func (pr *PullRequest) GetBinaryChangedFiles() ([]string, error) {
+	if pr.IsPatchTooLarge() {
+		return nil, ErrPatchTooLarge{ID: pr.ID, MaxSize: setting.Repository.PullRequest.MaxPatchSize}
+	}
+	if pr.MergeBase == "" {
+		return nil, fmt.Errorf("pull request %d has no merge base", pr.ID)
+	}
+	if err := pr.GetBaseRepo(); err != nil {
+		return nil, err
+	}
+
+	// binary files are listed with "-" instead of the numbers of added and deleted lines
+	stdout, err := git.NewCommand("diff", "--numstat", "-z", "--no-renames", "--diff-filter=d",
+		pr.MergeBase, pr.GetGitRefName()).RunInDir(pr.BaseRepo.RepoPath())
+	if err != nil {
+		return nil, fmt.Errorf("diff --numstat: %v", err)
+	}
+
+	var files []string
+	for _, line := range strings.Split(stdout, "\x00") {
+		fields := strings.SplitN(line, "\t", 3)
+		if len(fields) == 3 && fields[0] == "-" && fields[1] == "-" {
+			files = append(files, fields[2])
+		}
+	}
+	return files, nil
+}
+
+// GetChangedFiles returns the paths of the files changed by the pull request, from its merge base
+// to the head branch in the head repository. Renamed files are listed with their old and new path.
+func (pr *PullRequest) GetChangedFiles() ([]string, error) {
+	if pr.MergeBase == "" {
+		return nil, fmt.Errorf("pull request %d has no merge base", pr.ID)
+	}
+	if err := pr.GetHeadRepo(); err != nil {
+		return nil, err
+	}
+	if pr.HeadRepo == nil {
+		return nil, ErrPullRequestHeadRepoMissing{pr.ID, pr.HeadRepoID}
+	}
+	headGitRepo, err := git.OpenRepository(pr.HeadRepo.RepoPath())
+	if err != nil {
+		return nil, err
+	}
+	defer headGitRepo.Close()
+
+	return headGitRepo.GetChangedFilesBetween(pr.MergeBase, git.BranchPrefix+pr.HeadBranch)
+}
+
+// GetReviewComments returns the code comments of the pull request grouped by file path,
+// each ordered by line and creation time. Lines of the old side of the diff are negative,
+// so they are ordered by their absolute value, the old side first on the same line.
+// Comments of pending reviews are left out.
+// Comments on lines which changed since they were made are included, marked as Invalidated.
+func (pr *PullRequest) GetReviewComments() (map[string][]*Comment, error) {
+	var comments []*Comment
+	if err := x.Where("issue_id = ? AND type = ?", pr.IssueID, CommentTypeCode).
+		And(builder.Or(
+			builder.Eq{"review_id": 0},
+			builder.IsNull{"review_id"},
+			builder.NotIn("review_id", builder.Select("id").From("review").Where(builder.Eq{"type": ReviewTypePending})),
+		)).
+		OrderBy("ABS(line) ASC, line ASC, created_unix ASC, id ASC").
+		Find(&comments); err != nil {
+		return nil, err
+	}
+
+	if err := CommentList(comments).loadPosters(x); err != nil {
+		return nil, err
+	}
+
+	pathToComments := make(map[string][]*Comment)
+	for _, comment := range comments {
+		pathToComments[comment.TreePath] = append(pathToComments[comment.TreePath], comment)
+	}
+	return pathToComments, nil
+}
+
+// DiffAgainstBase returns the diff between baseCommit and the current head of the pull request,
+// e.g. to compare with the base at the time of a review. baseCommit must be a commit the head
+// is based on, otherwise ErrPullRequestInvalidDiffBase is returned.
+func (pr *PullRequest) DiffAgainstBase(baseCommit string) (string, error) {
+	gitRepo, err := pr.baseGitRepo()
+	if err != nil {
+		return "", err
+	}
+	defer gitRepo.Close()
+
+	headCommitID, err := gitRepo.GetRefCommitID(pr.GetGitRefName())
+	if err != nil {
+		return "", fmt.Errorf("GetRefCommitID(%s): %v", pr.GetGitRefName(), err)
+	}
+
+	if strings.HasPrefix(baseCommit, "-") {
+		return "", ErrPullRequestInvalidDiffBase{pr.ID, baseCommit}
+	}
+	// rev-parse fails for anything which does not resolve to a commit
+	stdout, err := git.NewCommand("rev-parse", "--verify", "--quiet", baseCommit+"^{commit}").RunInDir(gitRepo.Path)
+	if err != nil {
+		return "", ErrPullRequestInvalidDiffBase{pr.ID, baseCommit}
+	}
+	baseCommitID := strings.TrimSpace(stdout)
+	if _, err = git.NewCommand("merge-base", "--is-ancestor", baseCommitID, headCommitID).RunInDir(gitRepo.Path); err != nil {
+		return "", ErrPullRequestInvalidDiffBase{pr.ID, baseCommit}
+	}
+
+	var diff strings.Builder
+	if err = gitRepo.GetDiff(baseCommitID, headCommitID, &diff); err != nil {
+		return "", fmt.Errorf("GetDiff: %v", err)
+	}
+	return diff.String(), nil
+}
+
+// GetMergeBaseDirect returns the merge base of the base branch and the head of the pull request,
+// computed in the base repository without adding a temporary remote. If head and base repository
+// are the same the head branch is used, otherwise the head reference pushed by PushToBaseRepo,
+// which is only as recent as the last push.
+func (pr *PullRequest) GetMergeBaseDirect() (string, error) {
+	gitRepo, err := pr.baseGitRepo()
+	if err != nil {
+		return "", err
+	}
+	defer gitRepo.Close()
+
+	head := pr.GetGitRefName()
+	if pr.IsSameRepo() {
+		head = git.BranchPrefix + pr.HeadBranch
+	}
+	mergeBase, _, err := gitRepo.GetMergeBase("", git.BranchPrefix+pr.BaseBranch, head)
+	if err != nil {
+		return "", fmt.Errorf("GetMergeBase: %v", err)
+	}
+	return mergeBase, nil
+}
+
+// DiffHash returns a hash of the endpoints of the diff of the pull request, i.e. its merge base
+// and its head commit. It changes exactly when the diff does, so it can be used as a cache key
+// for the rendered diff without reading the patch.
+func (pr *PullRequest) DiffHash() (string, error) {
+	if pr.MergeBase == "" {
+		return "", fmt.Errorf("pull request %d has no merge base", pr.ID)
+	}
+	gitRepo, err := pr.baseGitRepo()
+	if err != nil {
+		return "", err
+	}
+	defer gitRepo.Close()
+
+	headCommitID, err := gitRepo.GetRefCommitID(pr.GetGitRefName())
+	if err != nil {
+		return "", fmt.Errorf("GetRefCommitID(%s): %v", pr.GetGitRefName(), err)
+	}
+
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(pr.MergeBase+".."+headCommitID))), nil
+}
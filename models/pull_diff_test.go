@@ -0,0 +1,189 @@
+// Copyright 2020 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+
+	"code.gitea.io/gitea/modules/git"
+	"code.gitea.io/gitea/modules/setting"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPullRequest_GetReviewComments(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+	pr := AssertExistsAndLoadBean(t, &PullRequest{ID: 1}).(*PullRequest)
+
+	comments, err := pr.GetReviewComments()
+	assert.NoError(t, err)
+	if assert.Len(t, comments, 1) && assert.Len(t, comments["README.md"], 2) {
+		assert.EqualValues(t, 5, comments["README.md"][0].ID)
+		assert.False(t, comments["README.md"][0].Invalidated)
+		assert.EqualValues(t, 6, comments["README.md"][1].ID)
+		assert.True(t, comments["README.md"][1].Invalidated)
+	}
+
+	// lines of both sides of the diff are interleaved, the old side first
+	for _, line := range []int64{5, -2, 3, -5, 1} {
+		_, err = x.Insert(&Comment{
+			Type:        CommentTypeCode,
+			PosterID:    1,
+			IssueID:     pr.IssueID,
+			Line:        line,
+			TreePath:    "README.md",
+			CreatedUnix: 946684812,
+		})
+		assert.NoError(t, err)
+	}
+	comments, err = pr.GetReviewComments()
+	assert.NoError(t, err)
+	var lines []int64
+	for _, comment := range comments["README.md"] {
+		lines = append(lines, comment.Line)
+	}
+	assert.Equal(t, []int64{1, -2, 3, -4, -4, -5, 5}, lines)
+}
+
+func TestPullRequest_DiffHash(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+	pr := AssertExistsAndLoadBean(t, &PullRequest{ID: 2}).(*PullRequest)
+
+	hash, err := pr.DiffHash()
+	assert.NoError(t, err)
+	assert.Len(t, hash, 64)
+
+	// stable for the same endpoints
+	again, err := pr.DiffHash()
+	assert.NoError(t, err)
+	assert.Equal(t, hash, again)
+
+	// changes with the merge base
+	pr.MergeBase = "65f1bf27bc3bf70f64657658635e66094edbcb4d"
+	rebased, err := pr.DiffHash()
+	assert.NoError(t, err)
+	assert.NotEqual(t, hash, rebased)
+
+	// and with the head
+	pr.Index = 5
+	other, err := pr.DiffHash()
+	assert.NoError(t, err)
+	assert.NotEqual(t, rebased, other)
+
+	pr.MergeBase = ""
+	_, err = pr.DiffHash()
+	assert.Error(t, err)
+}
+
+func TestPullRequest_GetBinaryChangedFiles(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+	pr := AssertExistsAndLoadBean(t, &PullRequest{ID: 2}).(*PullRequest)
+	pr.MergeBase = "65f1bf27bc3bf70f64657658635e66094edbcb4d"
+
+	files, err := pr.GetBinaryChangedFiles()
+	assert.NoError(t, err)
+	assert.Empty(t, files)
+
+	pr.Status = PullRequestStatusPatchTooLarge
+	_, err = pr.GetBinaryChangedFiles()
+	assert.True(t, IsErrPatchTooLarge(err))
+}
+
+func TestPullRequest_GetMergeBaseDirect(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+	repoPath := RepoPath("user2", "repo1")
+	remotes, err := git.NewCommand("remote").RunInDir(repoPath)
+	assert.NoError(t, err)
+
+	// same repository, the head branch is used
+	pr := AssertExistsAndLoadBean(t, &PullRequest{ID: 2}).(*PullRequest)
+	assert.NoError(t, pr.GetBaseRepo())
+	// a fork, the head reference in the base repository is used
+	fork := *pr
+	fork.HeadRepoID = 11
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		for _, pr := range []*PullRequest{pr, &fork} {
+			wg.Add(1)
+			go func(pr *PullRequest) {
+				defer wg.Done()
+				mergeBase, err := pr.GetMergeBaseDirect()
+				assert.NoError(t, err)
+				assert.EqualValues(t, "65f1bf27bc3bf70f64657658635e66094edbcb4d", mergeBase)
+			}(pr)
+		}
+	}
+	wg.Wait()
+
+	after, err := git.NewCommand("remote").RunInDir(repoPath)
+	assert.NoError(t, err)
+	assert.Equal(t, remotes, after)
+}
+
+func TestPullRequest_GetChangedFiles(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+	pr := AssertExistsAndLoadBean(t, &PullRequest{ID: 2}).(*PullRequest)
+	pr.MergeBase = "65f1bf27bc3bf70f64657658635e66094edbcb4d"
+
+	files, err := pr.GetChangedFiles()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"README.md"}, files)
+
+	pr.MergeBase = ""
+	_, err = pr.GetChangedFiles()
+	assert.Error(t, err)
+}
+
+func TestPullRequest_GetPatchSeries(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+	pr := AssertExistsAndLoadBean(t, &PullRequest{ID: 2}).(*PullRequest)
+	pr.MergeBase = "65f1bf27bc3bf70f64657658635e66094edbcb4d"
+
+	series := new(bytes.Buffer)
+	assert.NoError(t, pr.GetPatchSeries(series))
+	assert.Contains(t, series.String(), "Subject: [PATCH] For PR3")
+
+	// the head of pull request 5 is two commits ahead of the merge base
+	pr.Index = 5
+	series.Reset()
+	assert.NoError(t, pr.GetPatchSeries(series))
+	assert.Contains(t, series.String(), "Subject: [PATCH 1/2] a change")
+	assert.Contains(t, series.String(), "Subject: [PATCH 2/2] add WoW File")
+
+	defer func(maxCommits int) {
+		setting.Repository.PullRequest.MaxPatchSeriesCommits = maxCommits
+	}(setting.Repository.PullRequest.MaxPatchSeriesCommits)
+	setting.Repository.PullRequest.MaxPatchSeriesCommits = 1
+	series.Reset()
+	assert.True(t, IsErrPatchSeriesTooLarge(pr.GetPatchSeries(series)))
+	// nothing is written for too large series
+	assert.Zero(t, series.Len())
+}
+
+func TestPullRequest_DiffAgainstBase(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+	pr := AssertExistsAndLoadBean(t, &PullRequest{ID: 2}).(*PullRequest)
+
+	diff, err := pr.DiffAgainstBase("65f1bf27bc3bf70f64657658635e66094edbcb4d")
+	assert.NoError(t, err)
+	assert.Contains(t, diff, "diff --git")
+
+	// the head itself has no changes against itself
+	diff, err = pr.DiffAgainstBase("4a357436d925b5c974181ff12a994538ddc5a269")
+	assert.NoError(t, err)
+	assert.Empty(t, diff)
+
+	for _, base := range []string{
+		"985f0301dba5e7b34be866819cd15ad3d8f508ee", // not an ancestor of the head
+		"0123456789abcdef0123456789abcdef01234567", // no such commit
+		"--output=/tmp/diff",
+	} {
+		_, err = pr.DiffAgainstBase(base)
+		assert.True(t, IsErrPullRequestInvalidDiffBase(err), base)
+	}
+}
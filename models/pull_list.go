@@ -6,6 +6,7 @@ package models
 
 import (
 	"fmt"
+	"time"
 
 	"code.gitea.io/gitea/modules/base"
 	"code.gitea.io/gitea/modules/git"
@@ -68,6 +69,40 @@ func GetUnmergedPullRequestsByBaseInfo(repoID int64, branch string) ([]*PullRequ
 		Find(&prs)
 }
 
+// CountOpenPullRequestsByBaseBranch returns the number of pull requests that are open and have
+// not been merged, targeting the given branch of the base repository.
+func CountOpenPullRequestsByBaseBranch(baseRepoID int64, branch string) (int64, error) {
+	return x.
+		Where("pull_request.base_repo_id=? AND pull_request.base_branch=? AND pull_request.has_merged=? AND issue.is_closed=?",
+			baseRepoID, branch, false, false).
+		Join("INNER", "issue", "issue.id=pull_request.issue_id").
+		Count(new(PullRequest))
+}
+
+// GetStalePullRequests returns all pull requests of the base repository that are open and
+// have not been merged, and whose issue has not been updated since olderThan.
+func GetStalePullRequests(baseRepoID int64, olderThan time.Time) ([]*PullRequest, error) {
+	prs := make([]*PullRequest, 0, 10)
+	return prs, x.
+		Where("pull_request.base_repo_id=? AND pull_request.has_merged=? AND issue.is_closed=? AND issue.updated_unix<?",
+			baseRepoID, false, false, olderThan.Unix()).
+		Join("INNER", "issue", "issue.id=pull_request.issue_id").
+		Asc("issue.updated_unix").
+		Find(&prs)
+}
+
+// GetStalePullRequestBaseRepoIDs returns the IDs of all base repositories having at least
+// one open and unmerged pull request whose issue has not been updated since olderThan.
+func GetStalePullRequestBaseRepoIDs(olderThan time.Time) ([]int64, error) {
+	repoIDs := make([]int64, 0, 10)
+	return repoIDs, x.Table("pull_request").
+		Join("INNER", "issue", "issue.id=pull_request.issue_id").
+		Where("pull_request.has_merged=? AND issue.is_closed=? AND issue.updated_unix<?",
+			false, false, olderThan.Unix()).
+		Distinct("pull_request.base_repo_id").
+		Find(&repoIDs)
+}
+
 // GetPullRequestIDsByCheckStatus returns all pull requests according the special checking status.
 func GetPullRequestIDsByCheckStatus(status PullRequestStatus) ([]int64, error) {
 	prs := make([]int64, 0, 10)
@@ -77,6 +112,26 @@ func GetPullRequestIDsByCheckStatus(status PullRequestStatus) ([]int64, error) {
 		Find(&prs)
 }
 
+// FindOrphanedPullRequests returns the ids of all pull requests whose issue does not exist.
+func FindOrphanedPullRequests() ([]int64, error) {
+	ids := make([]int64, 0, 10)
+	return ids, x.Table("pull_request").
+		Join("LEFT", "issue", "issue.id=pull_request.issue_id").
+		Where("issue.id IS NULL").
+		Cols("pull_request.id").
+		Find(&ids)
+}
+
+// DeleteOrphanedPullRequests deletes all pull requests whose issue does not exist
+// and returns how many were deleted.
+func DeleteOrphanedPullRequests() (int64, error) {
+	ids, err := FindOrphanedPullRequests()
+	if err != nil || len(ids) == 0 {
+		return 0, err
+	}
+	return x.In("id", ids).Delete(new(PullRequest))
+}
+
 // PullRequests returns all pull requests for a base Repo by the given conditions
 func PullRequests(baseRepoID int64, opts *PullRequestsOptions) ([]*PullRequest, int64, error) {
 	if opts.Page <= 0 {
@@ -0,0 +1,269 @@
+// Copyright 2020 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"code.gitea.io/gitea/modules/git"
+)
+
+// unmergeableReason returns why the pull request can not be merged at all in its
+// current state, or an empty string if it is open and free of conflicts.
+func (pr *PullRequest) unmergeableReason() (string, error) {
+	if pr.HasMerged {
+		return "repo.pulls.merge_blocked.merged", nil
+	}
+	if err := pr.LoadIssue(); err != nil {
+		return "", err
+	}
+	if pr.Issue.IsClosed {
+		return "repo.pulls.merge_blocked.closed", nil
+	}
+	switch pr.Status {
+	case PullRequestStatusChecking:
+		return "repo.pulls.merge_blocked.checking", nil
+	case PullRequestStatusConflict, PullRequestStatusError:
+		return "repo.pulls.merge_blocked.conflicts", nil
+	case PullRequestStatusPatchTooLarge:
+		return "repo.pulls.merge_blocked.too_large", nil
+	}
+	return "", nil
+}
+
+// CanRebaseAndMerge checks whether doer may rebase the head branch of the pull request onto
+// its base branch, push the result and merge it. If not, the locale key of the reason is returned.
+func (pr *PullRequest) CanRebaseAndMerge(doer *User) (bool, string, error) {
+	if reason, err := pr.unmergeableReason(); err != nil || len(reason) > 0 {
+		return false, reason, err
+	}
+
+	if err := pr.LoadBaseRepo(); err != nil {
+		return false, "", err
+	}
+	prUnit, err := pr.BaseRepo.GetUnit(UnitTypePullRequests)
+	if err != nil {
+		return false, "", err
+	}
+	if !prUnit.PullRequestsConfig().AllowRebase {
+		return false, "repo.pulls.rebase_blocked.not_allowed", nil
+	}
+
+	if err := pr.GetHeadRepo(); err != nil {
+		return false, "", err
+	}
+	if pr.HeadRepo == nil {
+		return false, "repo.pulls.rebase_blocked.head_repo_missing", nil
+	}
+	perm, err := GetUserRepoPermission(pr.HeadRepo, doer)
+	if err != nil {
+		return false, "", err
+	}
+	if !perm.CanWrite(UnitTypeCode) {
+		return false, "repo.pulls.rebase_blocked.no_push_access", nil
+	}
+
+	// protected branches refuse force pushes, which are needed to update a rebased head branch
+	protectedBranch, err := GetProtectedBranchBy(pr.HeadRepo.ID, pr.HeadBranch)
+	if err != nil {
+		return false, "", err
+	}
+	if protectedBranch != nil && protectedBranch.IsProtected() {
+		return false, "repo.pulls.rebase_blocked.head_protected", nil
+	}
+
+	return true, "", nil
+}
+
+// MergeStyleAvailability describes whether a merge style can be used to merge a pull request.
+type MergeStyleAvailability struct {
+	Style    MergeStyle
+	Allowed  bool   // the merge style is enabled in the base repository
+	Possible bool   // the pull request can currently be merged with the merge style by the doer
+	Reason   string // locale key of why the merge style can not be used, empty if it can
+}
+
+// mergeStyles are all merge styles in the order they are offered
+var mergeStyles = []MergeStyle{MergeStyleMerge, MergeStyleRebase, MergeStyleRebaseMerge, MergeStyleSquash, MergeStyleFastForwardOnly}
+
+// AvailableMergeStyles returns for every merge style whether it is enabled in the base repository
+// and whether doer can currently merge the pull request with it, along with the locale key of a reason if not.
+// Besides the checks which apply to all merge styles, fast-forward only requires that the base
+// branch can be fast-forwarded to the head, and the styles creating or rewriting commits require
+// that these commits would be signed if the base branch requires signed commits. Conflicts which
+// only show up while rebasing the commits one by one are not detected.
+func (pr *PullRequest) AvailableMergeStyles(doer *User) ([]MergeStyleAvailability, error) {
+	if err := pr.LoadBaseRepo(); err != nil {
+		return nil, err
+	}
+	prUnit, err := pr.BaseRepo.GetUnit(UnitTypePullRequests)
+	if err != nil {
+		return nil, err
+	}
+	prConfig := prUnit.PullRequestsConfig()
+
+	reason, err := pr.mergeBlockedReason(doer)
+	if err != nil {
+		return nil, err
+	}
+	var canFastForward bool
+	if len(reason) == 0 {
+		if canFastForward, err = pr.CanFastForward(); err != nil {
+			return nil, err
+		}
+	}
+
+	styles := make([]MergeStyleAvailability, 0, len(mergeStyles))
+	for _, style := range mergeStyles {
+		availability := MergeStyleAvailability{
+			Style:   style,
+			Allowed: prConfig.IsMergeStyleAllowed(style),
+		}
+		if !availability.Allowed {
+			availability.Reason = "repo.pulls.merge_blocked.style_not_allowed"
+		} else if len(reason) > 0 {
+			availability.Reason = reason
+		} else if availability.Reason, err = pr.mergeStyleBlockedReason(style, doer, canFastForward); err != nil {
+			return nil, err
+		} else {
+			availability.Possible = len(availability.Reason) == 0
+		}
+		styles = append(styles, availability)
+	}
+	return styles, nil
+}
+
+// mergeStyleBlockedReason returns why doer can not merge the pull request with the given merge
+// style, or an empty string if nothing specific to the merge style prevents it.
+func (pr *PullRequest) mergeStyleBlockedReason(style MergeStyle, doer *User, canFastForward bool) (string, error) {
+	requireSigned := pr.ProtectedBranch != nil && pr.ProtectedBranch.RequireSignedCommits
+
+	switch style {
+	case MergeStyleFastForwardOnly:
+		if !canFastForward {
+			return "repo.pulls.merge_blocked.no_fast_forward", nil
+		}
+		return "", nil
+	case MergeStyleRebase, MergeStyleRebaseMerge:
+		// Rebasing onto a moved base branch rewrites the commits without signing them
+		if requireSigned && !canFastForward {
+			return "repo.pulls.merge_blocked.rebase_unsigned", nil
+		}
+	}
+	if style == MergeStyleRebase || !requireSigned {
+		return "", nil
+	}
+
+	// The remaining merge styles create a new commit on the base branch
+	if _, _, err := pr.SignMerge(doer, pr.BaseRepo.RepoPath(), pr.BaseBranch, pr.GetGitRefName()); IsErrWontSign(err) {
+		return "repo.pulls.merge_blocked.merge_unsigned", nil
+	} else if err != nil {
+		return "", err
+	}
+	return "", nil
+}
+
+// CanFastForward returns whether the base branch can be fast-forwarded to the head of the pull
+// request, that is whether the tip of the base branch is an ancestor of the head.
+func (pr *PullRequest) CanFastForward() (bool, error) {
+	if err := pr.GetBaseRepo(); err != nil {
+		return false, err
+	}
+
+	_, err := git.NewCommand("merge-base", "--is-ancestor", git.BranchPrefix+pr.BaseBranch, pr.GetGitRefName()).RunInDir(pr.BaseRepo.RepoPath())
+	if err != nil {
+		// Errors are signaled by a non-zero status that is not 1
+		if strings.Contains(err.Error(), "exit status 1") {
+			return false, nil
+		}
+		return false, fmt.Errorf("git merge-base --is-ancestor: %v", err)
+	}
+	return true, nil
+}
+
+// IsBaseBranchAhead returns whether the base branch got commits since the stored merge base,
+// that is whether the pull request is out of date until its merge base is recomputed.
+func (pr *PullRequest) IsBaseBranchAhead() (bool, error) {
+	if pr.MergeBase == "" {
+		return false, fmt.Errorf("pull request %d has no merge base", pr.ID)
+	}
+	if err := pr.GetBaseRepo(); err != nil {
+		return false, err
+	}
+
+	_, err := git.NewCommand("merge-base", "--is-ancestor", git.BranchPrefix+pr.BaseBranch, pr.MergeBase).RunInDir(pr.BaseRepo.RepoPath())
+	if err != nil {
+		// Errors are signaled by a non-zero status that is not 1
+		if strings.Contains(err.Error(), "exit status 1") {
+			return true, nil
+		}
+		return false, fmt.Errorf("git merge-base --is-ancestor: %v", err)
+	}
+	return false, nil
+}
+
+// mergeBlockedReason returns why doer can not merge the pull request regardless of the merge style,
+// or an empty string if nothing prevents it.
+func (pr *PullRequest) mergeBlockedReason(doer *User) (string, error) {
+	if reason, err := pr.unmergeableReason(); err != nil || len(reason) > 0 {
+		return reason, err
+	}
+
+	if doer == nil {
+		return "repo.pulls.merge_blocked.no_merge_access", nil
+	}
+	perm, err := GetUserRepoPermission(pr.BaseRepo, doer)
+	if err != nil {
+		return "", err
+	}
+	if !perm.CanWrite(UnitTypeCode) {
+		return "repo.pulls.merge_blocked.no_merge_access", nil
+	}
+	if err := pr.LoadProtectedBranch(); err != nil {
+		return "", err
+	}
+	if pr.ProtectedBranch != nil && !pr.ProtectedBranch.IsUserMergeWhitelisted(doer.ID) {
+		return "repo.pulls.merge_blocked.protected_branch", nil
+	}
+	return "", nil
+}
+
+// RequiresUpdate returns whether the head branch of the pull request should be updated with its base branch.
+// This is the case if it is more than threshold commits behind the base branch, or if any of the commits
+// added to the base branch since the merge base touched one of watchedPaths.
+func (pr *PullRequest) RequiresUpdate(threshold int, watchedPaths []string) (bool, error) {
+	if err := pr.GetBaseRepo(); err != nil {
+		return false, err
+	}
+	repoPath := pr.BaseRepo.RepoPath()
+
+	// commits of the base branch which are not part of the head
+	behindRange := pr.GetGitRefName() + ".." + git.BranchPrefix + pr.BaseBranch
+	stdout, err := git.NewCommand("rev-list", "--count", behindRange).RunInDir(repoPath)
+	if err != nil {
+		return false, fmt.Errorf("rev-list --count %s: %v", behindRange, err)
+	}
+	behind, err := strconv.Atoi(strings.TrimSpace(stdout))
+	if err != nil {
+		return false, err
+	}
+	if behind == 0 {
+		return false, nil
+	} else if behind > threshold {
+		return true, nil
+	}
+	if len(watchedPaths) == 0 {
+		return false, nil
+	}
+
+	args := append([]string{"rev-list", "--max-count=1", behindRange, "--"}, watchedPaths...)
+	stdout, err = git.NewCommand(args...).RunInDir(repoPath)
+	if err != nil {
+		return false, fmt.Errorf("rev-list %s -- %v: %v", behindRange, watchedPaths, err)
+	}
+	return len(strings.TrimSpace(stdout)) > 0, nil
+}
@@ -0,0 +1,193 @@
+// Copyright 2020 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"testing"
+
+	"code.gitea.io/gitea/modules/git"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPullRequest_AvailableMergeStyles(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+	owner := AssertExistsAndLoadBean(t, &User{ID: 2}).(*User)
+	other := AssertExistsAndLoadBean(t, &User{ID: 4}).(*User)
+
+	pr := AssertExistsAndLoadBean(t, &PullRequest{ID: 2}).(*PullRequest)
+	styles, err := pr.AvailableMergeStyles(owner)
+	assert.NoError(t, err)
+	if assert.Len(t, styles, 5) {
+		for _, style := range styles {
+			assert.True(t, style.Allowed)
+			assert.True(t, style.Possible)
+			assert.Empty(t, style.Reason)
+		}
+		assert.Equal(t, MergeStyleMerge, styles[0].Style)
+	}
+
+	styles, err = pr.AvailableMergeStyles(other)
+	assert.NoError(t, err)
+	for _, style := range styles {
+		assert.True(t, style.Allowed)
+		assert.False(t, style.Possible)
+		assert.NotEmpty(t, style.Reason)
+	}
+
+	pr = AssertExistsAndLoadBean(t, &PullRequest{ID: 1}).(*PullRequest)
+	styles, err = pr.AvailableMergeStyles(owner)
+	assert.NoError(t, err)
+	for _, style := range styles {
+		assert.False(t, style.Possible)
+	}
+}
+
+func TestPullRequest_CanFastForward(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+	owner := AssertExistsAndLoadBean(t, &User{ID: 2}).(*User)
+
+	// the head of the pull request is a child of the tip of master
+	pr := AssertExistsAndLoadBean(t, &PullRequest{ID: 2}).(*PullRequest)
+	ok, err := pr.CanFastForward()
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	// branch2 has diverged from the head of the pull request
+	pr.BaseBranch = "branch2"
+	ok, err = pr.CanFastForward()
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	styles, err := pr.AvailableMergeStyles(owner)
+	assert.NoError(t, err)
+	for _, style := range styles {
+		if style.Style == MergeStyleFastForwardOnly {
+			assert.True(t, style.Allowed)
+			assert.False(t, style.Possible)
+			assert.NotEmpty(t, style.Reason)
+		} else {
+			assert.True(t, style.Possible)
+		}
+	}
+}
+
+func TestPullRequest_AvailableMergeStyles_RequireSignedCommits(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+	owner := AssertExistsAndLoadBean(t, &User{ID: 2}).(*User)
+
+	// there is no signing key, so merge commits would not be signed
+	pr := AssertExistsAndLoadBean(t, &PullRequest{ID: 2}).(*PullRequest)
+	pr.ProtectedBranch = &ProtectedBranch{RepoID: pr.BaseRepoID, BranchName: pr.BaseBranch, RequireSignedCommits: true}
+	styles, err := pr.AvailableMergeStyles(owner)
+	assert.NoError(t, err)
+	possible := make(map[MergeStyle]bool, len(styles))
+	for _, style := range styles {
+		possible[style.Style] = style.Possible
+		assert.Equal(t, style.Possible, len(style.Reason) == 0, "%s", style.Style)
+	}
+	assert.Equal(t, map[MergeStyle]bool{
+		MergeStyleMerge:           false,
+		MergeStyleRebase:          true, // rebasing onto an unchanged base branch keeps the commits
+		MergeStyleRebaseMerge:     false,
+		MergeStyleSquash:          false,
+		MergeStyleFastForwardOnly: true,
+	}, possible)
+
+	// rebasing onto a diverged base branch rewrites the commits
+	pr.BaseBranch = "branch2"
+	pr.ProtectedBranch.BranchName = pr.BaseBranch
+	styles, err = pr.AvailableMergeStyles(owner)
+	assert.NoError(t, err)
+	for _, style := range styles {
+		assert.False(t, style.Possible, "%s", style.Style)
+		assert.NotEmpty(t, style.Reason)
+	}
+}
+
+func TestPullRequest_CanRebaseAndMerge(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+	owner := AssertExistsAndLoadBean(t, &User{ID: 2}).(*User)
+	other := AssertExistsAndLoadBean(t, &User{ID: 4}).(*User)
+
+	pr := AssertExistsAndLoadBean(t, &PullRequest{ID: 1}).(*PullRequest)
+	ok, reason, err := pr.CanRebaseAndMerge(owner)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, "repo.pulls.merge_blocked.merged", reason)
+
+	pr = AssertExistsAndLoadBean(t, &PullRequest{ID: 2}).(*PullRequest)
+	ok, reason, err = pr.CanRebaseAndMerge(owner)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Empty(t, reason)
+
+	ok, reason, err = pr.CanRebaseAndMerge(other)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, "repo.pulls.rebase_blocked.no_push_access", reason)
+
+	_, err = x.Insert(&ProtectedBranch{RepoID: pr.HeadRepoID, BranchName: pr.HeadBranch})
+	assert.NoError(t, err)
+	ok, reason, err = pr.CanRebaseAndMerge(owner)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, "repo.pulls.rebase_blocked.head_protected", reason)
+}
+
+func TestPullRequest_IsBaseBranchAhead(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+	pr := AssertExistsAndLoadBean(t, &PullRequest{ID: 2}).(*PullRequest)
+	assert.NoError(t, pr.GetBaseRepo())
+	pr.MergeBase = "65f1bf27bc3bf70f64657658635e66094edbcb4d"
+
+	ahead, err := pr.IsBaseBranchAhead()
+	assert.NoError(t, err)
+	assert.False(t, ahead)
+
+	// the base branch advances after the pull request was created
+	_, err = git.NewCommand("update-ref", git.BranchPrefix+pr.BaseBranch, "5c050d3b6d2db231ab1f64e324f1b6b9a0b181c2").RunInDir(pr.BaseRepo.RepoPath())
+	assert.NoError(t, err)
+	defer func() {
+		_, err := git.NewCommand("update-ref", git.BranchPrefix+pr.BaseBranch, "65f1bf27bc3bf70f64657658635e66094edbcb4d").RunInDir(pr.BaseRepo.RepoPath())
+		assert.NoError(t, err)
+	}()
+
+	ahead, err = pr.IsBaseBranchAhead()
+	assert.NoError(t, err)
+	assert.True(t, ahead)
+
+	pr.MergeBase = "5c050d3b6d2db231ab1f64e324f1b6b9a0b181c2"
+	ahead, err = pr.IsBaseBranchAhead()
+	assert.NoError(t, err)
+	assert.False(t, ahead)
+}
+
+func TestPullRequest_RequiresUpdate(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+	// the head of pull request 3 is two commits behind branch2, both touching README.md
+	pr := &PullRequest{BaseRepoID: 1, Index: 3, BaseBranch: "branch2"}
+
+	requiresUpdate, err := pr.RequiresUpdate(1, nil)
+	assert.NoError(t, err)
+	assert.True(t, requiresUpdate)
+
+	requiresUpdate, err = pr.RequiresUpdate(2, nil)
+	assert.NoError(t, err)
+	assert.False(t, requiresUpdate)
+
+	requiresUpdate, err = pr.RequiresUpdate(5, []string{"README.md"})
+	assert.NoError(t, err)
+	assert.True(t, requiresUpdate)
+
+	requiresUpdate, err = pr.RequiresUpdate(5, []string{"docs"})
+	assert.NoError(t, err)
+	assert.False(t, requiresUpdate)
+
+	pr = &PullRequest{BaseRepoID: 1, Index: 3, BaseBranch: "master"}
+	requiresUpdate, err = pr.RequiresUpdate(0, []string{"README.md"})
+	assert.NoError(t, err)
+	assert.False(t, requiresUpdate)
+}
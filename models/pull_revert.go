@@ -0,0 +1,55 @@
+// Copyright 2020 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package models
+
+// IsReverted returns true if the merged pull request has been reverted.
+func (pr *PullRequest) IsReverted() bool {
+	return len(pr.RevertedByCommitID) > 0
+}
+
+// SetRevertedBy records that the merged pull request has been reverted by the given commit,
+// and by the pull request prID if the reverting commit was merged through one.
+func (pr *PullRequest) SetRevertedBy(commitID string, prID int64) error {
+	if !pr.HasMerged {
+		return ErrPullRequestNotMerged{pr.ID}
+	}
+	pr.RevertedByCommitID = commitID
+	pr.RevertedByPullID = prID
+	// do not touch the merge time
+	_, err := x.ID(pr.ID).Cols("reverted_by_commit_id", "reverted_by_pull_id").NoAutoTime().Update(pr)
+	return err
+}
+
+// GetMergedCommitParents returns the IDs of the parents of the merged commit of the pull request
+// in their order, e.g. to pick the mainline when reverting a merge commit.
+func (pr *PullRequest) GetMergedCommitParents() ([]string, error) {
+	if !pr.HasMerged || len(pr.MergedCommitID) == 0 {
+		return nil, ErrPullRequestNotMerged{pr.ID}
+	}
+	gitRepo, err := pr.baseGitRepo()
+	if err != nil {
+		return nil, err
+	}
+	defer gitRepo.Close()
+
+	// the commit is gone if the base branch has been rewritten and garbage collected
+	if !gitRepo.IsCommitExist(pr.MergedCommitID) {
+		return nil, ErrMergedCommitNotExist{pr.ID, pr.MergedCommitID}
+	}
+	commit, err := gitRepo.GetCommit(pr.MergedCommitID)
+	if err != nil {
+		return nil, err
+	}
+
+	parents := make([]string, 0, commit.ParentCount())
+	for i := 0; i < commit.ParentCount(); i++ {
+		parentID, err := commit.ParentID(i)
+		if err != nil {
+			return nil, err
+		}
+		parents = append(parents, parentID.String())
+	}
+	return parents, nil
+}
@@ -0,0 +1,44 @@
+// Copyright 2020 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPullRequest_GetMergedCommitParents(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+	pr := AssertExistsAndLoadBean(t, &PullRequest{ID: 1}).(*PullRequest)
+
+	pr.MergedCommitID = "985f0301dba5e7b34be866819cd15ad3d8f508ee"
+	parents, err := pr.GetMergedCommitParents()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"5c050d3b6d2db231ab1f64e324f1b6b9a0b181c2"}, parents)
+
+	pr.MergedCommitID = "0000000000000000000000000000000000000001"
+	_, err = pr.GetMergedCommitParents()
+	assert.True(t, IsErrMergedCommitNotExist(err))
+
+	pr = AssertExistsAndLoadBean(t, &PullRequest{ID: 2}).(*PullRequest)
+	_, err = pr.GetMergedCommitParents()
+	assert.True(t, IsErrPullRequestNotMerged(err))
+}
+
+func TestPullRequest_SetRevertedBy(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+	pr := AssertExistsAndLoadBean(t, &PullRequest{ID: 1}).(*PullRequest)
+	assert.False(t, pr.IsReverted())
+
+	assert.NoError(t, pr.SetRevertedBy("985f0301dba5e7b34be866819cd15ad3d8f508ee", 2))
+	pr = AssertExistsAndLoadBean(t, &PullRequest{ID: 1}).(*PullRequest)
+	assert.True(t, pr.IsReverted())
+	assert.Equal(t, "985f0301dba5e7b34be866819cd15ad3d8f508ee", pr.RevertedByCommitID)
+	assert.EqualValues(t, 2, pr.RevertedByPullID)
+
+	pr = AssertExistsAndLoadBean(t, &PullRequest{ID: 2}).(*PullRequest)
+	assert.True(t, IsErrPullRequestNotMerged(pr.SetRevertedBy("985f0301dba5e7b34be866819cd15ad3d8f508ee", 0)))
+}
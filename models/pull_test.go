@@ -6,6 +6,10 @@ package models
 
 import (
 	"testing"
+	"time"
+
+	"code.gitea.io/gitea/modules/git"
+	"code.gitea.io/gitea/modules/timeutil"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -29,6 +33,17 @@ func TestPullRequest_LoadIssue(t *testing.T) {
 	assert.Equal(t, int64(2), pr.Issue.ID)
 }
 
+func TestPullRequest_GetLabels(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+	pr := AssertExistsAndLoadBean(t, &PullRequest{ID: 1}).(*PullRequest)
+	labels, err := pr.GetLabels()
+	assert.NoError(t, err)
+	assert.NotNil(t, pr.Issue)
+	if assert.Len(t, labels, 1) {
+		assert.EqualValues(t, 1, labels[0].ID)
+	}
+}
+
 func TestPullRequest_GetBaseRepo(t *testing.T) {
 	assert.NoError(t, PrepareTestDatabase())
 	pr := AssertExistsAndLoadBean(t, &PullRequest{ID: 1}).(*PullRequest)
@@ -119,6 +134,56 @@ func TestGetUnmergedPullRequestsByBaseInfo(t *testing.T) {
 	assert.Equal(t, "master", pr.BaseBranch)
 }
 
+func TestCountOpenPullRequestsByBaseBranch(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+	count, err := CountOpenPullRequestsByBaseBranch(1, "master")
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, count)
+
+	count, err = CountOpenPullRequestsByBaseBranch(1, "branch2")
+	assert.NoError(t, err)
+	assert.EqualValues(t, 0, count)
+}
+
+func TestGetStalePullRequests(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+	prs, err := GetStalePullRequests(1, time.Unix(1000000000, 0))
+	assert.NoError(t, err)
+	if assert.Len(t, prs, 1) {
+		assert.Equal(t, int64(2), prs[0].ID)
+	}
+
+	prs, err = GetStalePullRequests(1, time.Unix(946684800, 0))
+	assert.NoError(t, err)
+	assert.Len(t, prs, 0)
+
+	repoIDs, err := GetStalePullRequestBaseRepoIDs(time.Unix(1000000000, 0))
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []int64{1, 10, 48}, repoIDs)
+}
+
+func TestDeleteOrphanedPullRequests(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	ids, err := FindOrphanedPullRequests()
+	assert.NoError(t, err)
+	assert.Empty(t, ids)
+
+	orphan := &PullRequest{IssueID: NonexistentID, BaseRepoID: 1, HeadRepoID: 1}
+	_, err = x.Insert(orphan)
+	assert.NoError(t, err)
+
+	ids, err = FindOrphanedPullRequests()
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{orphan.ID}, ids)
+
+	deleted, err := DeleteOrphanedPullRequests()
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, deleted)
+	AssertNotExistsBean(t, &PullRequest{ID: orphan.ID})
+	CheckConsistencyFor(t, &PullRequest{})
+}
+
 func TestGetPullRequestByIndex(t *testing.T) {
 	assert.NoError(t, PrepareTestDatabase())
 	pr, err := GetPullRequestByIndex(1, 2)
@@ -154,6 +219,25 @@ func TestGetPullRequestByIssueID(t *testing.T) {
 	assert.True(t, IsErrPullRequestNotExist(err))
 }
 
+func TestGetPullRequestsByIssueIDs(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+	prs, err := GetPullRequestsByIssueIDs([]int64{1, 2, 3, 9223372036854775807})
+	assert.NoError(t, err)
+	assert.Len(t, prs, 2)
+	if assert.Contains(t, prs, int64(2)) {
+		assert.EqualValues(t, 1, prs[2].ID)
+		assert.NotNil(t, prs[2].Merger)
+	}
+	if assert.Contains(t, prs, int64(3)) {
+		assert.EqualValues(t, 2, prs[3].ID)
+	}
+	assert.NotContains(t, prs, int64(1))
+
+	prs, err = GetPullRequestsByIssueIDs(nil)
+	assert.NoError(t, err)
+	assert.Empty(t, prs)
+}
+
 func TestPullRequest_Update(t *testing.T) {
 	assert.NoError(t, PrepareTestDatabase())
 	pr := AssertExistsAndLoadBean(t, &PullRequest{ID: 1}).(*PullRequest)
@@ -230,3 +314,158 @@ func TestPullRequest_GetWorkInProgressPrefixWorkInProgress(t *testing.T) {
 	pr.Issue.Title = "[wip] " + original
 	assert.Equal(t, "[wip]", pr.GetWorkInProgressPrefix())
 }
+
+func TestWorkInProgressPrefix(t *testing.T) {
+	kases := []struct {
+		prefixes []string
+		title    string
+		expected string
+	}{
+		{[]string{"WIP:", "[WIP]"}, "[wip] feature", "[wip]"},
+		{[]string{"WIP:"}, "WIP", ""},
+		{[]string{"", "WIP:"}, "feature", ""},
+		// the uppercase of ɐ takes more bytes than ɐ itself
+		{[]string{"Ɐ:"}, "ɐ: feature", "ɐ:"},
+		{[]string{"Ɐ:"}, "ɐ", ""},
+		// the uppercase of ſ takes less bytes than ſ itself
+		{[]string{"S:"}, "ſ: feature", "ſ:"},
+		{[]string{"Entwurf:"}, "ENTWURF: Größe", "ENTWURF:"},
+	}
+	for _, kase := range kases {
+		assert.Equal(t, kase.expected, workInProgressPrefix(kase.prefixes, kase.title), "title %q", kase.title)
+	}
+}
+
+func TestPullRequest_GetWorkInProgressPrefixRepository(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	pr := AssertExistsAndLoadBean(t, &PullRequest{ID: 2}).(*PullRequest)
+	assert.NoError(t, pr.LoadIssue())
+	assert.NoError(t, pr.LoadBaseRepo())
+
+	unit, err := pr.BaseRepo.GetUnit(UnitTypePullRequests)
+	assert.NoError(t, err)
+	unit.PullRequestsConfig().WorkInProgressPrefixes = []string{"[Draft]"}
+	assert.Equal(t, []string{"[Draft]"}, pr.BaseRepo.GetWorkInProgressPrefixes())
+
+	original := pr.Issue.Title
+	pr.Issue.Title = "[DRAFT] " + original
+	assert.True(t, pr.IsWorkInProgress())
+	assert.Equal(t, "[DRAFT]", pr.GetWorkInProgressPrefix())
+	assert.True(t, pr.BaseRepo.HasWorkInProgressPrefix(pr.Issue.Title))
+
+	// the global prefixes no longer apply once the repository defines its own
+	pr.Issue.Title = "WIP: " + original
+	assert.False(t, pr.IsWorkInProgress())
+	assert.Empty(t, pr.GetWorkInProgressPrefix())
+
+	unit.PullRequestsConfig().WorkInProgressPrefixes = nil
+	assert.True(t, pr.IsWorkInProgress())
+}
+
+func TestPullRequest_UnresolvedReviewThreadCount(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+	pr := AssertExistsAndLoadBean(t, &PullRequest{ID: 1}).(*PullRequest)
+	count, err := pr.UnresolvedReviewThreadCount()
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, count)
+
+	comment := AssertExistsAndLoadBean(t, &Comment{ID: 5}).(*Comment)
+	assert.NoError(t, MarkConversation(comment, true))
+	count, err = pr.UnresolvedReviewThreadCount()
+	assert.NoError(t, err)
+	assert.EqualValues(t, 0, count)
+
+	pr = AssertExistsAndLoadBean(t, &PullRequest{ID: 2}).(*PullRequest)
+	count, err = pr.UnresolvedReviewThreadCount()
+	assert.NoError(t, err)
+	assert.EqualValues(t, 0, count)
+}
+
+func TestPullRequest_HasUnresolvedConversations(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+	pr := AssertExistsAndLoadBean(t, &PullRequest{ID: 1}).(*PullRequest)
+	unresolved, err := pr.HasUnresolvedConversations()
+	assert.NoError(t, err)
+	assert.True(t, unresolved)
+
+	comment := AssertExistsAndLoadBean(t, &Comment{ID: 5}).(*Comment)
+	assert.NoError(t, MarkConversation(comment, true))
+	unresolved, err = pr.HasUnresolvedConversations()
+	assert.NoError(t, err)
+	assert.False(t, unresolved)
+}
+
+func TestPullRequest_CloseWithComment(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+	pr := AssertExistsAndLoadBean(t, &PullRequest{ID: 2}).(*PullRequest)
+	doer := AssertExistsAndLoadBean(t, &User{ID: 2}).(*User)
+
+	comment, closeComment, err := pr.CloseWithComment(doer, "closing as stale")
+	assert.NoError(t, err)
+	assert.NotNil(t, closeComment)
+	AssertExistsAndLoadBean(t, &Comment{ID: comment.ID, Type: CommentTypeComment, Content: "closing as stale"})
+	AssertExistsAndLoadBean(t, &Issue{ID: pr.IssueID, IsClosed: true})
+
+	comment, closeComment, err = pr.CloseWithComment(doer, "still closed")
+	assert.NoError(t, err)
+	assert.Nil(t, closeComment)
+	AssertExistsAndLoadBean(t, &Comment{ID: comment.ID, Content: "still closed"})
+}
+
+func TestPullRequest_SetMerged(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+	pr := AssertExistsAndLoadBean(t, &PullRequest{ID: 2}).(*PullRequest)
+	pr.MergedCommitID = "1234567890abcdef1234567890abcdef12345678"
+	pr.MergedUnix = timeutil.TimeStampNow()
+	pr.Merger = AssertExistsAndLoadBean(t, &User{ID: 2}).(*User)
+	pr.MergerID = pr.Merger.ID
+
+	assert.NoError(t, pr.SetMerged())
+	pr = AssertExistsAndLoadBean(t, &PullRequest{ID: 2}).(*PullRequest)
+	assert.True(t, pr.HasMerged)
+	assert.Equal(t, []int64{4}, pr.MergedReviewers)
+	CheckConsistencyFor(t, &Repository{ID: pr.BaseRepoID})
+}
+
+func TestPullRequest_IsSameRef(t *testing.T) {
+	pr := &PullRequest{HeadRepoID: 1, BaseRepoID: 1, HeadBranch: "master", BaseBranch: "master"}
+	assert.True(t, pr.IsSameRef())
+
+	pr.HeadBranch = "branch2"
+	assert.False(t, pr.IsSameRef())
+
+	pr.HeadBranch = "master"
+	pr.HeadRepoID = 2
+	assert.False(t, pr.IsSameRef())
+}
+
+func TestPullRequest_GetDefaultSquashMessageWithCoAuthors(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+	pr := AssertExistsAndLoadBean(t, &PullRequest{ID: 2}).(*PullRequest)
+
+	// no commits
+	pr.MergeBase = "985f0301dba5e7b34be866819cd15ad3d8f508ee"
+	message, err := pr.GetDefaultSquashMessageWithCoAuthors()
+	assert.NoError(t, err)
+	assert.Equal(t, "issue3 (#3)", message)
+
+	// both commits of branch2 have the same author
+	pr.MergeBase = "65f1bf27bc3bf70f64657658635e66094edbcb4d"
+	message, err = pr.GetDefaultSquashMessageWithCoAuthors()
+	assert.NoError(t, err)
+	assert.Equal(t, "issue3 (#3)\n\nCo-authored-by: 6543 <6543@obermui.de>\n", message)
+
+	// the poster is not credited twice
+	pr.Issue.Poster = &User{Name: "6543", Email: "6543@Obermui.de"}
+	message, err = pr.GetDefaultSquashMessageWithCoAuthors()
+	assert.NoError(t, err)
+	assert.Equal(t, "issue3 (#3)", message)
+}
+
+func TestIsBotSignature(t *testing.T) {
+	assert.True(t, isBotSignature(&git.Signature{Name: "dependabot[bot]", Email: "49699333+dependabot[bot]@users.noreply.github.com"}))
+	assert.True(t, isBotSignature(&git.Signature{Name: "Renovate", Email: "renovate[bot]@example.com"}))
+	assert.False(t, isBotSignature(&git.Signature{Name: "user2", Email: "user2@example.com"}))
+	assert.False(t, isBotSignature(&git.Signature{Name: "user2", Email: "user2@[bot].example.com"}))
+}
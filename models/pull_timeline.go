@@ -0,0 +1,106 @@
+// Copyright 2020 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"sort"
+
+	"code.gitea.io/gitea/modules/git"
+	"code.gitea.io/gitea/modules/timeutil"
+)
+
+// TimelineEntryType defines the kind of a pull request timeline entry
+type TimelineEntryType int
+
+// Enumerate all the pull request timeline entry types
+const (
+	TimelineEntryCommit TimelineEntryType = iota
+	TimelineEntryReview
+	TimelineEntryStatus
+	TimelineEntryMerged
+)
+
+// TimelineEntry represents a single event in the history of a pull request.
+// Depending on Type, one of Commit, Review or Status is set; merged entries carry none.
+type TimelineEntry struct {
+	Type   TimelineEntryType
+	Time   timeutil.TimeStamp
+	Commit *git.Commit
+	Review *Review
+	Status *CommitStatus
+}
+
+// GetTimeline returns the commits, submitted reviews, commit status updates and the merge
+// of the pull request, ordered by time. Entries with the same time keep that order.
+func (pr *PullRequest) GetTimeline() ([]TimelineEntry, error) {
+	commits, err := pr.commits()
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]TimelineEntry, 0, commits.Len())
+	shas := make([]string, 0, commits.Len())
+	// commits are listed newest first
+	for e := commits.Back(); e != nil; e = e.Prev() {
+		commit := e.Value.(*git.Commit)
+		shas = append(shas, commit.ID.String())
+		entries = append(entries, TimelineEntry{
+			Type:   TimelineEntryCommit,
+			Time:   timeutil.TimeStamp(commit.Committer.When.Unix()),
+			Commit: commit,
+		})
+	}
+
+	reviews, err := findReviews(x, FindReviewOptions{Type: ReviewTypeUnknown, IssueID: pr.IssueID})
+	if err != nil {
+		return nil, err
+	}
+	for _, review := range reviews {
+		if review.Type == ReviewTypePending {
+			continue
+		}
+		if err = review.loadReviewer(x); err != nil && !IsErrUserNotExist(err) {
+			return nil, err
+		}
+		if review.Reviewer == nil {
+			review.Reviewer = NewGhostUser()
+		}
+		entries = append(entries, TimelineEntry{
+			Type:   TimelineEntryReview,
+			Time:   review.CreatedUnix,
+			Review: review,
+		})
+	}
+
+	if len(shas) > 0 {
+		var statuses []*CommitStatus
+		if err = x.Where("repo_id = ?", pr.BaseRepoID).
+			In("sha", shas).
+			Asc("created_unix").
+			Asc("id").
+			Find(&statuses); err != nil {
+			return nil, err
+		}
+		for _, status := range statuses {
+			entries = append(entries, TimelineEntry{
+				Type:   TimelineEntryStatus,
+				Time:   status.CreatedUnix,
+				Status: status,
+			})
+		}
+	}
+
+	if pr.HasMerged {
+		entries = append(entries, TimelineEntry{
+			Type: TimelineEntryMerged,
+			Time: pr.MergedUnix,
+		})
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Time < entries[j].Time
+	})
+	return entries, nil
+}
@@ -0,0 +1,50 @@
+// Copyright 2020 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPullRequest_GetTimeline(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+	pr := AssertExistsAndLoadBean(t, &PullRequest{ID: 2}).(*PullRequest)
+	pr.MergeBase = "65f1bf27bc3bf70f64657658635e66094edbcb4d"
+
+	entries, err := pr.GetTimeline()
+	assert.NoError(t, err)
+
+	// the pending review is left out, the commit was made long after the reviews
+	var types []TimelineEntryType
+	var reviewIDs []int64
+	for i, entry := range entries {
+		types = append(types, entry.Type)
+		if entry.Type == TimelineEntryReview {
+			reviewIDs = append(reviewIDs, entry.Review.ID)
+			assert.NotNil(t, entry.Review.Reviewer)
+		}
+		if i > 0 {
+			assert.True(t, entries[i-1].Time <= entry.Time)
+		}
+	}
+	assert.Equal(t, []TimelineEntryType{
+		TimelineEntryReview, TimelineEntryReview, TimelineEntryReview, TimelineEntryReview, TimelineEntryReview,
+		TimelineEntryCommit,
+	}, types)
+	assert.Equal(t, []int64{5, 7, 8, 9, 10}, reviewIDs)
+	assert.Equal(t, "4a357436d925b5c974181ff12a994538ddc5a269", entries[5].Commit.ID.String())
+	// the reviewer of review 10 has been deleted
+	assert.EqualValues(t, -1, entries[4].Review.Reviewer.ID)
+
+	pr.HasMerged = true
+	pr.MergedUnix = entries[5].Time + 1
+	entries, err = pr.GetTimeline()
+	assert.NoError(t, err)
+	if assert.Len(t, entries, 7) {
+		assert.Equal(t, TimelineEntryMerged, entries[6].Type)
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright 2020 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"fmt"
+
+	"code.gitea.io/gitea/modules/timeutil"
+)
+
+// PullViewedFile represents a file of a pull request a user has marked as viewed,
+// it only counts as viewed as long as the head of the pull request is CommitID.
+type PullViewedFile struct {
+	ID          int64              `xorm:"pk autoincr"`
+	IssueID     int64              `xorm:"INDEX NOT NULL"`
+	UserID      int64              `xorm:"INDEX NOT NULL"`
+	TreePath    string             `xorm:"VARCHAR(4000) NOT NULL"`
+	CommitID    string             `xorm:"VARCHAR(40) NOT NULL"`
+	UpdatedUnix timeutil.TimeStamp `xorm:"updated"`
+}
+
+// MarkFileViewed records that the user has viewed the file at path when the head of the
+// pull request was the commit head.
+func (pr *PullRequest) MarkFileViewed(userID int64, path, head string) error {
+	sess := x.NewSession()
+	defer sess.Close()
+	if err := sess.Begin(); err != nil {
+		return err
+	}
+
+	viewed := &PullViewedFile{IssueID: pr.IssueID, UserID: userID, TreePath: path}
+	has, err := sess.Get(viewed)
+	if err != nil {
+		return err
+	}
+	viewed.CommitID = head
+	if has {
+		if _, err = sess.ID(viewed.ID).Cols("commit_id").Update(viewed); err != nil {
+			return err
+		}
+	} else if _, err = sess.Insert(viewed); err != nil {
+		return err
+	}
+
+	return sess.Commit()
+}
+
+// GetViewedFiles returns the paths of the files of the pull request the user has viewed
+// since its head was last changed.
+func (pr *PullRequest) GetViewedFiles(userID int64) (map[string]bool, error) {
+	gitRepo, err := pr.baseGitRepo()
+	if err != nil {
+		return nil, err
+	}
+	defer gitRepo.Close()
+
+	headCommitID, err := gitRepo.GetRefCommitID(pr.GetGitRefName())
+	if err != nil {
+		return nil, fmt.Errorf("GetRefCommitID(%s): %v", pr.GetGitRefName(), err)
+	}
+
+	files := make([]*PullViewedFile, 0, 10)
+	if err = x.Where("issue_id = ? AND user_id = ? AND commit_id = ?", pr.IssueID, userID, headCommitID).
+		Find(&files); err != nil {
+		return nil, err
+	}
+
+	viewed := make(map[string]bool, len(files))
+	for _, file := range files {
+		viewed[file.TreePath] = true
+	}
+	return viewed, nil
+}
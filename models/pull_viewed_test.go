@@ -0,0 +1,37 @@
+// Copyright 2020 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPullRequest_ViewedFiles(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+	pr := AssertExistsAndLoadBean(t, &PullRequest{ID: 2}).(*PullRequest)
+	const head = "4a357436d925b5c974181ff12a994538ddc5a269"
+
+	viewed, err := pr.GetViewedFiles(2)
+	assert.NoError(t, err)
+	assert.Empty(t, viewed)
+
+	assert.NoError(t, pr.MarkFileViewed(2, "README.md", head))
+	assert.NoError(t, pr.MarkFileViewed(2, "docs/index.md", "65f1bf27bc3bf70f64657658635e66094edbcb4d"))
+	assert.NoError(t, pr.MarkFileViewed(1, "LICENSE", head))
+
+	// files viewed at an older head are not viewed anymore
+	viewed, err = pr.GetViewedFiles(2)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]bool{"README.md": true}, viewed)
+
+	// viewing again at the current head updates the record
+	assert.NoError(t, pr.MarkFileViewed(2, "docs/index.md", head))
+	viewed, err = pr.GetViewedFiles(2)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]bool{"README.md": true, "docs/index.md": true}, viewed)
+	AssertCount(t, &PullViewedFile{IssueID: pr.IssueID, UserID: 2}, 2)
+}
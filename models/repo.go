@@ -165,6 +165,7 @@ type Repository struct {
 	NumPulls            int
 	NumClosedPulls      int
 	NumOpenPulls        int `xorm:"-"`
+	NumMergedPulls      int `xorm:"NOT NULL DEFAULT 0"`
 	NumMilestones       int `xorm:"NOT NULL DEFAULT 0"`
 	NumClosedMilestones int `xorm:"NOT NULL DEFAULT 0"`
 	NumOpenMilestones   int `xorm:"-"`
@@ -267,6 +268,17 @@ func (repo *Repository) APIFormat(mode AccessMode) *api.Repository {
 	return repo.innerAPIFormat(x, mode, false)
 }
 
+// GetBranchCommitIDCacheKey returns cache key used for caching the head commit of a branch.
+func (repo *Repository) GetBranchCommitIDCacheKey(branchName string) string {
+	return fmt.Sprintf("branch-commit-id-%d-%s", repo.ID, branchName)
+}
+
+// GetCommitsCountBetweenCacheKey returns cache key used for caching the number of commits
+// between two commits.
+func (repo *Repository) GetCommitsCountBetweenCacheKey(start, end string) string {
+	return fmt.Sprintf("commits-count-between-%d-%s-%s", repo.ID, start, end)
+}
+
 // GetCommitsCountCacheKey returns cache key used for commits count caching.
 func (repo *Repository) GetCommitsCountCacheKey(contextName string, isRef bool) string {
 	var prefix string
@@ -333,14 +345,16 @@ func (repo *Repository) innerAPIFormat(e Engine, mode AccessMode, isParent bool)
 	allowRebase := false
 	allowRebaseMerge := false
 	allowSquash := false
+	allowFastForwardOnly := false
 	if unit, err := repo.getUnit(e, UnitTypePullRequests); err == nil {
 		config := unit.PullRequestsConfig()
 		hasPullRequests = true
-		ignoreWhitespaceConflicts = config.IgnoreWhitespaceConflicts
+		ignoreWhitespaceConflicts = config.GetWhitespaceConflictMode() == WhitespaceConflictsIgnoreAll
 		allowMerge = config.AllowMerge
 		allowRebase = config.AllowRebase
 		allowRebaseMerge = config.AllowRebaseMerge
 		allowSquash = config.AllowSquash
+		allowFastForwardOnly = config.AllowFastForwardOnly
 	}
 
 	repo.mustOwner(e)
@@ -368,6 +382,7 @@ func (repo *Repository) innerAPIFormat(e Engine, mode AccessMode, isParent bool)
 		Watchers:                  repo.NumWatches,
 		OpenIssues:                repo.NumOpenIssues,
 		OpenPulls:                 repo.NumOpenPulls,
+		MergedPulls:               repo.NumMergedPulls,
 		Releases:                  repo.NumReleases,
 		DefaultBranch:             repo.DefaultBranch,
 		Created:                   repo.CreatedUnix.AsTime(),
@@ -384,6 +399,7 @@ func (repo *Repository) innerAPIFormat(e Engine, mode AccessMode, isParent bool)
 		AllowRebase:               allowRebase,
 		AllowRebaseMerge:          allowRebaseMerge,
 		AllowSquash:               allowSquash,
+		AllowFastForwardOnly:      allowFastForwardOnly,
 		AvatarURL:                 repo.avatarLink(e),
 	}
 }
@@ -1050,7 +1066,7 @@ func CreateRepository(ctx DBContext, doer, u *User, repo *Repository) (err error
 			units = append(units, RepoUnit{
 				RepoID: repo.ID,
 				Type:   tp,
-				Config: &PullRequestsConfig{AllowMerge: true, AllowRebase: true, AllowRebaseMerge: true, AllowSquash: true},
+				Config: &PullRequestsConfig{AllowMerge: true, AllowRebase: true, AllowRebaseMerge: true, AllowSquash: true, AllowFastForwardOnly: true},
 			})
 		} else {
 			units = append(units, RepoUnit{
@@ -1582,6 +1598,11 @@ func DeleteRepository(doer *User, uid, repoID int64) error {
 		return err
 	}
 
+	if _, err = sess.In("issue_id", deleteCond).
+		Delete(&PullViewedFile{}); err != nil {
+		return err
+	}
+
 	attachments = attachments[:0]
 	if err = sess.Join("INNER", "issue", "issue.id = attachment.issue_id").
 		Where("issue.repo_id = ?", repoID).
@@ -1989,6 +2010,18 @@ func GitFsck(ctx context.Context) {
 			}); err != nil {
 		log.Error("GitFsck: %v", err)
 	}
+
+	if setting.Cron.RepoHealthCheck.DeleteOrphanedPullRequests {
+		if count, err := DeleteOrphanedPullRequests(); err != nil {
+			log.Error("DeleteOrphanedPullRequests: %v", err)
+		} else if count > 0 {
+			desc := fmt.Sprintf("Deleted %d pull requests without an issue", count)
+			log.Warn(desc)
+			if err = CreateRepositoryNotice(desc); err != nil {
+				log.Error("CreateRepositoryNotice: %v", err)
+			}
+		}
+	}
 	log.Trace("Finished: GitFsck")
 }
 
@@ -2111,6 +2144,29 @@ func CheckRepoStats(ctx context.Context) {
 	}
 	// ***** END: Repository.NumClosedIssues *****
 
+	// ***** START: Repository.NumPulls *****
+	desc = "repository count 'num_pulls'"
+	results, err = x.Query("SELECT repo.id FROM `repository` repo WHERE repo.num_pulls!=(SELECT COUNT(*) FROM `issue` WHERE repo_id=repo.id AND is_pull=?)", true)
+	if err != nil {
+		log.Error("Select %s: %v", desc, err)
+	} else {
+		for _, result := range results {
+			select {
+			case <-ctx.Done():
+				log.Warn("CheckRepoStats: Aborting due to shutdown")
+				return
+			default:
+			}
+			id := com.StrTo(result["id"]).MustInt64()
+			log.Trace("Updating %s: %d", desc, id)
+			_, err = x.Exec("UPDATE `repository` SET num_pulls=(SELECT COUNT(*) FROM `issue` WHERE repo_id=? AND is_pull=?) WHERE id=?", id, true, id)
+			if err != nil {
+				log.Error("Update %s[%d]: %v", desc, id, err)
+			}
+		}
+	}
+	// ***** END: Repository.NumPulls *****
+
 	// ***** START: Repository.NumClosedPulls *****
 	desc = "repository count 'num_closed_pulls'"
 	results, err = x.Query("SELECT repo.id FROM `repository` repo WHERE repo.num_closed_pulls!=(SELECT COUNT(*) FROM `issue` WHERE repo_id=repo.id AND is_closed=? AND is_pull=?)", true, true)
@@ -2134,6 +2190,29 @@ func CheckRepoStats(ctx context.Context) {
 	}
 	// ***** END: Repository.NumClosedPulls *****
 
+	// ***** START: Repository.NumMergedPulls *****
+	desc = "repository count 'num_merged_pulls'"
+	results, err = x.Query("SELECT repo.id FROM `repository` repo WHERE repo.num_merged_pulls!=(SELECT COUNT(*) FROM `pull_request` WHERE base_repo_id=repo.id AND has_merged=?)", true)
+	if err != nil {
+		log.Error("Select %s: %v", desc, err)
+	} else {
+		for _, result := range results {
+			select {
+			case <-ctx.Done():
+				log.Warn("CheckRepoStats: Aborting due to shutdown")
+				return
+			default:
+			}
+			id := com.StrTo(result["id"]).MustInt64()
+			log.Trace("Updating %s: %d", desc, id)
+			_, err = x.Exec("UPDATE `repository` SET num_merged_pulls=(SELECT COUNT(*) FROM `pull_request` WHERE base_repo_id=? AND has_merged=?) WHERE id=?", id, true, id)
+			if err != nil {
+				log.Error("Update %s[%d]: %v", desc, id, err)
+			}
+		}
+	}
+	// ***** END: Repository.NumMergedPulls *****
+
 	// FIXME: use checker when stop supporting old fork repo format.
 	// ***** START: Repository.NumForks *****
 	results, err = x.Query("SELECT repo.id FROM `repository` repo WHERE repo.num_forks!=(SELECT COUNT(*) FROM `repository` WHERE fork_id=repo.id)")
@@ -6,6 +6,7 @@ package models
 
 import (
 	"bytes"
+	"context"
 	"crypto/md5"
 	"fmt"
 	"image"
@@ -187,3 +188,26 @@ func TestDeleteAvatar(t *testing.T) {
 
 	assert.Equal(t, "", repo.Avatar)
 }
+
+func TestCheckRepoStats(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	_, err := x.Exec("UPDATE `repository` SET num_pulls=?, num_merged_pulls=? WHERE id=?", 42, 42, 1)
+	assert.NoError(t, err)
+
+	CheckRepoStats(context.Background())
+
+	repo := AssertExistsAndLoadBean(t, &Repository{ID: 1}).(*Repository)
+	assert.EqualValues(t, 3, repo.NumPulls)
+	assert.EqualValues(t, 1, repo.NumMergedPulls)
+	CheckConsistencyFor(t, &Repository{ID: 1})
+}
+
+func TestRepoAPIFormat_MergedPulls(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	repo := AssertExistsAndLoadBean(t, &Repository{ID: 1}).(*Repository)
+	apiRepo := repo.APIFormat(AccessModeRead)
+	assert.EqualValues(t, repo.NumMergedPulls, apiRepo.MergedPulls)
+	assert.EqualValues(t, 1, apiRepo.MergedPulls)
+}
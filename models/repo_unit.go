@@ -7,6 +7,7 @@ package models
 import (
 	"encoding/json"
 
+	"code.gitea.io/gitea/modules/setting"
 	"code.gitea.io/gitea/modules/timeutil"
 
 	"github.com/unknwon/com"
@@ -86,13 +87,32 @@ func (cfg *IssuesConfig) ToDB() ([]byte, error) {
 	return json.Marshal(cfg)
 }
 
+// WhitespaceConflictMode defines how whitespace differences are treated
+// when checking whether a pull request conflicts with its base branch
+type WhitespaceConflictMode string
+
+const (
+	// WhitespaceConflictsStrict treats every whitespace difference as a conflict
+	WhitespaceConflictsStrict WhitespaceConflictMode = ""
+	// WhitespaceConflictsIgnoreEOL tolerates whitespace errors such as trailing whitespace
+	WhitespaceConflictsIgnoreEOL WhitespaceConflictMode = "eol"
+	// WhitespaceConflictsIgnoreAll ignores all whitespace differences
+	WhitespaceConflictsIgnoreAll WhitespaceConflictMode = "all"
+)
+
 // PullRequestsConfig describes pull requests config
 type PullRequestsConfig struct {
 	IgnoreWhitespaceConflicts bool
+	WhitespaceConflicts       WhitespaceConflictMode
 	AllowMerge                bool
 	AllowRebase               bool
 	AllowRebaseMerge          bool
 	AllowSquash               bool
+	AllowFastForwardOnly      bool
+	DisableStaleAutoClose     bool
+	RequireSignoff            bool
+	BlockBinaryFiles          bool
+	WorkInProgressPrefixes    []string
 }
 
 // FromDB fills up a PullRequestsConfig from serialized format.
@@ -105,12 +125,38 @@ func (cfg *PullRequestsConfig) ToDB() ([]byte, error) {
 	return json.Marshal(cfg)
 }
 
+// GetWhitespaceConflictMode returns how whitespace differences are treated in conflict checks.
+// The older IgnoreWhitespaceConflicts flag stands for WhitespaceConflictsIgnoreAll.
+func (cfg *PullRequestsConfig) GetWhitespaceConflictMode() WhitespaceConflictMode {
+	if cfg.WhitespaceConflicts == WhitespaceConflictsStrict && cfg.IgnoreWhitespaceConflicts {
+		return WhitespaceConflictsIgnoreAll
+	}
+	return cfg.WhitespaceConflicts
+}
+
+// SetWhitespaceConflictMode sets how whitespace differences are treated in conflict checks,
+// keeping the IgnoreWhitespaceConflicts flag in sync.
+func (cfg *PullRequestsConfig) SetWhitespaceConflictMode(mode WhitespaceConflictMode) {
+	cfg.WhitespaceConflicts = mode
+	cfg.IgnoreWhitespaceConflicts = mode == WhitespaceConflictsIgnoreAll
+}
+
+// GetWorkInProgressPrefixes returns the title prefixes marking a pull request as a work in progress.
+// It falls back to the global setting when the repository defines none.
+func (cfg *PullRequestsConfig) GetWorkInProgressPrefixes() []string {
+	if len(cfg.WorkInProgressPrefixes) > 0 {
+		return cfg.WorkInProgressPrefixes
+	}
+	return setting.Repository.PullRequest.WorkInProgressPrefixes
+}
+
 // IsMergeStyleAllowed returns if merge style is allowed
 func (cfg *PullRequestsConfig) IsMergeStyleAllowed(mergeStyle MergeStyle) bool {
 	return mergeStyle == MergeStyleMerge && cfg.AllowMerge ||
 		mergeStyle == MergeStyleRebase && cfg.AllowRebase ||
 		mergeStyle == MergeStyleRebaseMerge && cfg.AllowRebaseMerge ||
-		mergeStyle == MergeStyleSquash && cfg.AllowSquash
+		mergeStyle == MergeStyleSquash && cfg.AllowSquash ||
+		mergeStyle == MergeStyleFastForwardOnly && cfg.AllowFastForwardOnly
 }
 
 // BeforeSet is invoked from XORM before setting the value of a field of this object.
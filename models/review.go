@@ -385,6 +385,57 @@ func GetReviewersByIssueID(issueID int64) (reviews []*Review, err error) {
 	return reviews, nil
 }
 
+// ReviewCounts represents the numbers of approvals and change requests of a pull request
+type ReviewCounts struct {
+	Approvals      int64
+	ChangeRequests int64
+}
+
+// GetReviewCountsForPulls returns the review counts of the given pull requests keyed by their IDs,
+// counting the latest approving or rejecting review of each reviewer unless it is stale.
+// Pull requests without such reviews are missing from the result.
+func GetReviewCountsForPulls(prIDs []int64) (map[int64]ReviewCounts, error) {
+	counts := make(map[int64]ReviewCounts, len(prIDs))
+	if len(prIDs) == 0 {
+		return counts, nil
+	}
+
+	// latest approving or rejecting review of each reviewer, like GetReviewersByIssueID
+	latestReviews := builder.Select("max(review.id)").From("review").
+		InnerJoin("pull_request", "pull_request.issue_id = review.issue_id").
+		Where(builder.In("pull_request.id", prIDs).
+			And(builder.In("review.type", ReviewTypeApprove, ReviewTypeReject))).
+		GroupBy("review.issue_id, review.reviewer_id")
+
+	type reviewCount struct {
+		PullID int64
+		Type   ReviewType
+		Num    int64
+	}
+	reviewCounts := make([]*reviewCount, 0, len(prIDs))
+	if err := x.Table("review").
+		Select("pull_request.id AS pull_id, review.type, count(*) AS num").
+		Join("INNER", "pull_request", "pull_request.issue_id = review.issue_id").
+		Join("INNER", "`user`", "`user`.id = review.reviewer_id").
+		Where(builder.In("review.id", latestReviews)).
+		And("review.stale = ?", false).
+		GroupBy("pull_request.id, review.type").
+		Find(&reviewCounts); err != nil {
+		return nil, err
+	}
+
+	for _, count := range reviewCounts {
+		prCounts := counts[count.PullID]
+		if count.Type == ReviewTypeApprove {
+			prCounts.Approvals = count.Num
+		} else {
+			prCounts.ChangeRequests = count.Num
+		}
+		counts[count.PullID] = prCounts
+	}
+	return counts, nil
+}
+
 // MarkReviewsAsStale marks existing reviews as stale
 func MarkReviewsAsStale(issueID int64) (err error) {
 	_, err = x.Exec("UPDATE `review` SET stale=? WHERE issue_id=?", true, issueID)
@@ -132,3 +132,24 @@ func TestGetReviewersByIssueID(t *testing.T) {
 		assert.Equal(t, expectedReviews[i].UpdatedUnix, review.UpdatedUnix)
 	}
 }
+
+func TestGetReviewCountsForPulls(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	counts, err := GetReviewCountsForPulls([]int64{1, 2, 3})
+	assert.NoError(t, err)
+	assert.Equal(t, map[int64]ReviewCounts{
+		1: {Approvals: 1},
+		// the pending review is ignored as well as the review of the deleted user
+		2: {Approvals: 1, ChangeRequests: 2},
+	}, counts)
+
+	assert.NoError(t, MarkReviewsAsStale(3))
+	counts, err = GetReviewCountsForPulls([]int64{2})
+	assert.NoError(t, err)
+	assert.Empty(t, counts)
+
+	counts, err = GetReviewCountsForPulls(nil)
+	assert.NoError(t, err)
+	assert.Empty(t, counts)
+}
@@ -23,6 +23,7 @@ import (
 	"time"
 	"unicode/utf8"
 
+	"code.gitea.io/gitea/modules/auth/ldap"
 	"code.gitea.io/gitea/modules/avatar"
 	"code.gitea.io/gitea/modules/base"
 	"code.gitea.io/gitea/modules/generate"
@@ -106,6 +107,10 @@ type User struct {
 	// is to change his/her password after registration.
 	MustChangePassword bool `xorm:"NOT NULL DEFAULT false"`
 
+	// PasswordExpiresIn is the time until the password expires in the external
+	// login source, as reported on sign in. It is nil if unknown.
+	PasswordExpiresIn *time.Duration `xorm:"-"`
+
 	LoginType   LoginType
 	LoginSource int64 `xorm:"NOT NULL DEFAULT 0"`
 	LoginName   string
@@ -1162,6 +1167,7 @@ func deleteUser(e *xorm.Session, u *User) error {
 		&TeamUser{UID: u.ID},
 		&Collaboration{UserID: u.ID},
 		&Stopwatch{UserID: u.ID},
+		&PullViewedFile{UserID: u.ID},
 	); err != nil {
 		return fmt.Errorf("deleteBeans: %v", err)
 	}
@@ -1728,6 +1734,33 @@ func synchronizeLdapSSHPublicKeys(usr *User, s *LoginSource, sshPublicKeys []str
 	return sshKeysNeedUpdate
 }
 
+// linkLdapExternalID links the user to its external ID in the LDAP source,
+// so the user is still found once renamed in LDAP.
+func linkLdapExternalID(usr *User, s *LoginSource, externalID string) error {
+	err := LinkExternalToUser(usr, &ExternalLoginUser{
+		ExternalID:    externalID,
+		UserID:        usr.ID,
+		LoginSourceID: s.ID,
+		Name:          usr.Name,
+		Email:         usr.Email,
+	})
+	if IsErrExternalLoginUserAlreadyExist(err) {
+		return nil
+	}
+	return err
+}
+
+// renameLdapUser renames the user to the name it was renamed to in LDAP.
+func renameLdapUser(usr *User, newName, loginName string) error {
+	if err := ChangeUserName(usr, newName); err != nil {
+		return err
+	}
+	usr.Name = newName
+	usr.LowerName = strings.ToLower(newName)
+	usr.LoginName = loginName
+	return UpdateUserCols(usr, "name", "lower_name", "login_name")
+}
+
 // SyncExternalUsers is used to synchronize users with external authorization source
 func SyncExternalUsers(ctx context.Context) {
 	log.Trace("Doing: SyncExternalUsers")
@@ -1767,6 +1800,27 @@ func SyncExternalUsers(ctx context.Context) {
 				log.Error("SyncExternalUsers: %v", err)
 				return
 			}
+			// Find the users linked to their external ID, which survives renames
+			linkedUserIDs, err := getExternalLoginUserIDs(s.ID)
+			if err != nil {
+				log.Error("SyncExternalUsers: %v", err)
+				return
+			}
+			findUser := func(su *ldap.SearchResult) *User {
+				if userID, ok := linkedUserIDs[su.ExternalID]; ok && len(su.ExternalID) > 0 {
+					for _, du := range users {
+						if du.ID == userID {
+							return du
+						}
+					}
+				}
+				for _, du := range users {
+					if du.LowerName == strings.ToLower(su.Username) {
+						return du
+					}
+				}
+				return nil
+			}
 			select {
 			case <-ctx.Done():
 				log.Warn("SyncExternalUsers: Aborted due to shutdown before update of %s", s.Name)
@@ -1774,10 +1828,22 @@ func SyncExternalUsers(ctx context.Context) {
 			default:
 			}
 
-			sr, err := s.LDAP().SearchEntries()
-			if err != nil {
-				log.Error("SyncExternalUsers LDAP source failure [%s], skipped", s.Name)
-				continue
+			// Only fetch the entries changed since the last run if possible, falling back to a full search
+			incremental := s.LDAP().IsIncrementalSync()
+			var sr []*ldap.SearchResult
+			if incremental {
+				sr, err = s.LDAP().SearchChangedEntries()
+				if err != nil {
+					log.Warn("SyncExternalUsers[%s]: Incremental search failed, falling back to a full search: %v", s.Name, err)
+					incremental = false
+				}
+			}
+			if !incremental {
+				sr, err = s.LDAP().SearchEntries()
+				if err != nil {
+					log.Error("SyncExternalUsers LDAP source failure [%s], skipped", s.Name)
+					continue
+				}
 			}
 
 			for _, su := range sr {
@@ -1798,18 +1864,26 @@ func SyncExternalUsers(ctx context.Context) {
 					continue
 				}
 
+				if !su.IsMember {
+					if s.LDAP().RequireGroupMembership && updateExisting {
+						// Deactivate explicitly as incremental synchronizations do not deactivate missing users
+						if du := findUser(su); du != nil && du.IsActive {
+							log.Trace("SyncExternalUsers[%s]: Deactivating user %s: %v", s.Name, du.Name, ErrLDAPNotInRequiredGroup{s.Name, su.Username})
+							du.IsActive = false
+							if err = UpdateUserCols(du, "is_active"); err != nil {
+								log.Error("SyncExternalUsers[%s]: Error deactivating user %s: %v", s.Name, du.Name, err)
+							}
+						}
+					}
+					continue
+				}
+
 				if len(su.Mail) == 0 {
 					su.Mail = fmt.Sprintf("%s@localhost", su.Username)
 				}
 
-				var usr *User
 				// Search for existing user
-				for _, du := range users {
-					if du.LowerName == strings.ToLower(su.Username) {
-						usr = du
-						break
-					}
-				}
+				usr := findUser(su)
 
 				fullName := composeFullName(su.Name, su.Surname, su.Username)
 				// If no existing user found, create one
@@ -1826,28 +1900,53 @@ func SyncExternalUsers(ctx context.Context) {
 						Email:       su.Mail,
 						IsAdmin:     su.IsAdmin,
 						IsActive:    true,
+						Language:    su.Language,
 					}
 
 					err = CreateUser(usr)
 
 					if err != nil {
 						log.Error("SyncExternalUsers[%s]: Error creating user %s: %v", s.Name, su.Username, err)
-					} else if isAttributeSSHPublicKeySet {
+					} else if usr.IsAdmin {
+						logLDAPAdminGrant(s, usr.Name, su.AdminGroups)
+					}
+					if err == nil && isAttributeSSHPublicKeySet {
 						log.Trace("SyncExternalUsers[%s]: Adding LDAP Public SSH Keys for user %s", s.Name, usr.Name)
 						if addLdapSSHPublicKeys(usr, s, su.SSHPublicKey) {
 							sshKeysNeedUpdate = true
 						}
 					}
+					if err == nil && len(su.ExternalID) > 0 {
+						if err = linkLdapExternalID(usr, s, su.ExternalID); err != nil {
+							log.Error("SyncExternalUsers[%s]: Error linking user %s to external ID %s: %v", s.Name, usr.Name, su.ExternalID, err)
+						}
+					}
 				} else if updateExisting {
 					existingUsers = append(existingUsers, usr.ID)
 
+					if len(su.ExternalID) > 0 {
+						if _, ok := linkedUserIDs[su.ExternalID]; !ok {
+							if err = linkLdapExternalID(usr, s, su.ExternalID); err != nil {
+								log.Error("SyncExternalUsers[%s]: Error linking user %s to external ID %s: %v", s.Name, usr.Name, su.ExternalID, err)
+							}
+						}
+					}
+
+					// Follow renames of users found by their external ID
+					if usr.LowerName != strings.ToLower(su.Username) {
+						log.Trace("SyncExternalUsers[%s]: Renaming user %s to %s", s.Name, usr.Name, su.Username)
+						if err = renameLdapUser(usr, su.Username, su.Username); err != nil {
+							log.Error("SyncExternalUsers[%s]: Error renaming user %s to %s: %v", s.Name, usr.Name, su.Username, err)
+						}
+					}
+
 					// Synchronize SSH Public Key if that attribute is set
 					if isAttributeSSHPublicKeySet && synchronizeLdapSSHPublicKeys(usr, s, su.SSHPublicKey) {
 						sshKeysNeedUpdate = true
 					}
 
 					// Check if user data has changed
-					if (len(s.LDAP().AdminFilter) > 0 && usr.IsAdmin != su.IsAdmin) ||
+					if (s.LDAP().HasAdminFilter() && usr.IsAdmin != su.IsAdmin) ||
 						!strings.EqualFold(usr.Email, su.Mail) ||
 						usr.FullName != fullName ||
 						!usr.IsActive {
@@ -1857,7 +1956,10 @@ func SyncExternalUsers(ctx context.Context) {
 						usr.FullName = fullName
 						usr.Email = su.Mail
 						// Change existing admin flag only if AdminFilter option is set
-						if len(s.LDAP().AdminFilter) > 0 {
+						if s.LDAP().HasAdminFilter() {
+							if su.IsAdmin && !usr.IsAdmin {
+								logLDAPAdminGrant(s, usr.Name, su.AdminGroups)
+							}
 							usr.IsAdmin = su.IsAdmin
 						}
 						usr.IsActive = true
@@ -1885,8 +1987,13 @@ func SyncExternalUsers(ctx context.Context) {
 			default:
 			}
 
-			// Deactivate users not present in LDAP
-			if updateExisting {
+			// Remember the most recent change for the next incremental run
+			if err = s.updateSyncState(sr, incremental); err != nil {
+				log.Error("SyncExternalUsers[%s]: Error saving synchronization state: %v", s.Name, err)
+			}
+
+			// Deactivate users not present in LDAP, which only a full search can tell
+			if updateExisting && !incremental {
 				for _, usr := range users {
 					found := false
 					for _, uid := range existingUsers {
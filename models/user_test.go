@@ -386,3 +386,39 @@ func TestGetUserIDsByNames(t *testing.T) {
 	assert.Error(t, err)
 	assert.Equal(t, []int64(nil), IDs)
 }
+
+func TestLinkLdapExternalID(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+	source := &LoginSource{ID: 1}
+	user := AssertExistsAndLoadBean(t, &User{ID: 2}).(*User)
+
+	assert.NoError(t, linkLdapExternalID(user, source, "8fd7b3b4-3ee8-4ca1-8d29-5a5d0a3dc6c1"))
+	// linking again is no error
+	assert.NoError(t, linkLdapExternalID(user, source, "8fd7b3b4-3ee8-4ca1-8d29-5a5d0a3dc6c1"))
+
+	ids, err := getExternalLoginUserIDs(source.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int64{"8fd7b3b4-3ee8-4ca1-8d29-5a5d0a3dc6c1": 2}, ids)
+
+	ids, err = getExternalLoginUserIDs(2)
+	assert.NoError(t, err)
+	assert.Empty(t, ids)
+}
+
+func TestRenameLdapUser(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+	user := AssertExistsAndLoadBean(t, &User{ID: 2}).(*User)
+
+	assert.NoError(t, renameLdapUser(user, "User2-Renamed", "user2-renamed@example.com"))
+	user = AssertExistsAndLoadBean(t, &User{ID: 2}).(*User)
+	assert.Equal(t, "User2-Renamed", user.Name)
+	assert.Equal(t, "user2-renamed", user.LowerName)
+	assert.Equal(t, "user2-renamed@example.com", user.LoginName)
+
+	// names taken by other users are not stolen
+	err := renameLdapUser(user, "user4", "user4")
+	assert.True(t, IsErrUserAlreadyExist(err))
+	AssertExistsAndLoadBean(t, &User{ID: 2, Name: "User2-Renamed"})
+
+	assert.NoError(t, renameLdapUser(user, "user2", "user2"))
+}
@@ -66,6 +66,7 @@ type HookEvents struct {
 	PullRequest  bool `json:"pull_request"`
 	Repository   bool `json:"repository"`
 	Release      bool `json:"release"`
+	Status       bool `json:"status"`
 }
 
 // HookEvent represents events that will delivery hook.
@@ -74,6 +75,7 @@ type HookEvent struct {
 	SendEverything bool   `json:"send_everything"`
 	ChooseEvents   bool   `json:"choose_events"`
 	BranchFilter   string `json:"branch_filter"`
+	BatchWindow    int    `json:"batch_window"` // seconds to wait for further events before delivering
 
 	HookEvents `json:"events"`
 }
@@ -184,6 +186,12 @@ func (w *Webhook) HasRepositoryEvent() bool {
 		(w.ChooseEvents && w.HookEvents.Repository)
 }
 
+// HasStatusEvent returns if hook enabled commit status event.
+func (w *Webhook) HasStatusEvent() bool {
+	return w.SendEverything ||
+		(w.ChooseEvents && w.HookEvents.Status)
+}
+
 // EventCheckers returns event checkers
 func (w *Webhook) EventCheckers() []struct {
 	Has  func() bool
@@ -202,6 +210,7 @@ func (w *Webhook) EventCheckers() []struct {
 		{w.HasPullRequestEvent, HookEventPullRequest},
 		{w.HasRepositoryEvent, HookEventRepository},
 		{w.HasReleaseEvent, HookEventRelease},
+		{w.HasStatusEvent, HookEventStatus},
 	}
 }
 
@@ -490,6 +499,7 @@ const (
 	HookEventPullRequest         HookEventType = "pull_request"
 	HookEventRepository          HookEventType = "repository"
 	HookEventRelease             HookEventType = "release"
+	HookEventStatus              HookEventType = "status"
 	HookEventPullRequestApproved HookEventType = "pull_request_approved"
 	HookEventPullRequestRejected HookEventType = "pull_request_rejected"
 	HookEventPullRequestComment  HookEventType = "pull_request_comment"
@@ -516,6 +526,7 @@ type HookTask struct {
 	Type            HookTaskType
 	URL             string `xorm:"TEXT"`
 	Signature       string `xorm:"TEXT"`
+	BodySignature   string `xorm:"TEXT"`
 	api.Payloader   `xorm:"-"`
 	PayloadContent  string `xorm:"TEXT"`
 	HTTPMethod      string `xorm:"http_method"`
@@ -0,0 +1,131 @@
+// Copyright 2020 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package acme provides helpers for clients of golang.org/x/crypto/acme which the
+// package itself does not offer, e.g. to decide whether and when to retry a request.
+package acme
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/acme"
+)
+
+// StatusIsFinal returns if status is a state an ACME order, authorization or challenge
+// never leaves, so that polling it can stop. Pending, processing, ready and unknown
+// statuses are not final.
+func StatusIsFinal(status string) bool {
+	switch status {
+	case acme.StatusValid, acme.StatusInvalid, acme.StatusRevoked, acme.StatusDeactivated, acme.StatusExpired:
+		return true
+	}
+	return false
+}
+
+// IsErrorType returns if err is an *acme.Error of the given problem type, e.g. "connection".
+// Both the pre-RFC "urn:acme:error:" and the "urn:ietf:params:acme:error:" namespaces are
+// recognized, case-insensitively as not all CAs are consistent about it.
+func IsErrorType(err error, typ string) bool {
+	e, ok := err.(*acme.Error)
+	return ok && strings.HasSuffix(strings.ToLower(e.ProblemType), ":error:"+strings.ToLower(typ))
+}
+
+// IsBadNonce returns if err is an *acme.Error telling that the CA rejected the anti-replay
+// nonce of the request. Such a request should be retried once, with a fresh nonce.
+// Any problem type ending in ":badNonce" is recognized as CAs use their own namespaces.
+func IsBadNonce(err error) bool {
+	e, ok := err.(*acme.Error)
+	return ok && strings.HasSuffix(strings.ToLower(e.ProblemType), ":badnonce")
+}
+
+// transientErrorTypes are the problem types of failures which may go away on their own
+var transientErrorTypes = []string{"connection", "serverInternal", "dns"}
+
+// IsRetryable returns if retrying the failed authorization later could succeed, which is
+// the case if all of its errors have a transient problem type. Any other error, e.g. of
+// the types "caa" or "rejectedIdentifier", makes the failure permanent.
+func IsRetryable(err *acme.AuthorizationError) bool {
+	if len(err.Errors) == 0 {
+		return false
+	}
+	for _, e := range err.Errors {
+		transient := false
+		for _, typ := range transientErrorTypes {
+			if IsErrorType(e, typ) {
+				transient = true
+				break
+			}
+		}
+		if !transient {
+			return false
+		}
+	}
+	return true
+}
+
+const (
+	// minBackoff is the delay returned by Backoff if the CA gave no usable Retry-After hint
+	minBackoff = time.Second
+	// maxBackoff caps the delay returned by Backoff
+	maxBackoff = 24 * time.Hour
+)
+
+// Backoff returns how long to wait as of now before retrying the request which failed with e.
+// The Retry-After header of the response is honoured in both its delay-seconds and HTTP-date
+// forms. A missing, invalid or past value results in a short delay rather than none, and
+// absurdly long delays are capped at a day.
+func Backoff(e *acme.Error, now time.Time) time.Duration {
+	var d time.Duration
+	if e.Header != nil {
+		d = retryAfter(e.Header.Get("Retry-After"), now)
+	}
+	if d < minBackoff {
+		return minBackoff
+	}
+	if d > maxBackoff {
+		return maxBackoff
+	}
+	return d
+}
+
+// retryAfter parses the value of a Retry-After header relative to now,
+// it returns 0 if the value can not be parsed.
+func retryAfter(v string, now time.Time) time.Duration {
+	if i, err := strconv.Atoi(v); err == nil {
+		return time.Duration(i) * time.Second
+	}
+	t, err := http.ParseTime(v)
+	if err != nil {
+		return 0
+	}
+	return t.Sub(now)
+}
+
+// AlternateLinks returns the URLs of the rel="alternate" links in h in their order, which CAs
+// use to point to alternate chains of a certificate, e.g. cross-signed ones. The issuers of a
+// chain are linked with rel="up" and followed by acme.Client.FetchCert already.
+func AlternateLinks(h http.Header) []string {
+	return links(h, "alternate")
+}
+
+// links returns the URLs of the links in h with the given relation,
+// a single Link header may hold several comma separated links.
+func links(h http.Header, rel string) []string {
+	var urls []string
+	for _, v := range h["Link"] {
+		for _, link := range strings.Split(v, ",") {
+			parts := strings.Split(link, ";")
+			for _, p := range parts[1:] {
+				p = strings.TrimSpace(p)
+				if strings.HasPrefix(p, "rel=") && strings.Trim(p[4:], `"`) == rel {
+					urls = append(urls, strings.Trim(strings.TrimSpace(parts[0]), "<>"))
+				}
+			}
+		}
+	}
+	return urls
+}
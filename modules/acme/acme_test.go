@@ -0,0 +1,149 @@
+// Copyright 2020 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package acme
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/acme"
+)
+
+func TestStatusIsFinal(t *testing.T) {
+	for _, status := range []string{acme.StatusValid, acme.StatusInvalid, acme.StatusRevoked, acme.StatusDeactivated, acme.StatusExpired} {
+		assert.True(t, StatusIsFinal(status), status)
+	}
+	for _, status := range []string{acme.StatusPending, acme.StatusProcessing, acme.StatusReady, acme.StatusUnknown, ""} {
+		assert.False(t, StatusIsFinal(status), status)
+	}
+}
+
+func TestIsErrorType(t *testing.T) {
+	kases := []struct {
+		problemType string
+		typ         string
+		expected    bool
+	}{
+		{"urn:ietf:params:acme:error:connection", "connection", true},
+		{"urn:acme:error:connection", "connection", true},
+		{"urn:ietf:params:acme:error:rejectedIdentifier", "rejectedidentifier", true},
+		{"urn:ietf:params:acme:error:caa", "connection", false},
+		{"urn:ietf:params:acme:error:xconnection", "connection", false},
+	}
+	for _, kase := range kases {
+		assert.Equal(t, kase.expected, IsErrorType(&acme.Error{ProblemType: kase.problemType}, kase.typ), kase.problemType)
+	}
+	assert.False(t, IsErrorType(errors.New("urn:acme:error:connection"), "connection"))
+}
+
+func TestIsBadNonce(t *testing.T) {
+	assert.True(t, IsBadNonce(&acme.Error{ProblemType: "urn:ietf:params:acme:error:badNonce"}))
+	assert.True(t, IsBadNonce(&acme.Error{ProblemType: "urn:example:BADNONCE"}))
+	assert.False(t, IsBadNonce(&acme.Error{ProblemType: "urn:ietf:params:acme:error:malformed"}))
+	assert.False(t, IsBadNonce(errors.New("badNonce")))
+}
+
+func TestIsRetryable(t *testing.T) {
+	connection := &acme.Error{ProblemType: "urn:ietf:params:acme:error:connection"}
+	dns := &acme.Error{ProblemType: "urn:ietf:params:acme:error:dns"}
+	caa := &acme.Error{ProblemType: "urn:ietf:params:acme:error:caa"}
+
+	assert.True(t, IsRetryable(&acme.AuthorizationError{Errors: []error{connection, dns}}))
+	assert.False(t, IsRetryable(&acme.AuthorizationError{Errors: []error{connection, caa}}))
+	assert.False(t, IsRetryable(&acme.AuthorizationError{Errors: []error{errors.New("timeout")}}))
+	assert.False(t, IsRetryable(&acme.AuthorizationError{}))
+}
+
+func TestBackoff(t *testing.T) {
+	now := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	kases := []struct {
+		retryAfter string
+		expected   time.Duration
+	}{
+		{"", minBackoff},
+		{"0", minBackoff},
+		{"-5", minBackoff},
+		{"invalid", minBackoff},
+		{"120", 2 * time.Minute},
+		{now.Add(time.Hour).Format(http.TimeFormat), time.Hour},
+		{now.Add(-time.Hour).Format(http.TimeFormat), minBackoff},
+		{"31536000", maxBackoff},
+	}
+	for _, kase := range kases {
+		header := http.Header{}
+		if kase.retryAfter != "" {
+			header.Set("Retry-After", kase.retryAfter)
+		}
+		assert.Equal(t, kase.expected, Backoff(&acme.Error{Header: header}, now), kase.retryAfter)
+	}
+	assert.Equal(t, minBackoff, Backoff(&acme.Error{}, now))
+}
+
+func TestAlternateLinks(t *testing.T) {
+	h := http.Header{}
+	h.Add("Link", `<https://example.com/cert/1>;rel="alternate"`)
+	h.Add("Link", `<https://example.com/issuer>; rel="up", <https://example.com/cert/2>; rel="alternate"`)
+	assert.Equal(t, []string{"https://example.com/cert/1", "https://example.com/cert/2"}, AlternateLinks(h))
+	assert.Empty(t, AlternateLinks(http.Header{}))
+}
+
+func TestDecodeDirectory(t *testing.T) {
+	dir, err := DecodeDirectory(strings.NewReader(`{
+		"newAccount": "https://example.com/acme/new-acct",
+		"newNonce": "https://example.com/acme/new-nonce",
+		"newOrder": "https://example.com/acme/new-order",
+		"meta": {
+			"termsOfService": "https://example.com/terms",
+			"website": "https://example.com",
+			"caaIdentities": ["example.com"],
+			"externalAccountRequired": true
+		}
+	}`))
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com/acme/new-acct", dir.RegURL)
+	assert.Equal(t, "https://example.com/acme/new-order", dir.OrderURL)
+	assert.Equal(t, "https://example.com/terms", dir.Terms)
+	assert.Equal(t, "https://example.com", dir.Website)
+	assert.Equal(t, []string{"example.com"}, dir.CAA)
+	assert.True(t, dir.ExternalAccountRequired)
+
+	// CAs without a newOrder endpoint use the pre-RFC names
+	dir, err = DecodeDirectory(strings.NewReader(`{
+		"new-reg": "https://example.com/acme/new-reg",
+		"meta": {"terms-of-service": "https://example.com/terms", "caa-identities": ["example.com"]}
+	}`))
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com/acme/new-reg", dir.RegURL)
+	assert.Equal(t, "https://example.com/terms", dir.Terms)
+	assert.Equal(t, []string{"example.com"}, dir.CAA)
+
+	_, err = DecodeDirectory(strings.NewReader("{"))
+	assert.Error(t, err)
+}
+
+func TestHTTP01Response(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	c := &acme.Challenge{Type: "http-01", Token: "token"}
+	assert.Equal(t, "/.well-known/acme-challenge/token", HTTP01ResponsePath(c))
+	body, err := HTTP01ResponseBody(c, key)
+	assert.NoError(t, err)
+	expected, err := (&acme.Client{Key: key}).HTTP01ChallengeResponse("token")
+	assert.NoError(t, err)
+	assert.Equal(t, expected, body)
+
+	c = &acme.Challenge{Type: "dns-01", Token: "token"}
+	assert.Empty(t, HTTP01ResponsePath(c))
+	_, err = HTTP01ResponseBody(c, key)
+	assert.Error(t, err)
+}
@@ -0,0 +1,34 @@
+// Copyright 2020 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package acme
+
+import (
+	"crypto"
+	"fmt"
+
+	"golang.org/x/crypto/acme"
+)
+
+// HTTP01ResponsePath returns the URL path the response to the http-01 challenge c
+// must be served at, or an empty string if c is not an http-01 challenge.
+func HTTP01ResponsePath(c *acme.Challenge) string {
+	if c.Type != "http-01" {
+		return ""
+	}
+	return "/.well-known/acme-challenge/" + c.Token
+}
+
+// HTTP01ResponseBody returns the key authorization to serve at HTTP01ResponsePath
+// for the account key.
+func HTTP01ResponseBody(c *acme.Challenge, key crypto.Signer) (string, error) {
+	if c.Type != "http-01" {
+		return "", fmt.Errorf("challenge type %q is not http-01", c.Type)
+	}
+	thumbprint, err := acme.JWKThumbprint(key.Public())
+	if err != nil {
+		return "", err
+	}
+	return c.Token + "." + thumbprint, nil
+}
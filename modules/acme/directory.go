@@ -0,0 +1,73 @@
+// Copyright 2020 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package acme
+
+import (
+	"encoding/json"
+	"io"
+
+	"golang.org/x/crypto/acme"
+)
+
+// wireDirectory is the JSON representation of an ACME directory,
+// with both the RFC 8555 and the pre-RFC (draft-02) field names.
+type wireDirectory struct {
+	Reg          string `json:"new-reg"`
+	RegRFC       string `json:"newAccount"`
+	Authz        string `json:"new-authz"`
+	AuthzRFC     string `json:"newAuthz"`
+	OrderRFC     string `json:"newOrder"`
+	Cert         string `json:"new-cert"`
+	Revoke       string `json:"revoke-cert"`
+	RevokeRFC    string `json:"revokeCert"`
+	NonceRFC     string `json:"newNonce"`
+	KeyChangeRFC string `json:"keyChange"`
+	Meta         struct {
+		Terms           string   `json:"terms-of-service"`
+		TermsRFC        string   `json:"termsOfService"`
+		WebsiteRFC      string   `json:"website"`
+		CAA             []string `json:"caa-identities"`
+		CAARFC          []string `json:"caaIdentities"`
+		ExternalAcctRFC bool     `json:"externalAccountRequired"`
+	} `json:"meta"`
+}
+
+// directory maps the endpoints and the meta object of v, the pre-RFC field names are
+// used for CAs which do not advertise a newOrder endpoint.
+func (v *wireDirectory) directory() acme.Directory {
+	if v.OrderRFC == "" {
+		return acme.Directory{
+			RegURL:    v.Reg,
+			AuthzURL:  v.Authz,
+			CertURL:   v.Cert,
+			RevokeURL: v.Revoke,
+			Terms:     v.Meta.Terms,
+			Website:   v.Meta.WebsiteRFC,
+			CAA:       v.Meta.CAA,
+		}
+	}
+	return acme.Directory{
+		RegURL:                  v.RegRFC,
+		AuthzURL:                v.AuthzRFC,
+		OrderURL:                v.OrderRFC,
+		RevokeURL:               v.RevokeRFC,
+		NonceURL:                v.NonceRFC,
+		KeyChangeURL:            v.KeyChangeRFC,
+		Terms:                   v.Meta.TermsRFC,
+		Website:                 v.Meta.WebsiteRFC,
+		CAA:                     v.Meta.CAARFC,
+		ExternalAccountRequired: v.Meta.ExternalAcctRFC,
+	}
+}
+
+// DecodeDirectory reads an ACME directory object, including the terms of service,
+// website, CAA identities and external account requirement of its meta object.
+func DecodeDirectory(r io.Reader) (acme.Directory, error) {
+	var v wireDirectory
+	if err := json.NewDecoder(r).Decode(&v); err != nil {
+		return acme.Directory{}, err
+	}
+	return v.directory(), nil
+}
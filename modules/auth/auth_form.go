@@ -15,21 +15,38 @@ type AuthenticationForm struct {
 	Type                          int    `binding:"Range(2,7)"`
 	Name                          string `binding:"Required;MaxSize(30)"`
 	Host                          string
+	FailoverHosts                 string
 	Port                          int
+	Timeout                       int
 	BindDN                        string
 	BindPassword                  string
 	UserBase                      string
+	UserBases                     string
 	UserDN                        string
 	AttributeUsername             string
 	AttributeName                 string
 	AttributeSurname              string
 	AttributeMail                 string
 	AttributeSSHPublicKey         string
+	AttributeLanguage             string
+	AttributeExternalID           string
+	AttributeChanged              string
+	FullSyncInterval              int
 	AttributesInBind              bool
 	UsePagedSearch                bool
 	SearchPageSize                int
+	SortResults                   bool
+	PoolSize                      int
 	Filter                        string
 	AdminFilter                   string
+	AdminFilters                  string
+	GroupAttributeName            string
+	SearchRetries                 int
+	SearchCacheTTL                int
+	MemberGroupFilter             string
+	RequireGroupMembership        bool
+	NestedGroupSearch             bool
+	NestedGroupMaxDepth           int
 	IsActive                      bool
 	IsSyncEnabled                 bool
 	SMTPAuth                      string
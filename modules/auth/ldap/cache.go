@@ -0,0 +1,74 @@
+// Copyright 2020 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package ldap
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+type cachedSearch struct {
+	results []*SearchResult
+	expires time.Time
+}
+
+// searchCache keeps the results of searches for users by the configuration of the
+// source and the filter, so that a changed configuration never returns stale results.
+var searchCache = struct {
+	lock    sync.Mutex
+	entries map[string]*cachedSearch
+}{entries: make(map[string]*cachedSearch)}
+
+// searchCacheKey returns the key of the results of a search with userFilter.
+// The state of the synchronizations and the enabled flag are not part of the configuration.
+func (ls *Source) searchCacheKey(userFilter string) string {
+	cfg := *ls
+	cfg.LastSyncChanged = ""
+	cfg.IncrementalSyncs = 0
+	cfg.Enabled = false
+	bs, _ := json.Marshal(&cfg)
+	sum := sha256.Sum256(append(bs, userFilter...))
+	return hex.EncodeToString(sum[:])
+}
+
+// cachedSearchResults returns the cached results of a search with userFilter if they
+// are younger than SearchCacheTTL. The results are shared and must not be modified.
+func (ls *Source) cachedSearchResults(userFilter string) ([]*SearchResult, bool) {
+	if ls.SearchCacheTTL <= 0 {
+		return nil, false
+	}
+	key := ls.searchCacheKey(userFilter)
+
+	searchCache.lock.Lock()
+	defer searchCache.lock.Unlock()
+	for k, c := range searchCache.entries {
+		if time.Now().After(c.expires) {
+			delete(searchCache.entries, k)
+		}
+	}
+	c, ok := searchCache.entries[key]
+	if !ok {
+		return nil, false
+	}
+	return c.results, true
+}
+
+// cacheSearchResults keeps the results of a search with userFilter for SearchCacheTTL.
+func (ls *Source) cacheSearchResults(userFilter string, results []*SearchResult) {
+	if ls.SearchCacheTTL <= 0 {
+		return
+	}
+	key := ls.searchCacheKey(userFilter)
+
+	searchCache.lock.Lock()
+	defer searchCache.lock.Unlock()
+	searchCache.entries[key] = &cachedSearch{
+		results: results,
+		expires: time.Now().Add(ls.SearchCacheTTL),
+	}
+}
@@ -0,0 +1,48 @@
+// Copyright 2020 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package ldap
+
+import (
+	"fmt"
+
+	ber "gopkg.in/asn1-ber.v1"
+)
+
+// controlTypeServerSideSorting - https://tools.ietf.org/html/rfc2891
+const controlTypeServerSideSorting = "1.2.840.113556.1.4.473"
+
+// controlServerSideSorting implements the server side sorting request control.
+// It is never marked as critical, so servers not supporting it ignore it.
+type controlServerSideSorting struct {
+	// AttributeType is the attribute the results are ordered by
+	AttributeType string
+}
+
+// GetControlType returns the OID
+func (c *controlServerSideSorting) GetControlType() string {
+	return controlTypeServerSideSorting
+}
+
+// Encode returns the ber packet representation
+func (c *controlServerSideSorting) Encode() *ber.Packet {
+	packet := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Control")
+	packet.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, controlTypeServerSideSorting, "Control Type (Server Side Sorting)"))
+
+	value := ber.Encode(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, nil, "Control Value (Server Side Sorting)")
+	keys := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Sort Key List")
+	key := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Sort Key")
+	key.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, c.AttributeType, "Attribute Type"))
+	keys.AppendChild(key)
+	value.AppendChild(keys)
+
+	packet.AppendChild(value)
+	return packet
+}
+
+// String returns a human-readable description
+func (c *controlServerSideSorting) String() string {
+	return fmt.Sprintf("Control Type: Server Side Sorting (%q)  Criticality: %t  AttributeType: %s",
+		controlTypeServerSideSorting, false, c.AttributeType)
+}
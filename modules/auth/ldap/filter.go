@@ -0,0 +1,100 @@
+// Copyright 2020 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package ldap
+
+import (
+	"strings"
+
+	ber "gopkg.in/asn1-ber.v1"
+	ldap "gopkg.in/ldap.v3"
+)
+
+// groupAttribute is the attribute of an entry listing the DNs of the groups it is a direct member of
+const groupAttribute = "memberOf"
+
+// normalizeDN returns a form of dn which is the same for all spellings of the same DN,
+// ignoring case and the spaces around separators.
+func normalizeDN(dn string) string {
+	lowerDN := strings.ToLower(strings.TrimSpace(dn))
+	parsed, err := ldap.ParseDN(lowerDN)
+	if err != nil {
+		return lowerDN
+	}
+	rdns := make([]string, 0, len(parsed.RDNs))
+	for _, rdn := range parsed.RDNs {
+		attributes := make([]string, 0, len(rdn.Attributes))
+		for _, attribute := range rdn.Attributes {
+			attributes = append(attributes, attribute.Type+"="+attribute.Value)
+		}
+		rdns = append(rdns, strings.Join(attributes, "+"))
+	}
+	return strings.Join(rdns, ",")
+}
+
+// resolveMemberOf rewrites filter, replacing every equality assertion on memberOf by a term
+// which always matches if isMember reports the asserted group and never matches otherwise.
+// All other terms are kept, so that the server still evaluates them together with the
+// resolved memberships, including negations.
+func resolveMemberOf(filter string, isMember func(groupDN string) bool) (string, error) {
+	packet, err := ldap.CompileFilter(filter)
+	if err != nil {
+		return "", err
+	}
+	return ldap.DecompileFilter(replaceMemberOf(packet, isMember))
+}
+
+func replaceMemberOf(packet *ber.Packet, isMember func(groupDN string) bool) *ber.Packet {
+	switch packet.Tag {
+	case ldap.FilterAnd, ldap.FilterOr, ldap.FilterNot:
+		for i, child := range packet.Children {
+			packet.Children[i] = replaceMemberOf(child, isMember)
+		}
+	case ldap.FilterEqualityMatch:
+		if strings.EqualFold(ber.DecodeString(packet.Children[0].Data.Bytes()), groupAttribute) {
+			return constantFilter(isMember(ber.DecodeString(packet.Children[1].Data.Bytes())))
+		}
+	}
+	return packet
+}
+
+// constantFilter returns a filter term matching every entry if matches is set, and none otherwise
+func constantFilter(matches bool) *ber.Packet {
+	filter := "(objectClass=*)"
+	if !matches {
+		filter = "(!(objectClass=*))"
+	}
+	packet, _ := ldap.CompileFilter(filter)
+	return packet
+}
+
+// positiveMemberOf returns the normalized DNs of the groups filter asserts membership of
+// with an equality assertion on memberOf which is not negated, i.e. the groups whose
+// members can match filter because of their membership.
+func positiveMemberOf(filter string) (map[string]bool, error) {
+	packet, err := ldap.CompileFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+	groups := make(map[string]bool)
+	collectMemberOf(packet, false, groups)
+	return groups, nil
+}
+
+func collectMemberOf(packet *ber.Packet, negated bool, groups map[string]bool) {
+	switch packet.Tag {
+	case ldap.FilterAnd, ldap.FilterOr:
+		for _, child := range packet.Children {
+			collectMemberOf(child, negated, groups)
+		}
+	case ldap.FilterNot:
+		for _, child := range packet.Children {
+			collectMemberOf(child, !negated, groups)
+		}
+	case ldap.FilterEqualityMatch:
+		if !negated && strings.EqualFold(ber.DecodeString(packet.Children[0].Data.Bytes()), groupAttribute) {
+			groups[normalizeDN(ber.DecodeString(packet.Children[1].Data.Bytes()))] = true
+		}
+	}
+}
@@ -8,10 +8,18 @@ package ldap
 
 import (
 	"crypto/tls"
+	"encoding/binary"
+	"encoding/hex"
 	"fmt"
+	"net"
+	"strconv"
 	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"code.gitea.io/gitea/modules/log"
+	"code.gitea.io/gitea/modules/setting"
 
 	ldap "gopkg.in/ldap.v3"
 )
@@ -28,25 +36,44 @@ const (
 
 // Source Basic LDAP authentication service
 type Source struct {
-	Name                  string // canonical name (ie. corporate.ad)
-	Host                  string // LDAP host
-	Port                  int    // port number
-	SecurityProtocol      SecurityProtocol
-	SkipVerify            bool
-	BindDN                string // DN to bind with
-	BindPassword          string // Bind DN password
-	UserBase              string // Base search path for users
-	UserDN                string // Template for the DN of the user for simple auth
-	AttributeUsername     string // Username attribute
-	AttributeName         string // First name attribute
-	AttributeSurname      string // Surname attribute
-	AttributeMail         string // E-mail attribute
-	AttributesInBind      bool   // fetch attributes in bind context (not user)
-	AttributeSSHPublicKey string // LDAP SSH Public Key attribute
-	SearchPageSize        uint32 // Search with paging page size
-	Filter                string // Query filter to validate entry
-	AdminFilter           string // Query filter to check if user is admin
-	Enabled               bool   // if this source is disabled
+	Name                   string   // canonical name (ie. corporate.ad)
+	Host                   string   // LDAP host
+	FailoverHosts          []string // Additional LDAP hosts tried in order when Host cannot be reached
+	Port                   int      // port number
+	Timeout                int      // Connection timeout in seconds, 0 uses the default
+	SecurityProtocol       SecurityProtocol
+	SkipVerify             bool
+	BindDN                 string        // DN to bind with
+	BindPassword           string        // Bind DN password
+	UserBase               string        // Base search path for users
+	UserBases              []string      // Additional base search paths for users, searched in order when UserBase has no single match
+	UserDN                 string        // Template for the DN of the user for simple auth
+	AttributeUsername      string        // Username attribute
+	AttributeName          string        // First name attribute
+	AttributeSurname       string        // Surname attribute
+	AttributeMail          string        // E-mail attribute
+	AttributesInBind       bool          // fetch attributes in bind context (not user)
+	AttributeSSHPublicKey  string        // LDAP SSH Public Key attribute
+	AttributeLanguage      string        // Preferred language attribute
+	AttributeExternalID    string        // Stable unique identifier attribute (e.g. objectGUID or entryUUID)
+	AttributeChanged       string        // Last modification attribute (e.g. whenChanged), enables incremental synchronization
+	LastSyncChanged        string        // Highest AttributeChanged value seen by the last synchronization
+	FullSyncInterval       int           // Number of incremental synchronizations between two full ones, 0 uses the default
+	IncrementalSyncs       int           // Number of incremental synchronizations since the last full one
+	SearchPageSize         uint32        // Search with paging page size
+	SortResults            bool          // Ask the server to sort search results by username
+	PoolSize               int           // Number of idle BindDN connections kept for reuse
+	SearchRetries          int           // Number of retries of searches failing with a transient error
+	SearchCacheTTL         time.Duration // Time the results of identical searches are reused, 0 disables caching
+	Filter                 string        // Query filter to validate entry
+	AdminFilter            string        // Query filter to check if user is admin
+	AdminFilters           []string      // Additional query filters to check if user is admin, any match is enough
+	GroupAttributeName     string        // Attribute of the groups granting admin to read their display name from (e.g. cn)
+	MemberGroupFilter      string        // Query filter to check if user is a member of the group allowed to sign in
+	RequireGroupMembership bool          // Deny users not matching MemberGroupFilter with an explicit error
+	NestedGroupSearch      bool          // Evaluate the memberOf assertions of MemberGroupFilter against the nested groups of users
+	NestedGroupMaxDepth    int           // Maximum number of group levels followed by NestedGroupSearch, 0 uses the default
+	Enabled                bool          // if this source is disabled
 }
 
 // SearchResult : user data
@@ -56,7 +83,31 @@ type SearchResult struct {
 	Surname      string   // Surname
 	Mail         string   // E-mail address
 	SSHPublicKey []string // SSH Public Key
+	Language     string   // Preferred language, empty if unknown
+	ExternalID   string   // Value of the external ID attribute, empty if unknown
+	Changed      string   // Value of the last modification attribute, empty if unknown
 	IsAdmin      bool     // if user is administrator
+	AdminGroups  []string // display names of the groups granting administrator privileges, if GroupAttributeName is set
+	IsMember     bool     // if user matches MemberGroupFilter, always true without one
+
+	PasswordExpiresIn *time.Duration // Time until the password expires, nil if unknown
+}
+
+// ErrUserNotFound represents a "UserNotFound" kind of error:
+// the user filter matched no entry or more than one.
+type ErrUserNotFound struct {
+	Name    string
+	Matches int
+}
+
+// IsErrUserNotFound checks if an error is a ErrUserNotFound.
+func IsErrUserNotFound(err error) bool {
+	_, ok := err.(ErrUserNotFound)
+	return ok
+}
+
+func (err ErrUserNotFound) Error() string {
+	return fmt.Sprintf("LDAP user does not match exactly one entry [name: %s, matches: %d]", err.Name, err.Matches)
 }
 
 func (ls *Source) sanitizedUserQuery(username string) (string, bool) {
@@ -81,6 +132,11 @@ func (ls *Source) sanitizedUserDN(username string) (string, bool) {
 	return fmt.Sprintf(ls.UserDN, username), true
 }
 
+// userBases returns UserBase followed by the additional UserBases
+func (ls *Source) userBases() []string {
+	return append([]string{ls.UserBase}, ls.UserBases...)
+}
+
 func (ls *Source) findUserDN(l *ldap.Conn, name string) (string, bool) {
 	log.Trace("Search for LDAP user: %s", name)
 
@@ -90,86 +146,414 @@ func (ls *Source) findUserDN(l *ldap.Conn, name string) (string, bool) {
 		return "", false
 	}
 
-	log.Trace("Searching for DN using filter %s and base %s", userFilter, ls.UserBase)
-	search := ldap.NewSearchRequest(
-		ls.UserBase, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0,
-		false, userFilter, []string{}, nil)
+	// Search the bases in order, the first one matching exactly one user wins
+	for _, userBase := range ls.userBases() {
+		log.Trace("Searching for DN using filter %s and base %s", userFilter, userBase)
+		search := ldap.NewSearchRequest(
+			userBase, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0,
+			false, userFilter, []string{}, nil)
+
+		// Ensure we found a user
+		sr, err := ls.searchWithRetries(l, search)
+		if err != nil || len(sr.Entries) < 1 {
+			log.Debug("Failed search using filter[%s] and base[%s]: %v", userFilter, userBase, err)
+			continue
+		} else if len(sr.Entries) > 1 {
+			log.Debug("Filter '%s' returned more than one user in base '%s'.", userFilter, userBase)
+			continue
+		}
 
-	// Ensure we found a user
-	sr, err := l.Search(search)
-	if err != nil || len(sr.Entries) < 1 {
-		log.Debug("Failed search using filter[%s]: %v", userFilter, err)
-		return "", false
-	} else if len(sr.Entries) > 1 {
-		log.Debug("Filter '%s' returned more than one user.", userFilter)
-		return "", false
-	}
+		userDN := sr.Entries[0].DN
+		if userDN == "" {
+			log.Error("LDAP search was successful, but found no DN!")
+			return "", false
+		}
 
-	userDN := sr.Entries[0].DN
-	if userDN == "" {
-		log.Error("LDAP search was successful, but found no DN!")
-		return "", false
+		return userDN, true
 	}
 
-	return userDN, true
+	return "", false
 }
 
+// dial connects to the first reachable of Host and FailoverHosts, in this order.
+// The timeout applies to every host on its own.
 func dial(ls *Source) (*ldap.Conn, error) {
-	log.Trace("Dialing LDAP with security protocol (%v) without verifying: %v", ls.SecurityProtocol, ls.SkipVerify)
+	var err error
+	for i, host := range append([]string{ls.Host}, ls.FailoverHosts...) {
+		var conn *ldap.Conn
+		if conn, err = dialHost(ls, host); err == nil {
+			if i > 0 {
+				log.Warn("LDAP source %q connected to failover host %s, %s could not be reached", ls.Name, host, ls.Host)
+			} else {
+				log.Trace("Connected to LDAP host %s", host)
+			}
+			return conn, nil
+		}
+		log.Debug("Unable to connect to LDAP host %s: %v", host, err)
+	}
+	return nil, err
+}
+
+func dialHost(ls *Source, host string) (*ldap.Conn, error) {
+	log.Trace("Dialing LDAP host %s with security protocol (%v) without verifying: %v", host, ls.SecurityProtocol, ls.SkipVerify)
 
 	tlsCfg := &tls.Config{
-		ServerName:         ls.Host,
+		ServerName:         host,
 		InsecureSkipVerify: ls.SkipVerify,
 	}
+	timeout := ldap.DefaultTimeout
+	if ls.Timeout > 0 {
+		timeout = time.Duration(ls.Timeout) * time.Second
+	}
+	dialer := &net.Dialer{Timeout: timeout}
+	addr := net.JoinHostPort(host, strconv.Itoa(ls.Port))
+
 	if ls.SecurityProtocol == SecurityProtocolLDAPS {
-		return ldap.DialTLS("tcp", fmt.Sprintf("%s:%d", ls.Host, ls.Port), tlsCfg)
+		c, err := tls.DialWithDialer(dialer, "tcp", addr, tlsCfg)
+		if err != nil {
+			return nil, fmt.Errorf("DialTLS: %v", err)
+		}
+		conn := ldap.NewConn(c, true)
+		if ls.Timeout > 0 {
+			conn.SetTimeout(timeout)
+		}
+		conn.Start()
+		return conn, nil
+	}
+
+	if ls.SecurityProtocol == SecurityProtocolUnencrypted {
+		log.Warn("LDAP source %q connects to %s unencrypted, binds including passwords are sent in plaintext", ls.Name, addr)
 	}
 
-	conn, err := ldap.Dial("tcp", fmt.Sprintf("%s:%d", ls.Host, ls.Port))
+	c, err := dialer.Dial("tcp", addr)
 	if err != nil {
 		return nil, fmt.Errorf("Dial: %v", err)
 	}
+	conn := ldap.NewConn(c, false)
+	if ls.Timeout > 0 {
+		conn.SetTimeout(timeout)
+	}
+	conn.Start()
 
 	if ls.SecurityProtocol == SecurityProtocolStartTLS {
 		if err = conn.StartTLS(tlsCfg); err != nil {
 			conn.Close()
 			return nil, fmt.Errorf("StartTLS: %v", err)
 		}
+		// Never fall back to plaintext: make sure the connection really is TLS-wrapped now
+		if state, ok := conn.TLSConnectionState(); !ok || !state.HandshakeComplete {
+			conn.Close()
+			return nil, fmt.Errorf("StartTLS: connection to %s is not encrypted, refusing to continue in plaintext", addr)
+		}
 	}
 
 	return conn, nil
 }
 
-func bindUser(l *ldap.Conn, userDN, passwd string) error {
+// bindUser binds as userDN and returns the time until the password expires
+// if the server reported it in a password policy response control.
+func bindUser(l *ldap.Conn, userDN, passwd string) (*time.Duration, error) {
 	log.Trace("Binding with userDN: %s", userDN)
-	err := l.Bind(userDN, passwd)
+	res, err := l.SimpleBind(ldap.NewSimpleBindRequest(userDN, passwd, []ldap.Control{ldap.NewControlBeheraPasswordPolicy()}))
 	if err != nil {
 		log.Debug("LDAP auth. failed for %s, reason: %v", userDN, err)
-		return err
+		return nil, err
 	}
 	log.Trace("Bound successfully with userDN: %s", userDN)
-	return err
+
+	expiresIn := passwordExpiresIn(res.Controls)
+	if expiresIn != nil {
+		log.Debug("Password of %s expires in %v", userDN, *expiresIn)
+	}
+	return expiresIn, nil
+}
+
+// passwordExpiresIn reads the time until the password expires from the password
+// policy controls of a bind response. It returns nil if the server did not send one.
+func passwordExpiresIn(controls []ldap.Control) *time.Duration {
+	var seconds int64 = -1
+	if c, ok := ldap.FindControl(controls, ldap.ControlTypeBeheraPasswordPolicy).(*ldap.ControlBeheraPasswordPolicy); ok && c.Expire >= 0 {
+		seconds = c.Expire
+	} else if c, ok := ldap.FindControl(controls, ldap.ControlTypeVChuPasswordWarning).(*ldap.ControlVChuPasswordWarning); ok && c.Expire >= 0 {
+		seconds = c.Expire
+	}
+	if seconds < 0 {
+		return nil
+	}
+	d := time.Duration(seconds) * time.Second
+	return &d
+}
+
+// adminFilters returns the non-empty admin filters, AdminFilter first.
+func (ls *Source) adminFilters() []string {
+	filters := make([]string, 0, len(ls.AdminFilters)+1)
+	for _, filter := range append([]string{ls.AdminFilter}, ls.AdminFilters...) {
+		if filter = strings.TrimSpace(filter); len(filter) > 0 {
+			filters = append(filters, filter)
+		}
+	}
+	return filters
+}
+
+// HasAdminFilter returns if any admin filter is configured
+func (ls *Source) HasAdminFilter() bool {
+	return len(ls.adminFilters()) > 0
 }
 
-func checkAdmin(l *ldap.Conn, ls *Source, userDN string) bool {
-	if len(ls.AdminFilter) > 0 {
-		log.Trace("Checking admin with filter %s and base %s", ls.AdminFilter, userDN)
+// adminFilter combines all admin filters into a single filter matching any of them,
+// so that the admin check needs a single search.
+func (ls *Source) adminFilter() string {
+	filters := ls.adminFilters()
+	if len(filters) == 1 {
+		return filters[0]
+	}
+	return "(|" + strings.Join(filters, "") + ")"
+}
+
+// checkAdmin returns if the user matches an admin filter. If GroupAttributeName is set, it also
+// returns the display names of the groups of the user which are referenced by an admin filter.
+func checkAdmin(l *ldap.Conn, ls *Source, userDN string) (bool, []string) {
+	if ls.HasAdminFilter() {
+		adminFilter := ls.adminFilter()
+		attributes := []string{ls.AttributeName}
+		if len(ls.GroupAttributeName) > 0 {
+			attributes = append(attributes, "memberOf")
+		}
+		log.Trace("Checking admin with filter %s and base %s", adminFilter, userDN)
 		search := ldap.NewSearchRequest(
-			userDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false, ls.AdminFilter,
-			[]string{ls.AttributeName},
+			userDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false, adminFilter,
+			attributes,
 			nil)
 
-		sr, err := l.Search(search)
+		sr, err := ls.searchWithRetries(l, search)
 
 		if err != nil {
 			log.Error("LDAP Admin Search failed unexpectedly! (%v)", err)
 		} else if len(sr.Entries) < 1 {
 			log.Trace("LDAP Admin Search found no matching entries.")
 		} else {
-			return true
+			return true, ls.adminGroupNames(l, adminFilter, sr.Entries[0].GetAttributeValues("memberOf"))
+		}
+	}
+	return false, nil
+}
+
+// adminGroupNames returns the GroupAttributeName values of the groups among groupDNs
+// which adminFilter asserts a (not negated) memberOf of, or the DN of a group whose entry
+// can not be read.
+func (ls *Source) adminGroupNames(l *ldap.Conn, adminFilter string, groupDNs []string) []string {
+	if len(ls.GroupAttributeName) == 0 {
+		return nil
+	}
+	adminGroups, err := positiveMemberOf(adminFilter)
+	if err != nil {
+		log.Error("Unable to parse admin filter %s: %v", adminFilter, err)
+		return nil
+	}
+	var names []string
+	for _, groupDN := range groupDNs {
+		if !adminGroups[normalizeDN(groupDN)] {
+			continue
+		}
+		search := ldap.NewSearchRequest(
+			groupDN, ldap.ScopeBaseObject, ldap.NeverDerefAliases, 1, 0, false, "(objectClass=*)",
+			[]string{ls.GroupAttributeName},
+			nil)
+		sr, err := ls.searchWithRetries(l, search)
+		if err != nil || len(sr.Entries) < 1 || len(sr.Entries[0].GetAttributeValue(ls.GroupAttributeName)) == 0 {
+			log.Debug("Unable to read %s of group %s: %v", ls.GroupAttributeName, groupDN, err)
+			names = append(names, groupDN)
+			continue
+		}
+		names = append(names, sr.Entries[0].GetAttributeValue(ls.GroupAttributeName))
+	}
+	return names
+}
+
+func checkMemberGroup(l *ldap.Conn, ls *Source, userDN string) bool {
+	if len(ls.MemberGroupFilter) == 0 {
+		return true
+	}
+	if ls.NestedGroupSearch {
+		return ls.isNestedGroupMember(l, userDN)
+	}
+	log.Trace("Checking group membership with filter %s and base %s", ls.MemberGroupFilter, userDN)
+	search := ldap.NewSearchRequest(
+		userDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false, ls.MemberGroupFilter,
+		[]string{ls.AttributeName},
+		nil)
+
+	sr, err := ls.searchWithRetries(l, search)
+	if err != nil {
+		log.Error("LDAP Group Membership Search failed unexpectedly! (%v)", err)
+		return false
+	} else if len(sr.Entries) < 1 {
+		log.Trace("LDAP Group Membership Search found no matching entries.")
+		return false
+	}
+	return true
+}
+
+// defaultNestedGroupMaxDepth is the number of group levels followed if NestedGroupMaxDepth is not set
+const defaultNestedGroupMaxDepth = 5
+
+// isNestedGroupMember returns if the user matches MemberGroupFilter when the groups reached by following
+// the memberOf values from the entry of the user through the entries of its groups, level by level,
+// are taken into account. The membership assertions of the filter are resolved against these groups,
+// the other terms of the filter are still evaluated by the server on the entry of the user.
+func (ls *Source) isNestedGroupMember(l *ldap.Conn, userDN string) bool {
+	groups := ls.nestedGroups(l, userDN)
+	filter, err := resolveMemberOf(ls.MemberGroupFilter, func(groupDN string) bool {
+		return groups[normalizeDN(groupDN)]
+	})
+	if err != nil {
+		log.Error("Invalid member group filter %s: %v", ls.MemberGroupFilter, err)
+		return false
+	}
+
+	log.Trace("Checking nested group membership with filter %s and base %s", filter, userDN)
+	search := ldap.NewSearchRequest(
+		userDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false, filter,
+		[]string{ls.AttributeName},
+		nil)
+	sr, err := ls.searchWithRetries(l, search)
+	if err != nil {
+		log.Error("LDAP Nested Group Membership Search failed unexpectedly! (%v)", err)
+		return false
+	}
+	return len(sr.Entries) > 0
+}
+
+// nestedGroups returns the normalized DNs of the groups of the user up to NestedGroupMaxDepth levels.
+func (ls *Source) nestedGroups(l *ldap.Conn, userDN string) map[string]bool {
+	maxDepth := ls.NestedGroupMaxDepth
+	if maxDepth <= 0 {
+		maxDepth = defaultNestedGroupMaxDepth
+	}
+
+	// groups can be members of each other, every entry is only read once
+	groups := make(map[string]bool)
+	entries := []string{userDN}
+	for depth := 1; depth <= maxDepth && len(entries) > 0; depth++ {
+		var next []string
+		for _, dn := range entries {
+			for _, groupDN := range ls.memberOf(l, dn) {
+				normalized := normalizeDN(groupDN)
+				if groups[normalized] {
+					continue
+				}
+				groups[normalized] = true
+				next = append(next, groupDN)
+			}
+		}
+		entries = next
+	}
+	return groups
+}
+
+// memberOf returns the DNs of the groups the entry is a direct member of.
+func (ls *Source) memberOf(l *ldap.Conn, dn string) []string {
+	search := ldap.NewSearchRequest(
+		dn, ldap.ScopeBaseObject, ldap.NeverDerefAliases, 1, 0, false, "(objectClass=*)",
+		[]string{groupAttribute},
+		nil)
+	sr, err := ls.searchWithRetries(l, search)
+	if err != nil || len(sr.Entries) < 1 {
+		log.Debug("Unable to read the groups of %s: %v", dn, err)
+		return nil
+	}
+	return sr.Entries[0].GetAttributeValues(groupAttribute)
+}
+
+// Ping checks that the LDAP server is reachable and usable by dialing it,
+// binding with the BindDN (or anonymously if none is configured) and reading
+// the entries of all user search bases. No user credentials are needed.
+func (ls *Source) Ping() error {
+	l, err := dial(ls)
+	if err != nil {
+		return fmt.Errorf("unable to connect to %s:%d: %v", ls.Host, ls.Port, err)
+	}
+	defer l.Close()
+
+	if ls.BindDN != "" && ls.BindPassword != "" {
+		if err := l.Bind(ls.BindDN, ls.BindPassword); err != nil {
+			return fmt.Errorf("unable to bind as %s: %v", ls.BindDN, err)
+		}
+	}
+
+	for _, userBase := range ls.userBases() {
+		search := ldap.NewSearchRequest(userBase, ldap.ScopeBaseObject, ldap.NeverDerefAliases, 1, ls.Timeout, false,
+			"(objectClass=*)", []string{"1.1"}, nil)
+		if _, err := ls.searchWithRetries(l, search); err != nil {
+			return fmt.Errorf("unable to read user search base %q: %v", userBase, err)
 		}
 	}
-	return false
+	return nil
+}
+
+// ResolveUsername looks up the entry matching the user filter for input, which may be any
+// identifier the filter accepts (e.g. an e-mail address), and returns the value of its
+// username attribute. Like for sign-in, the first user search base with a single matching
+// entry is used. The search is done with the BindDN, no user password is needed.
+func (ls *Source) ResolveUsername(input string) (string, error) {
+	userFilter, ok := ls.sanitizedUserQuery(input)
+	if !ok {
+		return "", ErrUserNotFound{Name: input}
+	}
+
+	pooled := ls.usePool()
+	l, err := ls.getConn(pooled)
+	if err != nil {
+		log.Error("LDAP Connect error, %s:%v", ls.Host, err)
+		return "", err
+	}
+	reusable := false
+	defer func() {
+		ls.putConn(l, pooled && reusable)
+	}()
+
+	if ls.BindDN != "" && ls.BindPassword != "" {
+		if err := l.Bind(ls.BindDN, ls.BindPassword); err != nil {
+			log.Debug("Failed to bind as BindDN[%s]: %v", ls.BindDN, err)
+			return "", err
+		}
+		log.Trace("Bound as BindDN %s", ls.BindDN)
+	} else {
+		log.Trace("Proceeding with anonymous LDAP search.")
+	}
+
+	var entry *ldap.Entry
+	matches := 0
+	for _, userBase := range ls.userBases() {
+		log.Trace("Resolving username using filter %s and base %s", userFilter, userBase)
+		search := ldap.NewSearchRequest(
+			userBase, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false, userFilter,
+			[]string{ls.AttributeUsername}, nil)
+
+		sr, err := ls.searchWithRetries(l, search)
+		if err != nil {
+			log.Debug("Failed search using filter[%s] and base[%s]: %v", userFilter, userBase, err)
+			return "", err
+		}
+		if len(sr.Entries) == 1 {
+			entry = sr.Entries[0]
+			break
+		}
+		if len(sr.Entries) > matches {
+			matches = len(sr.Entries)
+		}
+	}
+	reusable = true
+	if entry == nil {
+		return "", ErrUserNotFound{Name: input, Matches: matches}
+	}
+
+	username := entry.GetAttributeValue(ls.AttributeUsername)
+	if username == "" {
+		log.Error("LDAP search for %s was successful, but the entry has no %s attribute", input, ls.AttributeUsername)
+		return "", ErrUserNotFound{Name: input, Matches: 1}
+	}
+	return username, nil
 }
 
 // SearchEntry : search an LDAP source if an entry (name, passwd) is valid and in the specific filter
@@ -179,15 +563,20 @@ func (ls *Source) SearchEntry(name, passwd string, directBind bool) *SearchResul
 		log.Debug("Auth. failed for %s, password cannot be empty", name)
 		return nil
 	}
-	l, err := dial(ls)
+	pooled := !directBind && ls.usePool()
+	l, err := ls.getConn(pooled)
 	if err != nil {
 		log.Error("LDAP Connect error, %s:%v", ls.Host, err)
 		ls.Enabled = false
 		return nil
 	}
-	defer l.Close()
+	reusable := false
+	defer func() {
+		ls.putConn(l, pooled && reusable)
+	}()
 
 	var userDN string
+	var expiresIn *time.Duration
 	if directBind {
 		log.Trace("LDAP will bind directly via UserDN template: %s", ls.UserDN)
 
@@ -198,7 +587,7 @@ func (ls *Source) SearchEntry(name, passwd string, directBind bool) *SearchResul
 			return nil
 		}
 
-		err = bindUser(l, userDN, passwd)
+		expiresIn, err = bindUser(l, userDN, passwd)
 		if err != nil {
 			return nil
 		}
@@ -236,7 +625,7 @@ func (ls *Source) SearchEntry(name, passwd string, directBind bool) *SearchResul
 
 	if !ls.AttributesInBind {
 		// binds user (checking password) before looking-up attributes in user context
-		err = bindUser(l, userDN, passwd)
+		expiresIn, err = bindUser(l, userDN, passwd)
 		if err != nil {
 			return nil
 		}
@@ -253,13 +642,22 @@ func (ls *Source) SearchEntry(name, passwd string, directBind bool) *SearchResul
 	if isAttributeSSHPublicKeySet {
 		attribs = append(attribs, ls.AttributeSSHPublicKey)
 	}
+	if len(ls.AttributeLanguage) > 0 {
+		attribs = append(attribs, ls.AttributeLanguage)
+	}
+	if len(ls.AttributeExternalID) > 0 {
+		attribs = append(attribs, ls.AttributeExternalID)
+	}
+	if len(ls.AttributeChanged) > 0 {
+		attribs = append(attribs, ls.AttributeChanged)
+	}
 
 	log.Trace("Fetching attributes '%v', '%v', '%v', '%v', '%v' with filter %s and base %s", ls.AttributeUsername, ls.AttributeName, ls.AttributeSurname, ls.AttributeMail, ls.AttributeSSHPublicKey, userFilter, userDN)
 	search := ldap.NewSearchRequest(
 		userDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false, userFilter,
 		attribs, nil)
 
-	sr, err := l.Search(search)
+	sr, err := ls.searchWithRetries(l, search)
 	if err != nil {
 		log.Error("LDAP Search failed unexpectedly! (%v)", err)
 		return nil
@@ -282,24 +680,134 @@ func (ls *Source) SearchEntry(name, passwd string, directBind bool) *SearchResul
 	if isAttributeSSHPublicKeySet {
 		sshPublicKey = sr.Entries[0].GetAttributeValues(ls.AttributeSSHPublicKey)
 	}
-	isAdmin := checkAdmin(l, ls, userDN)
+	language := ls.language(sr.Entries[0])
+	externalID := ls.externalID(sr.Entries[0])
+	isAdmin, adminGroups := checkAdmin(l, ls, userDN)
+	isMember := checkMemberGroup(l, ls, userDN)
 
 	if !directBind && ls.AttributesInBind {
 		// binds user (checking password) after looking-up attributes in BindDN context
-		err = bindUser(l, userDN, passwd)
+		expiresIn, err = bindUser(l, userDN, passwd)
 		if err != nil {
 			return nil
 		}
 	}
 
+	reusable = true
 	return &SearchResult{
 		Username:     username,
 		Name:         firstname,
 		Surname:      surname,
 		Mail:         mail,
 		SSHPublicKey: sshPublicKey,
+		Language:     language,
+		ExternalID:   externalID,
 		IsAdmin:      isAdmin,
+		AdminGroups:  adminGroups,
+		IsMember:     isMember,
+
+		PasswordExpiresIn: expiresIn,
+	}
+}
+
+// externalID returns the value of the external ID attribute of the entry. Binary values are
+// formatted as hexadecimal string, except for the objectGUID of Active Directory which is
+// formatted as GUID, e.g. "8fd7b3b4-3ee8-4ca1-8d29-5a5d0a3dc6c1".
+func (ls *Source) externalID(entry *ldap.Entry) string {
+	if len(ls.AttributeExternalID) == 0 {
+		return ""
+	}
+	raw := entry.GetRawAttributeValue(ls.AttributeExternalID)
+	if len(raw) == 0 {
+		return ""
+	}
+	if strings.EqualFold(ls.AttributeExternalID, "objectGUID") && len(raw) == 16 {
+		return formatGUID(raw)
+	}
+	if isPrintable(raw) {
+		return string(raw)
+	}
+	return hex.EncodeToString(raw)
+}
+
+// formatGUID formats a binary GUID, whose first three fields are stored little-endian.
+func formatGUID(b []byte) string {
+	return fmt.Sprintf("%08x-%04x-%04x-%x-%x",
+		binary.LittleEndian.Uint32(b[0:4]),
+		binary.LittleEndian.Uint16(b[4:6]),
+		binary.LittleEndian.Uint16(b[6:8]),
+		b[8:10], b[10:16])
+}
+
+func isPrintable(b []byte) bool {
+	if !utf8.Valid(b) {
+		return false
+	}
+	for _, r := range string(b) {
+		if !unicode.IsPrint(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// language returns the known locale matching the language attribute of the entry,
+// e.g. "de-DE" for "de_DE" or "de", or an empty string if there is none.
+func (ls *Source) language(entry *ldap.Entry) string {
+	if len(ls.AttributeLanguage) == 0 {
+		return ""
+	}
+	value := strings.Replace(strings.TrimSpace(entry.GetAttributeValue(ls.AttributeLanguage)), "_", "-", -1)
+	if len(value) == 0 {
+		return ""
+	}
+	for _, lang := range setting.Langs {
+		if strings.EqualFold(lang, value) {
+			return lang
+		}
+	}
+	for _, lang := range setting.Langs {
+		if strings.HasPrefix(strings.ToLower(lang), strings.ToLower(value)+"-") {
+			return lang
+		}
+	}
+	log.Trace("LDAP language attribute %q of %s is not a known locale", value, entry.DN)
+	return ""
+}
+
+// search runs the search request, paged if configured. When results should be sorted a
+// server side sorting control is sent along, and the search is retried without it if
+// the server refuses the control.
+func (ls *Source) search(l *ldap.Conn, search *ldap.SearchRequest) (*ldap.SearchResult, error) {
+	if ls.SortResults && len(ls.AttributeUsername) > 0 {
+		controls := search.Controls
+		search.Controls = append(controls, &controlServerSideSorting{AttributeType: ls.AttributeUsername})
+		sr, err := ls.searchControls(l, search)
+		if err == nil || !isSortingRefused(err) {
+			return sr, err
+		}
+		log.Warn("LDAP server refused to sort results by %s, searching unsorted: %v", ls.AttributeUsername, err)
+		search.Controls = controls
+	}
+	return ls.searchControls(l, search)
+}
+
+func (ls *Source) searchControls(l *ldap.Conn, search *ldap.SearchRequest) (*ldap.SearchResult, error) {
+	if ls.UsePagedSearch() {
+		controls := search.Controls
+		return ls.withRetries(func() (*ldap.SearchResult, error) {
+			// start over with a fresh paging control instead of resuming after a failed page
+			search.Controls = controls
+			return l.SearchWithPaging(search, ls.SearchPageSize)
+		})
 	}
+	return ls.searchWithRetries(l, search)
+}
+
+func isSortingRefused(err error) bool {
+	return ldap.IsErrorWithCode(err, ldap.LDAPResultUnavailableCriticalExtension) ||
+		ldap.IsErrorWithCode(err, ldap.LDAPResultUnwillingToPerform) ||
+		ldap.IsErrorWithCode(err, ldap.LDAPResultInappropriateMatching)
 }
 
 // UsePagedSearch returns if need to use paged search
@@ -307,15 +815,84 @@ func (ls *Source) UsePagedSearch() bool {
 	return ls.SearchPageSize > 0
 }
 
+// defaultFullSyncInterval is the number of incremental synchronizations between two full ones if FullSyncInterval is not set
+const defaultFullSyncInterval = 7
+
+// IsIncrementalSync returns if synchronizations should only fetch the entries
+// changed since the last synchronization. Only a full synchronization finds the
+// users removed from the directory, so one is done after FullSyncInterval
+// incremental synchronizations.
+func (ls *Source) IsIncrementalSync() bool {
+	return len(ls.AttributeChanged) > 0 && len(ls.LastSyncChanged) > 0 &&
+		ls.IncrementalSyncs < ls.fullSyncInterval()
+}
+
+// fullSyncInterval returns the number of incremental synchronizations between two full ones.
+func (ls *Source) fullSyncInterval() int {
+	if ls.FullSyncInterval > 0 {
+		return ls.FullSyncInterval
+	}
+	return defaultFullSyncInterval
+}
+
 // SearchEntries : search an LDAP source for all users matching userFilter
 func (ls *Source) SearchEntries() ([]*SearchResult, error) {
-	l, err := dial(ls)
+	return ls.searchEntries(fmt.Sprintf(ls.Filter, "*"))
+}
+
+// SearchChangedEntries searches an LDAP source for all users matching userFilter
+// which changed since the last synchronization
+func (ls *Source) SearchChangedEntries() ([]*SearchResult, error) {
+	if !ls.IsIncrementalSync() {
+		return nil, fmt.Errorf("incremental synchronization is not configured for %s", ls.Name)
+	}
+	return ls.searchEntries(fmt.Sprintf("(&%s(%s>=%s))", fmt.Sprintf(ls.Filter, "*"), ls.AttributeChanged, ldap.EscapeFilter(ls.LastSyncChanged)))
+}
+
+// LatestChange returns the highest last modification value of the given entries,
+// or mark if none of them is more recent.
+func LatestChange(entries []*SearchResult, mark string) string {
+	for _, entry := range entries {
+		if isLaterChange(entry.Changed, mark) {
+			mark = entry.Changed
+		}
+	}
+	return mark
+}
+
+// isLaterChange compares two last modification values. Numeric values like
+// uSNChanged are compared by value, others like GeneralizedTime lexically.
+func isLaterChange(a, b string) bool {
+	if len(a) == 0 {
+		return false
+	} else if len(b) == 0 {
+		return true
+	}
+	ai, aErr := strconv.ParseUint(a, 10, 64)
+	bi, bErr := strconv.ParseUint(b, 10, 64)
+	if aErr == nil && bErr == nil {
+		return ai > bi
+	}
+	return a > b
+}
+
+func (ls *Source) searchEntries(userFilter string) ([]*SearchResult, error) {
+	if results, ok := ls.cachedSearchResults(userFilter); ok {
+		log.Trace("Using cached LDAP search results for filter %s", userFilter)
+		return results, nil
+	}
+
+	pooled := ls.usePool()
+	l, err := ls.getConn(pooled)
 	if err != nil {
 		log.Error("LDAP Connect error, %s:%v", ls.Host, err)
 		ls.Enabled = false
 		return nil, err
 	}
-	defer l.Close()
+	reusable := false
+	defer func() {
+		ls.putConn(l, pooled && reusable)
+	}()
 
 	if ls.BindDN != "" && ls.BindPassword != "" {
 		err := l.Bind(ls.BindDN, ls.BindPassword)
@@ -328,45 +905,67 @@ func (ls *Source) SearchEntries() ([]*SearchResult, error) {
 		log.Trace("Proceeding with anonymous LDAP search.")
 	}
 
-	userFilter := fmt.Sprintf(ls.Filter, "*")
-
 	var isAttributeSSHPublicKeySet = len(strings.TrimSpace(ls.AttributeSSHPublicKey)) > 0
 
 	attribs := []string{ls.AttributeUsername, ls.AttributeName, ls.AttributeSurname, ls.AttributeMail}
 	if isAttributeSSHPublicKeySet {
 		attribs = append(attribs, ls.AttributeSSHPublicKey)
 	}
+	if len(ls.AttributeLanguage) > 0 {
+		attribs = append(attribs, ls.AttributeLanguage)
+	}
+	if len(ls.AttributeExternalID) > 0 {
+		attribs = append(attribs, ls.AttributeExternalID)
+	}
+	if len(ls.AttributeChanged) > 0 {
+		attribs = append(attribs, ls.AttributeChanged)
+	}
 
-	log.Trace("Fetching attributes '%v', '%v', '%v', '%v', '%v' with filter %s and base %s", ls.AttributeUsername, ls.AttributeName, ls.AttributeSurname, ls.AttributeMail, ls.AttributeSSHPublicKey, userFilter, ls.UserBase)
-	search := ldap.NewSearchRequest(
-		ls.UserBase, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false, userFilter,
-		attribs, nil)
+	// All bases are searched, a user missing from the results is deactivated by the synchronization.
+	// Bases may overlap, every entry is only returned once.
+	var entries []*ldap.Entry
+	seen := make(map[string]bool)
+	for _, userBase := range ls.userBases() {
+		log.Trace("Fetching attributes '%v', '%v', '%v', '%v', '%v' with filter %s and base %s", ls.AttributeUsername, ls.AttributeName, ls.AttributeSurname, ls.AttributeMail, ls.AttributeSSHPublicKey, userFilter, userBase)
+		search := ldap.NewSearchRequest(
+			userBase, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false, userFilter,
+			attribs, nil)
 
-	var sr *ldap.SearchResult
-	if ls.UsePagedSearch() {
-		sr, err = l.SearchWithPaging(search, ls.SearchPageSize)
-	} else {
-		sr, err = l.Search(search)
-	}
-	if err != nil {
-		log.Error("LDAP Search failed unexpectedly! (%v)", err)
-		return nil, err
+		sr, err := ls.search(l, search)
+		if err != nil {
+			log.Error("LDAP Search failed unexpectedly! (%v)", err)
+			return nil, err
+		}
+		for _, entry := range sr.Entries {
+			if dn := normalizeDN(entry.DN); !seen[dn] {
+				seen[dn] = true
+				entries = append(entries, entry)
+			}
+		}
 	}
 
-	result := make([]*SearchResult, len(sr.Entries))
+	result := make([]*SearchResult, len(entries))
 
-	for i, v := range sr.Entries {
+	for i, v := range entries {
 		result[i] = &SearchResult{
-			Username: v.GetAttributeValue(ls.AttributeUsername),
-			Name:     v.GetAttributeValue(ls.AttributeName),
-			Surname:  v.GetAttributeValue(ls.AttributeSurname),
-			Mail:     v.GetAttributeValue(ls.AttributeMail),
-			IsAdmin:  checkAdmin(l, ls, v.DN),
+			Username:   v.GetAttributeValue(ls.AttributeUsername),
+			Name:       v.GetAttributeValue(ls.AttributeName),
+			Surname:    v.GetAttributeValue(ls.AttributeSurname),
+			Mail:       v.GetAttributeValue(ls.AttributeMail),
+			Language:   ls.language(v),
+			ExternalID: ls.externalID(v),
+			IsMember:   checkMemberGroup(l, ls, v.DN),
 		}
+		result[i].IsAdmin, result[i].AdminGroups = checkAdmin(l, ls, v.DN)
 		if isAttributeSSHPublicKeySet {
 			result[i].SSHPublicKey = v.GetAttributeValues(ls.AttributeSSHPublicKey)
 		}
+		if len(ls.AttributeChanged) > 0 {
+			result[i].Changed = v.GetAttributeValue(ls.AttributeChanged)
+		}
 	}
 
+	reusable = true
+	ls.cacheSearchResults(userFilter, result)
 	return result, nil
 }
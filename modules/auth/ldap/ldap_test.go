@@ -0,0 +1,360 @@
+// Copyright 2020 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package ldap
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	ldap "gopkg.in/ldap.v3"
+)
+
+func TestDial_Failover(t *testing.T) {
+	s := newMockServer(t)
+	defer s.listener.Close()
+
+	// nothing listens on the port of the mock server at 127.0.0.2
+	ls := s.source("cn=failover")
+	ls.Timeout = 1
+	ls.FailoverHosts = []string{ls.Host}
+	ls.Host = "127.0.0.2"
+
+	l, err := dial(ls)
+	assert.NoError(t, err)
+	assert.NoError(t, l.Bind(ls.BindDN, ls.BindPassword))
+	l.Close()
+	s.assertDials(t, 1)
+
+	ls.FailoverHosts = []string{"127.0.0.2"}
+	_, err = dial(ls)
+	assert.Error(t, err)
+}
+
+func TestCheckMemberGroup_Nested(t *testing.T) {
+	s := newMockServer(t)
+	defer s.listener.Close()
+
+	// the user is in the developers, who are in the staff, which is in turn part of the developers
+	const (
+		userDN       = "uid=alice,ou=users,dc=example,dc=org"
+		developersDN = "cn=developers,ou=groups,dc=example,dc=org"
+		staffDN      = "cn=staff,ou=groups,dc=example,dc=org"
+	)
+	s.entries = map[string]map[string][]string{
+		userDN:       {"memberOf": {developersDN}},
+		developersDN: {"memberOf": {staffDN}},
+		staffDN:      {"memberOf": {developersDN}},
+	}
+	ls := s.source("cn=nested")
+	ls.MemberGroupFilter = "(memberOf=" + staffDN + ")"
+
+	l, err := dial(ls)
+	assert.NoError(t, err)
+	defer l.Close()
+
+	// the user is not a direct member of the staff
+	assert.False(t, checkMemberGroup(l, ls, userDN))
+
+	ls.NestedGroupSearch = true
+	assert.True(t, checkMemberGroup(l, ls, userDN))
+
+	ls.NestedGroupMaxDepth = 1
+	assert.False(t, checkMemberGroup(l, ls, userDN))
+
+	// groups which are not reached end the search despite the cycle
+	ls.NestedGroupMaxDepth = 0
+	ls.MemberGroupFilter = "(memberOf=cn=admins,ou=groups,dc=example,dc=org)"
+	assert.False(t, checkMemberGroup(l, ls, userDN))
+}
+
+func TestCheckMemberGroup_NestedFilter(t *testing.T) {
+	s := newMockServer(t)
+	defer s.listener.Close()
+
+	const (
+		directDN      = "uid=alice,ou=users,dc=example,dc=org"
+		nestedDN      = "uid=bob,ou=users,dc=example,dc=org"
+		employeeDN    = "uid=carol,ou=users,dc=example,dc=org"
+		disabledDN    = "uid=dave,ou=users,dc=example,dc=org"
+		contractorsDN = "cn=contractors,ou=groups,dc=example,dc=org"
+		externalDN    = "cn=external,ou=groups,dc=example,dc=org"
+		developersDN  = "cn=developers,ou=groups,dc=example,dc=org"
+		staffDN       = "cn=staff,ou=groups,dc=example,dc=org"
+	)
+	s.entries = map[string]map[string][]string{
+		directDN:     {"objectClass": {"person"}, "memberOf": {contractorsDN}},
+		nestedDN:     {"objectClass": {"person"}, "memberOf": {externalDN}},
+		employeeDN:   {"objectClass": {"person"}, "memberOf": {developersDN}, "accountStatus": {"active"}},
+		disabledDN:   {"objectClass": {"person"}, "memberOf": {developersDN}, "accountStatus": {"disabled"}},
+		externalDN:   {"memberOf": {contractorsDN}},
+		developersDN: {"memberOf": {staffDN}},
+	}
+	ls := s.source("cn=nested-filter")
+	ls.NestedGroupSearch = true
+
+	l, err := dial(ls)
+	assert.NoError(t, err)
+	defer l.Close()
+
+	// members of the excluded group are denied, directly or through another group
+	ls.MemberGroupFilter = "(&(objectClass=person)(!(memberOf=CN=Contractors, OU=Groups, DC=example, DC=org)))"
+	assert.False(t, checkMemberGroup(l, ls, directDN))
+	assert.False(t, checkMemberGroup(l, ls, nestedDN))
+	assert.True(t, checkMemberGroup(l, ls, employeeDN))
+
+	// the other terms of the filter still apply to members of nested groups
+	ls.MemberGroupFilter = "(&(memberOf=" + staffDN + ")(accountStatus=active))"
+	assert.True(t, checkMemberGroup(l, ls, employeeDN))
+	assert.False(t, checkMemberGroup(l, ls, disabledDN))
+	assert.False(t, checkMemberGroup(l, ls, directDN))
+
+	ls.MemberGroupFilter = "(&(memberOf=" + staffDN + ")"
+	assert.False(t, checkMemberGroup(l, ls, employeeDN))
+}
+
+func TestSearchEntries_UserBases(t *testing.T) {
+	s := newMockServer(t)
+	defer s.listener.Close()
+
+	s.entries = map[string]map[string][]string{
+		"uid=alice,ou=people,dc=example,dc=org":   {"objectClass": {"person"}, "uid": {"alice"}},
+		"uid=bob,ou=partners,dc=example,dc=org":   {"objectClass": {"person"}, "uid": {"bob"}},
+		"uid=carol,ou=partners,dc=example,dc=org": {"objectClass": {"person"}, "uid": {"carol"}, "mail": {"carol@example.org"}},
+		"uid=carol,ou=people,dc=example,dc=org":   {"objectClass": {"person"}, "uid": {"carol"}, "mail": {"carol@example.org"}},
+	}
+	ls := s.source("cn=bases")
+	ls.Filter = "(&(objectClass=person)(|(uid=%[1]s)(mail=%[1]s)))"
+	ls.AttributeUsername = "uid"
+	ls.UserBase = "ou=people,dc=example,dc=org"
+	// the last base overlaps the others
+	ls.UserBases = []string{"ou=partners,dc=example,dc=org", "dc=example,dc=org"}
+
+	assert.NoError(t, ls.Ping())
+
+	// users only found in an additional base are synchronized
+	results, err := ls.SearchEntries()
+	assert.NoError(t, err)
+	var usernames []string
+	for _, result := range results {
+		usernames = append(usernames, result.Username)
+	}
+	assert.ElementsMatch(t, []string{"alice", "carol", "bob", "carol"}, usernames)
+
+	username, err := ls.ResolveUsername("bob")
+	assert.NoError(t, err)
+	assert.Equal(t, "bob", username)
+
+	// the e-mail address matches a single entry in the first base
+	username, err = ls.ResolveUsername("carol@example.org")
+	assert.NoError(t, err)
+	assert.Equal(t, "carol", username)
+
+	_, err = ls.ResolveUsername("dave")
+	assert.True(t, IsErrUserNotFound(err))
+}
+
+func TestSearchEntries_Changed(t *testing.T) {
+	s := newMockServer(t)
+	defer s.listener.Close()
+
+	s.entries = map[string]map[string][]string{
+		"uid=alice,dc=example,dc=org": {"uid": {"alice"}, "modifyTimestamp": {"20200102000000Z"}},
+		"uid=bob,dc=example,dc=org":   {"uid": {"bob"}, "modifyTimestamp": {"20200103000000Z"}},
+	}
+	ls := s.source("cn=changed")
+	ls.Filter = "(uid=%s)"
+	ls.AttributeUsername = "uid"
+	ls.UserBase = "dc=example,dc=org"
+	ls.AttributeChanged = "modifyTimestamp"
+
+	results, err := ls.SearchEntries()
+	assert.NoError(t, err)
+	assert.Len(t, results, 2)
+	assert.Equal(t, "20200102000000Z", results[0].Changed)
+	assert.Equal(t, "20200103000000Z", results[1].Changed)
+	assert.Equal(t, "20200103000000Z", LatestChange(results, ""))
+}
+
+func TestSearchEntries_ExternalID(t *testing.T) {
+	s := newMockServer(t)
+	defer s.listener.Close()
+
+	// objectGUID of Active Directory stores its first three fields little-endian
+	guid := string([]byte{
+		0xb4, 0xb3, 0xd7, 0x8f, 0xe8, 0x3e, 0xa1, 0x4c,
+		0x8d, 0x29, 0x5a, 0x5d, 0x0a, 0x3d, 0xc6, 0xc1,
+	})
+	s.entries = map[string]map[string][]string{
+		"uid=alice,dc=example,dc=org": {"uid": {"alice"}, "objectGUID": {guid}},
+		"uid=bob,dc=example,dc=org":   {"uid": {"bob"}, "objectGUID": {"\x00\x01\xfe\xff"}},
+		"uid=carol,dc=example,dc=org": {"uid": {"carol"}},
+	}
+	ls := s.source("cn=external-id")
+	ls.Filter = "(uid=%s)"
+	ls.AttributeUsername = "uid"
+	ls.UserBase = "dc=example,dc=org"
+	ls.AttributeExternalID = "objectGUID"
+
+	results, err := ls.SearchEntries()
+	assert.NoError(t, err)
+	assert.Len(t, results, 3)
+	assert.Equal(t, "8fd7b3b4-3ee8-4ca1-8d29-5a5d0a3dc6c1", results[0].ExternalID)
+	// binary values which are no GUID are hex encoded
+	assert.Equal(t, "0001feff", results[1].ExternalID)
+	assert.Empty(t, results[2].ExternalID)
+
+	// printable values are kept as they are
+	s.entries["uid=alice,dc=example,dc=org"]["entryUUID"] = []string{"597ae2f6-16a6-1027-98f4-d28b5365dc14"}
+	ls.AttributeExternalID = "entryUUID"
+	results, err = ls.SearchEntries()
+	assert.NoError(t, err)
+	assert.Len(t, results, 3)
+	assert.Equal(t, "597ae2f6-16a6-1027-98f4-d28b5365dc14", results[0].ExternalID)
+	assert.Empty(t, results[1].ExternalID)
+}
+
+func TestSearchEntries_Cache(t *testing.T) {
+	s := newMockServer(t)
+	defer s.listener.Close()
+
+	// without a pool every search dials the server
+	ls := s.source("cn=cache")
+	ls.PoolSize = 0
+	ls.Filter = "(uid=%s)"
+	ls.SearchCacheTTL = time.Minute
+
+	_, err := ls.SearchEntries()
+	assert.NoError(t, err)
+	s.assertDials(t, 1)
+
+	_, err = ls.SearchEntries()
+	assert.NoError(t, err)
+	s.assertDials(t, 1)
+
+	// a changed configuration does not reuse the results
+	ls.AttributeMail = "mail"
+	_, err = ls.SearchEntries()
+	assert.NoError(t, err)
+	s.assertDials(t, 2)
+
+	// expired results are searched again
+	ls.SearchCacheTTL = 10 * time.Millisecond
+	_, err = ls.SearchEntries()
+	assert.NoError(t, err)
+	s.assertDials(t, 3)
+	time.Sleep(20 * time.Millisecond)
+	_, err = ls.SearchEntries()
+	assert.NoError(t, err)
+	s.assertDials(t, 4)
+
+	ls.SearchCacheTTL = 0
+	_, err = ls.SearchEntries()
+	assert.NoError(t, err)
+	_, err = ls.SearchEntries()
+	assert.NoError(t, err)
+	s.assertDials(t, 6)
+}
+
+func TestCheckAdmin_Groups(t *testing.T) {
+	s := newMockServer(t)
+	defer s.listener.Close()
+
+	const (
+		userDN    = "uid=alice,ou=users,dc=example,dc=org"
+		adminsDN  = "cn=admins,ou=groups,dc=example,dc=org"
+		internsDN = "cn=interns,ou=groups,dc=example,dc=org"
+		staffDN   = "cn=staff,ou=groups,dc=example,dc=org"
+	)
+	s.entries = map[string]map[string][]string{
+		userDN:    {"objectClass": {"person"}, "memberOf": {adminsDN, internsDN, staffDN}},
+		adminsDN:  {"cn": {"Administrators"}},
+		internsDN: {"cn": {"Interns"}},
+		staffDN:   {"cn": {"Staff"}},
+	}
+	ls := s.source("cn=admin-groups")
+	ls.AttributeName = "givenName"
+	ls.GroupAttributeName = "cn"
+
+	l, err := dial(ls)
+	assert.NoError(t, err)
+	defer l.Close()
+
+	// only groups asserted without negation are reported
+	ls.AdminFilter = "(|(memberOf=CN=Admins,OU=Groups,DC=example,DC=org)(!(memberOf=" + internsDN + ")))"
+	isAdmin, groups := checkAdmin(l, ls, userDN)
+	assert.True(t, isAdmin)
+	assert.Equal(t, []string{"Administrators"}, groups)
+
+	// other attributes merely containing a group DN do not count
+	ls.AdminFilter = "(&(objectClass=person)(description=" + staffDN + "))"
+	s.entries[userDN]["description"] = []string{staffDN}
+	isAdmin, groups = checkAdmin(l, ls, userDN)
+	assert.True(t, isAdmin)
+	assert.Empty(t, groups)
+
+	// without GroupAttributeName no groups are looked up
+	ls.GroupAttributeName = ""
+	ls.AdminFilter = "(memberOf=" + adminsDN + ")"
+	isAdmin, groups = checkAdmin(l, ls, userDN)
+	assert.True(t, isAdmin)
+	assert.Empty(t, groups)
+}
+
+func TestPositiveMemberOf(t *testing.T) {
+	kases := []struct {
+		filter string
+		groups []string
+	}{
+		{"(memberOf=cn=admins,ou=groups,dc=example,dc=org)", []string{"cn=admins,ou=groups,dc=example,dc=org"}},
+		{"(MemberOf=CN=Admins, OU=Groups, DC=example, DC=org)", []string{"cn=admins,ou=groups,dc=example,dc=org"}},
+		{"(!(memberOf=cn=interns,ou=groups,dc=example,dc=org))", []string{}},
+		{"(!(!(memberOf=cn=admins,ou=groups,dc=example,dc=org)))", []string{"cn=admins,ou=groups,dc=example,dc=org"}},
+		{"(&(|(memberOf=cn=admins,dc=org)(memberOf=cn=ops,dc=org))(!(memberOf=cn=interns,dc=org)))", []string{"cn=admins,dc=org", "cn=ops,dc=org"}},
+		{"(description=cn=admins,dc=org)", []string{}},
+		{"(uid=admin)", []string{}},
+	}
+	for _, kase := range kases {
+		groups, err := positiveMemberOf(kase.filter)
+		assert.NoError(t, err, kase.filter)
+		expected := make(map[string]bool, len(kase.groups))
+		for _, group := range kase.groups {
+			expected[group] = true
+		}
+		assert.Equal(t, expected, groups, kase.filter)
+	}
+
+	_, err := positiveMemberOf("(memberOf=cn=admins")
+	assert.Error(t, err)
+}
+
+func TestPasswordExpiresIn(t *testing.T) {
+	day := 24 * time.Hour
+	kases := []struct {
+		controls []ldap.Control
+		expected *time.Duration
+	}{
+		{nil, nil},
+		{[]ldap.Control{ldap.NewControlPaging(10)}, nil},
+		{[]ldap.Control{&ldap.ControlBeheraPasswordPolicy{Expire: 86400, Grace: -1}}, &day},
+		// the policy control reports no expiry, e.g. only grace logins
+		{[]ldap.Control{&ldap.ControlBeheraPasswordPolicy{Expire: -1, Grace: 2}}, nil},
+		{[]ldap.Control{&ldap.ControlVChuPasswordWarning{Expire: 86400}}, &day},
+	}
+	for _, kase := range kases {
+		assert.Equal(t, kase.expected, passwordExpiresIn(kase.controls))
+	}
+
+	// servers which do not send the control leave it unknown
+	s := newMockServer(t)
+	defer s.listener.Close()
+	ls := s.source("cn=expiry")
+	l, err := dial(ls)
+	assert.NoError(t, err)
+	defer l.Close()
+	expiresIn, err := bindUser(l, "uid=alice,dc=example,dc=org", "password")
+	assert.NoError(t, err)
+	assert.Nil(t, expiresIn)
+}
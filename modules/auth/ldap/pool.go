@@ -0,0 +1,126 @@
+// Copyright 2020 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package ldap
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"code.gitea.io/gitea/modules/log"
+
+	ldap "gopkg.in/ldap.v3"
+)
+
+// poolIdleTimeout is the time after which idle pooled connections are not reused anymore
+const poolIdleTimeout = time.Minute
+
+type pooledConn struct {
+	conn     *ldap.Conn
+	lastUsed time.Time
+}
+
+// connPool keeps idle connections to an LDAP server for reuse
+type connPool struct {
+	lock sync.Mutex
+	idle []*pooledConn
+}
+
+var (
+	poolsLock sync.Mutex
+	pools     = make(map[string]*connPool)
+)
+
+// usePool returns if connections bound with the BindDN should be reused.
+// A pool size of 1 or less dials a new connection for every search.
+func (ls *Source) usePool() bool {
+	return ls.PoolSize > 1 && ls.BindDN != "" && ls.BindPassword != ""
+}
+
+func (ls *Source) pool() *connPool {
+	key := fmt.Sprintf("%s:%d:%d:%t:%s", ls.Host, ls.Port, ls.SecurityProtocol, ls.SkipVerify, ls.BindDN)
+
+	poolsLock.Lock()
+	defer poolsLock.Unlock()
+	p, ok := pools[key]
+	if !ok {
+		p = &connPool{}
+		pools[key] = p
+	}
+	return p
+}
+
+// evictIdle closes the connections which have been idle for longer than poolIdleTimeout.
+// Connections are given back in order, so these are at the start of the idle list.
+func (p *connPool) evictIdle() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	expired := 0
+	for expired < len(p.idle) && time.Since(p.idle[expired].lastUsed) >= poolIdleTimeout {
+		p.idle[expired].conn.Close()
+		expired++
+	}
+	p.idle = p.idle[expired:]
+}
+
+func (p *connPool) pop() *pooledConn {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if len(p.idle) == 0 {
+		return nil
+	}
+	c := p.idle[len(p.idle)-1]
+	p.idle = p.idle[:len(p.idle)-1]
+	return c
+}
+
+// getConn returns a healthy idle connection of the pool if pooled is set, or dials a new one.
+// Pooled connections must be bound with the BindDN again before use.
+func (ls *Source) getConn(pooled bool) (*ldap.Conn, error) {
+	if pooled {
+		p := ls.pool()
+		p.evictIdle()
+		for c := p.pop(); c != nil; c = p.pop() {
+			if time.Since(c.lastUsed) < poolIdleTimeout && isAlive(c.conn) {
+				return c.conn, nil
+			}
+			c.conn.Close()
+		}
+	}
+	return dial(ls)
+}
+
+// putConn gives the connection back to the pool if it is reusable, otherwise closes it.
+// Connections which encountered an error must never be given back.
+func (ls *Source) putConn(l *ldap.Conn, reusable bool) {
+	if !reusable || l.IsClosing() {
+		l.Close()
+		return
+	}
+
+	p := ls.pool()
+	p.evictIdle()
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if len(p.idle) >= ls.PoolSize {
+		l.Close()
+		return
+	}
+	p.idle = append(p.idle, &pooledConn{conn: l, lastUsed: time.Now()})
+}
+
+// isAlive checks the connection by reading the root DSE
+func isAlive(l *ldap.Conn) bool {
+	if l.IsClosing() {
+		return false
+	}
+	search := ldap.NewSearchRequest("", ldap.ScopeBaseObject, ldap.NeverDerefAliases, 1, 5, false,
+		"(objectClass=*)", []string{"1.1"}, nil)
+	if _, err := l.Search(search); err != nil {
+		log.Debug("Discarding pooled LDAP connection: %v", err)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,262 @@
+// Copyright 2020 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package ldap
+
+import (
+	"net"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	ber "gopkg.in/asn1-ber.v1"
+	ldap "gopkg.in/ldap.v3"
+)
+
+// mockServer is an LDAP server accepting every bind. Searches return those of entries matching the
+// filter which are the base DN, or for subtree searches also below it, with the requested attributes.
+type mockServer struct {
+	listener net.Listener
+	dials    int32
+	entries  map[string]map[string][]string // attributes by DN
+}
+
+func newMockServer(t *testing.T) *mockServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	s := &mockServer{listener: listener}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			atomic.AddInt32(&s.dials, 1)
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *mockServer) serve(conn net.Conn) {
+	defer conn.Close()
+	for {
+		packet, err := ber.ReadPacket(conn)
+		if err != nil || len(packet.Children) < 2 {
+			return
+		}
+		messageID := packet.Children[0].Value
+		request := packet.Children[1]
+		var responseTag ber.Tag
+		switch request.Tag {
+		case ldap.ApplicationBindRequest:
+			responseTag = ldap.ApplicationBindResponse
+		case ldap.ApplicationSearchRequest:
+			responseTag = ldap.ApplicationSearchResultDone
+			baseDN, _ := request.Children[0].Value.(string)
+			subtree := request.Children[1].Value == int64(ldap.ScopeWholeSubtree)
+			for _, dn := range s.sortedDNs() {
+				inScope := strings.EqualFold(dn, baseDN) ||
+					(subtree && (baseDN == "" || strings.HasSuffix(strings.ToLower(dn), ","+strings.ToLower(baseDN))))
+				if !inScope || !matchesFilter(request.Children[6], s.entries[dn]) {
+					continue
+				}
+				if _, err := conn.Write(s.searchResultEntry(messageID, dn, requestedAttributes(request.Children[7], s.entries[dn])).Bytes()); err != nil {
+					return
+				}
+			}
+		default:
+			return
+		}
+
+		response := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "LDAP Response")
+		response.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, messageID, "MessageID"))
+		result := ber.Encode(ber.ClassApplication, ber.TypeConstructed, responseTag, nil, "Result")
+		result.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, int64(ldap.LDAPResultSuccess), "resultCode"))
+		result.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "matchedDN"))
+		result.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "errorMessage"))
+		response.AppendChild(result)
+		if _, err := conn.Write(response.Bytes()); err != nil {
+			return
+		}
+	}
+}
+
+func (s *mockServer) sortedDNs() []string {
+	dns := make([]string, 0, len(s.entries))
+	for dn := range s.entries {
+		dns = append(dns, dn)
+	}
+	sort.Strings(dns)
+	return dns
+}
+
+// matchesFilter evaluates the and, or, not, presence and equality terms of a search filter
+// on the attributes of an entry. Every entry has an objectClass.
+func matchesFilter(filter *ber.Packet, attributes map[string][]string) bool {
+	values := func(name string) []string {
+		for attribute, values := range attributes {
+			if strings.EqualFold(attribute, name) {
+				return values
+			}
+		}
+		return nil
+	}
+	switch filter.Tag {
+	case ldap.FilterAnd:
+		for _, child := range filter.Children {
+			if !matchesFilter(child, attributes) {
+				return false
+			}
+		}
+		return true
+	case ldap.FilterOr:
+		for _, child := range filter.Children {
+			if matchesFilter(child, attributes) {
+				return true
+			}
+		}
+		return false
+	case ldap.FilterNot:
+		return !matchesFilter(filter.Children[0], attributes)
+	case ldap.FilterPresent:
+		name := ber.DecodeString(filter.Data.Bytes())
+		return strings.EqualFold(name, "objectClass") || len(values(name)) > 0
+	case ldap.FilterEqualityMatch:
+		asserted := ber.DecodeString(filter.Children[1].Data.Bytes())
+		for _, value := range values(ber.DecodeString(filter.Children[0].Data.Bytes())) {
+			if strings.EqualFold(value, asserted) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// requestedAttributes returns the attributes asked for by a search, all of them if none are listed
+func requestedAttributes(requested *ber.Packet, attributes map[string][]string) map[string][]string {
+	if len(requested.Children) == 0 {
+		return attributes
+	}
+	result := make(map[string][]string)
+	for _, child := range requested.Children {
+		name := ber.DecodeString(child.Data.Bytes())
+		for attribute, values := range attributes {
+			if name == "*" || strings.EqualFold(attribute, name) {
+				result[attribute] = values
+			}
+		}
+	}
+	return result
+}
+
+func (s *mockServer) searchResultEntry(messageID interface{}, dn string, attributes map[string][]string) *ber.Packet {
+	response := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "LDAP Response")
+	response.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, messageID, "MessageID"))
+	entry := ber.Encode(ber.ClassApplication, ber.TypeConstructed, ldap.ApplicationSearchResultEntry, nil, "Entry")
+	entry.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, dn, "objectName"))
+	attributeList := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "attributes")
+	for name, values := range attributes {
+		attribute := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "attribute")
+		attribute.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, name, "type"))
+		valueSet := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "vals")
+		for _, value := range values {
+			valueSet.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, value, "value"))
+		}
+		attribute.AppendChild(valueSet)
+		attributeList.AppendChild(attribute)
+	}
+	entry.AppendChild(attributeList)
+	response.AppendChild(entry)
+	return response
+}
+
+// assertDials waits for the server to have accepted the expected number of connections
+func (s *mockServer) assertDials(t *testing.T, expected int32) {
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt32(&s.dials) == expected
+	}, time.Second, 10*time.Millisecond, "expected %d dials", expected)
+}
+
+func (s *mockServer) source(bindDN string) *Source {
+	addr := s.listener.Addr().(*net.TCPAddr)
+	return &Source{
+		Name:         "mock",
+		Host:         addr.IP.String(),
+		Port:         addr.Port,
+		BindDN:       bindDN,
+		BindPassword: "password",
+		PoolSize:     2,
+	}
+}
+
+func TestConnPool_CheckoutAndReturn(t *testing.T) {
+	s := newMockServer(t)
+	defer s.listener.Close()
+	ls := s.source("cn=checkout")
+	assert.True(t, ls.usePool())
+
+	l, err := ls.getConn(true)
+	assert.NoError(t, err)
+	assert.NoError(t, l.Bind(ls.BindDN, ls.BindPassword))
+	ls.putConn(l, true)
+
+	// the idle connection is checked and reused
+	reused, err := ls.getConn(true)
+	assert.NoError(t, err)
+	assert.True(t, l == reused)
+	s.assertDials(t, 1)
+
+	// a connection which is not reusable is closed instead of given back
+	ls.putConn(reused, false)
+	assert.True(t, reused.IsClosing())
+	l, err = ls.getConn(true)
+	assert.NoError(t, err)
+	assert.False(t, l == reused)
+	s.assertDials(t, 2)
+	ls.putConn(l, false)
+
+	// the direct bind path never uses the pool
+	l, err = ls.getConn(false)
+	assert.NoError(t, err)
+	s.assertDials(t, 3)
+	l.Close()
+}
+
+func TestConnPool_Eviction(t *testing.T) {
+	s := newMockServer(t)
+	defer s.listener.Close()
+	ls := s.source("cn=eviction")
+
+	first, err := ls.getConn(true)
+	assert.NoError(t, err)
+	second, err := ls.getConn(true)
+	assert.NoError(t, err)
+	ls.putConn(first, true)
+	ls.putConn(second, true)
+
+	p := ls.pool()
+	assert.Len(t, p.idle, 2)
+	p.idle[0].lastUsed = time.Now().Add(-2 * poolIdleTimeout)
+
+	// the expired connection is closed even though the fresh one is checked out first
+	l, err := ls.getConn(true)
+	assert.NoError(t, err)
+	assert.True(t, l == second)
+	assert.True(t, first.IsClosing())
+	assert.Empty(t, p.idle)
+
+	// a stale connection is replaced by a fresh dial
+	ls.putConn(l, true)
+	p.idle[0].lastUsed = time.Now().Add(-2 * poolIdleTimeout)
+	l, err = ls.getConn(true)
+	assert.NoError(t, err)
+	assert.False(t, l == second)
+	assert.True(t, second.IsClosing())
+	s.assertDials(t, 3)
+	l.Close()
+}
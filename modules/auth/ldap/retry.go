@@ -0,0 +1,52 @@
+// Copyright 2020 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package ldap
+
+import (
+	"math/rand"
+	"time"
+
+	"code.gitea.io/gitea/modules/log"
+
+	ldap "gopkg.in/ldap.v3"
+)
+
+// retryBaseDelay is the delay before the first retry of a search, it doubles with every further retry
+const retryBaseDelay = 100 * time.Millisecond
+
+// isTransientError returns if a search failed with a result code indicating
+// that the server may be able to answer the same search later on.
+func isTransientError(err error) bool {
+	return ldap.IsErrorWithCode(err, ldap.LDAPResultBusy) ||
+		ldap.IsErrorWithCode(err, ldap.LDAPResultUnavailable) ||
+		ldap.IsErrorWithCode(err, ldap.LDAPResultTimeLimitExceeded)
+}
+
+// retryDelay returns the delay before the given retry, an exponential
+// backoff with up to the same amount of random jitter added.
+func retryDelay(retry int) time.Duration {
+	delay := retryBaseDelay << uint(retry)
+	return delay + time.Duration(rand.Int63n(int64(delay)))
+}
+
+// withRetries runs the search, retrying it up to SearchRetries times as long
+// as it fails with a transient error.
+func (ls *Source) withRetries(search func() (*ldap.SearchResult, error)) (*ldap.SearchResult, error) {
+	sr, err := search()
+	for retry := 0; retry < ls.SearchRetries && isTransientError(err); retry++ {
+		delay := retryDelay(retry)
+		log.Debug("LDAP search on %s failed with a transient error, retrying in %v: %v", ls.Host, delay, err)
+		time.Sleep(delay)
+		sr, err = search()
+	}
+	return sr, err
+}
+
+// searchWithRetries runs a single search request, retrying it on transient errors.
+func (ls *Source) searchWithRetries(l *ldap.Conn, search *ldap.SearchRequest) (*ldap.SearchResult, error) {
+	return ls.withRetries(func() (*ldap.SearchResult, error) {
+		return l.Search(search)
+	})
+}
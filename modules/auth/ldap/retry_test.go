@@ -0,0 +1,90 @@
+// Copyright 2020 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package ldap
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	ldap "gopkg.in/ldap.v3"
+)
+
+func TestIsTransientError(t *testing.T) {
+	kases := []struct {
+		err       error
+		transient bool
+	}{
+		{nil, false},
+		{errors.New("busy"), false},
+		{ldap.NewError(ldap.LDAPResultBusy, errors.New("busy")), true},
+		{ldap.NewError(ldap.LDAPResultUnavailable, errors.New("unavailable")), true},
+		{ldap.NewError(ldap.LDAPResultTimeLimitExceeded, errors.New("time limit exceeded")), true},
+		{ldap.NewError(ldap.LDAPResultInvalidCredentials, errors.New("invalid credentials")), false},
+		{ldap.NewError(ldap.LDAPResultNoSuchObject, errors.New("no such object")), false},
+		{ldap.NewError(ldap.LDAPResultSizeLimitExceeded, errors.New("size limit exceeded")), false},
+	}
+	for _, kase := range kases {
+		assert.Equal(t, kase.transient, isTransientError(kase.err), "%v", kase.err)
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	kases := []struct {
+		retry int
+		min   time.Duration
+	}{
+		{0, 100 * time.Millisecond},
+		{1, 200 * time.Millisecond},
+		{2, 400 * time.Millisecond},
+		{5, 3200 * time.Millisecond},
+	}
+	for _, kase := range kases {
+		for i := 0; i < 10; i++ {
+			delay := retryDelay(kase.retry)
+			assert.True(t, delay >= kase.min, "retry %d: %v", kase.retry, delay)
+			assert.True(t, delay < 2*kase.min, "retry %d: %v", kase.retry, delay)
+		}
+	}
+}
+
+func TestWithRetries(t *testing.T) {
+	busy := ldap.NewError(ldap.LDAPResultBusy, errors.New("busy"))
+	denied := ldap.NewError(ldap.LDAPResultInsufficientAccessRights, errors.New("denied"))
+	result := &ldap.SearchResult{}
+
+	kases := []struct {
+		name     string
+		retries  int
+		errs     []error
+		searches int
+		err      error
+	}{
+		{"success", 2, []error{nil}, 1, nil},
+		{"no retries", 0, []error{busy, nil}, 1, busy},
+		{"transient error", 2, []error{busy, busy, nil}, 3, nil},
+		{"retries exhausted", 1, []error{busy, busy, nil}, 2, busy},
+		{"permanent error", 2, []error{denied, nil}, 1, denied},
+		{"permanent error after transient one", 2, []error{busy, denied, nil}, 2, denied},
+	}
+	for _, kase := range kases {
+		ls := &Source{Host: "ldap.example.com", SearchRetries: kase.retries}
+		searches := 0
+		sr, err := ls.withRetries(func() (*ldap.SearchResult, error) {
+			err := kase.errs[searches]
+			searches++
+			if err != nil {
+				return nil, err
+			}
+			return result, nil
+		})
+		assert.Equal(t, kase.searches, searches, kase.name)
+		assert.Equal(t, kase.err, err, kase.name)
+		if kase.err == nil {
+			assert.True(t, sr == result, kase.name)
+		}
+	}
+}
@@ -128,11 +128,16 @@ type RepoSettingForm struct {
 	TrackerURLFormat                 string
 	TrackerIssueStyle                string
 	EnablePulls                      bool
-	PullsIgnoreWhitespace            bool
+	PullsWhitespaceConflicts         string `binding:"OmitEmpty;In(eol,all)"`
 	PullsAllowMerge                  bool
 	PullsAllowRebase                 bool
 	PullsAllowRebaseMerge            bool
 	PullsAllowSquash                 bool
+	PullsAllowFastForwardOnly        bool
+	PullsDisableStaleAutoClose       bool
+	PullsRequireSignoff              bool
+	PullsBlockBinaryFiles            bool
+	PullsWorkInProgressPrefixes      string
 	EnableTimetracker                bool
 	AllowOnlyContributorsToTrackTime bool
 	EnableIssueDependencies          bool
@@ -157,23 +162,24 @@ func (f *RepoSettingForm) Validate(ctx *macaron.Context, errs binding.Errors) bi
 
 // ProtectBranchForm form for changing protected branch settings
 type ProtectBranchForm struct {
-	Protected                bool
-	EnablePush               string
-	WhitelistUsers           string
-	WhitelistTeams           string
-	WhitelistDeployKeys      bool
-	EnableMergeWhitelist     bool
-	MergeWhitelistUsers      string
-	MergeWhitelistTeams      string
-	EnableStatusCheck        bool `xorm:"NOT NULL DEFAULT false"`
-	StatusCheckContexts      []string
-	RequiredApprovals        int64
-	EnableApprovalsWhitelist bool
-	ApprovalsWhitelistUsers  string
-	ApprovalsWhitelistTeams  string
-	BlockOnRejectedReviews   bool
-	DismissStaleApprovals    bool
-	RequireSignedCommits     bool
+	Protected                      bool
+	EnablePush                     string
+	WhitelistUsers                 string
+	WhitelistTeams                 string
+	WhitelistDeployKeys            bool
+	EnableMergeWhitelist           bool
+	MergeWhitelistUsers            string
+	MergeWhitelistTeams            string
+	EnableStatusCheck              bool `xorm:"NOT NULL DEFAULT false"`
+	StatusCheckContexts            []string
+	RequiredApprovals              int64
+	EnableApprovalsWhitelist       bool
+	ApprovalsWhitelistUsers        string
+	ApprovalsWhitelistTeams        string
+	BlockOnRejectedReviews         bool
+	DismissStaleApprovals          bool
+	RequireSignedCommits           bool
+	BlockOnUnresolvedConversations bool
 }
 
 // Validate validates the fields
@@ -200,8 +206,10 @@ type WebhookForm struct {
 	Push         bool
 	PullRequest  bool
 	Repository   bool
+	Status       bool
 	Active       bool
 	BranchFilter string `binding:"GlobPattern"`
+	BatchWindow  int    `binding:"Range(0,3600)"`
 }
 
 // PushOnly if the hook will be triggered when push
@@ -445,11 +453,23 @@ func (f *InitializeLabelsForm) Validate(ctx *macaron.Context, errs binding.Error
 // swagger:model MergePullRequestOption
 type MergePullRequestForm struct {
 	// required: true
-	// enum: merge,rebase,rebase-merge,squash
-	Do                string `binding:"Required;In(merge,rebase,rebase-merge,squash)"`
+	// enum: merge,rebase,rebase-merge,squash,fast-forward-only
+	Do                string `binding:"Required;In(merge,rebase,rebase-merge,squash,fast-forward-only)"`
 	MergeTitleField   string
 	MergeMessageField string
 	ForceMerge        *bool `json:"force_merge,omitempty"`
+	// author name of the squashed commit, defaults to the pull request poster
+	SquashAuthorName string `json:"squash_author_name,omitempty"`
+	// author email of the squashed commit, defaults to the pull request poster
+	SquashAuthorEmail string `json:"squash_author_email,omitempty"`
+	// committer name of the squashed commit, defaults to the merger
+	SquashCommitterName string `json:"squash_committer_name,omitempty"`
+	// committer email of the squashed commit, defaults to the merger
+	SquashCommitterEmail string `json:"squash_committer_email,omitempty"`
+	// resolved contents of the conflicted files by path, to merge a conflicting pull request (merge and squash only)
+	ResolvedFiles map[string]string `json:"resolved_files,omitempty"`
+	// branch of the base repository to merge into instead of the base branch of the pull request, which is left unchanged
+	TargetBranch string `json:"target_branch,omitempty"`
 }
 
 // Validate validates the fields
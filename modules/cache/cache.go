@@ -7,6 +7,7 @@ package cache
 import (
 	"fmt"
 	"strconv"
+	"time"
 
 	"code.gitea.io/gitea/modules/setting"
 
@@ -94,6 +95,35 @@ func GetInt64(key string, getFunc func() (int64, error)) (int64, error) {
 	}
 }
 
+// GetStringWithTTL returns key value from cache with callback when no key exists in cache.
+// The value is kept for the given ttl instead of the default item TTL, a zero ttl bypasses the cache.
+func GetStringWithTTL(key string, ttl time.Duration, getFunc func() (string, error)) (string, error) {
+	if conn == nil || setting.CacheService.TTL == 0 || ttl == 0 {
+		return getFunc()
+	}
+	if !conn.IsExist(key) {
+		var (
+			value string
+			err   error
+		)
+		if value, err = getFunc(); err != nil {
+			return value, err
+		}
+		err = conn.Put(key, value, int64(ttl.Seconds()))
+		if err != nil {
+			return "", err
+		}
+	}
+	switch value := conn.Get(key).(type) {
+	case string:
+		return value, nil
+	case []byte:
+		return string(value), nil
+	default:
+		return "", fmt.Errorf("Unsupported cached value type: %v", value)
+	}
+}
+
 // Remove key from cache
 func Remove(key string) {
 	if conn == nil {
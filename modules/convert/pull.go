@@ -16,17 +16,15 @@ import (
 // Required - Issue
 // Optional - Merger
 func ToAPIPullRequest(pr *models.PullRequest) *api.PullRequest {
-	var (
-		baseBranch *git.Branch
-		headBranch *git.Branch
-		baseCommit *git.Commit
-		headCommit *git.Commit
-		err        error
-	)
+	var err error
 	if err = pr.Issue.LoadRepo(); err != nil {
 		log.Error("loadRepo[%d]: %v", pr.ID, err)
 		return nil
 	}
+	if _, err = pr.GetLabels(); err != nil {
+		log.Error("GetLabels[%d]: %v", pr.ID, err)
+		return nil
+	}
 	apiIssue := pr.Issue.APIFormat()
 	if pr.BaseRepo == nil {
 		pr.BaseRepo, err = models.GetRepositoryByID(pr.BaseRepoID)
@@ -70,73 +68,174 @@ func ToAPIPullRequest(pr *models.PullRequest) *api.PullRequest {
 		Created:   pr.Issue.CreatedUnix.AsTimePtr(),
 		Updated:   pr.Issue.UpdatedUnix.AsTimePtr(),
 	}
-	baseBranch, err = repo_module.GetBranch(pr.BaseRepo, pr.BaseBranch)
+	apiPullRequest.Base = &api.PRBranchInfo{
+		Name:       pr.BaseBranch,
+		Ref:        pr.BaseBranch,
+		RepoID:     pr.BaseRepoID,
+		Repository: pr.BaseRepo.APIFormat(models.AccessModeNone),
+	}
+	baseCommitID, err := repo_module.GetBranchCommitID(pr.BaseRepo, pr.BaseBranch)
 	if err != nil {
-		if git.IsErrBranchNotExist(err) {
-			apiPullRequest.Base = nil
-		} else {
-			log.Error("GetBranch[%s]: %v", pr.BaseBranch, err)
+		if !git.IsErrBranchNotExist(err) {
+			log.Error("GetBranchCommitID[%s]: %v", pr.BaseBranch, err)
 			return nil
 		}
 	} else {
-		apiBaseBranchInfo := &api.PRBranchInfo{
-			Name:       pr.BaseBranch,
-			Ref:        pr.BaseBranch,
-			RepoID:     pr.BaseRepoID,
-			Repository: pr.BaseRepo.APIFormat(models.AccessModeNone),
-		}
-		baseCommit, err = baseBranch.GetCommit()
-		if err != nil {
-			if git.IsErrNotExist(err) {
-				apiBaseBranchInfo.Sha = ""
-			} else {
-				log.Error("GetCommit[%s]: %v", baseBranch.Name, err)
-				return nil
-			}
-		} else {
-			apiBaseBranchInfo.Sha = baseCommit.ID.String()
-		}
-		apiPullRequest.Base = apiBaseBranchInfo
+		apiPullRequest.Base.Sha = baseCommitID
+		apiPullRequest.Base.Exists = true
+		apiPullRequest.Base.CommitsCount = commitsCountSinceMergeBase(pr.BaseRepo, pr.MergeBase, baseCommitID)
 	}
 
-	headBranch, err = repo_module.GetBranch(pr.HeadRepo, pr.HeadBranch)
+	apiPullRequest.Head = &api.PRBranchInfo{
+		Name:       pr.HeadBranch,
+		Ref:        pr.HeadBranch,
+		RepoID:     pr.HeadRepoID,
+		Repository: pr.HeadRepo.APIFormat(models.AccessModeNone),
+	}
+	headRepo := pr.HeadRepo
+	headCommitID, err := repo_module.GetBranchCommitID(pr.HeadRepo, pr.HeadBranch)
 	if err != nil {
-		if git.IsErrBranchNotExist(err) {
-			apiPullRequest.Head = nil
-		} else {
-			log.Error("GetBranch[%s]: %v", pr.HeadBranch, err)
+		if !git.IsErrBranchNotExist(err) {
+			log.Error("GetBranchCommitID[%s]: %v", pr.HeadBranch, err)
 			return nil
 		}
-	} else {
-		apiHeadBranchInfo := &api.PRBranchInfo{
-			Name:       pr.HeadBranch,
-			Ref:        pr.HeadBranch,
-			RepoID:     pr.HeadRepoID,
-			Repository: pr.HeadRepo.APIFormat(models.AccessModeNone),
+		// the head of the pull request is kept in the base repository after the branch was deleted
+		headRepo = pr.BaseRepo
+		if headCommitID, err = getRefCommitID(pr.BaseRepo, pr.GetGitRefName()); err != nil {
+			log.Debug("GetRefCommitID[%s]: %v", pr.GetGitRefName(), err)
+			headCommitID = ""
 		}
-		headCommit, err = headBranch.GetCommit()
-		if err != nil {
-			if git.IsErrNotExist(err) {
-				apiHeadBranchInfo.Sha = ""
-			} else {
-				log.Error("GetCommit[%s]: %v", headBranch.Name, err)
-				return nil
-			}
-		} else {
-			apiHeadBranchInfo.Sha = headCommit.ID.String()
-		}
-		apiPullRequest.Head = apiHeadBranchInfo
+	} else {
+		apiPullRequest.Head.Exists = true
+	}
+	if headCommitID != "" {
+		apiPullRequest.Head.Sha = headCommitID
+		apiPullRequest.Head.CommitsCount = commitsCountSinceMergeBase(headRepo, pr.MergeBase, headCommitID)
 	}
 
 	if pr.Status != models.PullRequestStatusChecking {
-		mergeable := !(pr.Status == models.PullRequestStatusConflict || pr.Status == models.PullRequestStatusError) && !pr.IsWorkInProgress()
+		mergeable := !(pr.Status == models.PullRequestStatusConflict || pr.Status == models.PullRequestStatusError || pr.Status == models.PullRequestStatusPatchTooLarge) && !pr.IsWorkInProgress()
 		apiPullRequest.Mergeable = mergeable
 	}
+	if !pr.HasMerged && !pr.Issue.IsClosed {
+		apiPullRequest.MergeableState = toMergeableState(pr, baseCommitID)
+	}
 	if pr.HasMerged {
 		apiPullRequest.Merged = pr.MergedUnix.AsTimePtr()
 		apiPullRequest.MergedCommitID = &pr.MergedCommitID
 		apiPullRequest.MergedBy = pr.Merger.APIFormat()
+		apiPullRequest.MergedReviewers = pr.MergedReviewers
+		apiPullRequest.RevertedByCommitID = pr.RevertedByCommitID
+		if pr.RevertedByPullID > 0 {
+			revertedBy, err := models.GetPullRequestByID(pr.RevertedByPullID)
+			if err != nil {
+				log.Error("GetPullRequestByID[%d]: %v", pr.RevertedByPullID, err)
+			} else {
+				apiPullRequest.RevertedByPullIndex = revertedBy.Index
+			}
+		}
 	}
 
 	return apiPullRequest
 }
+
+// commitsCountSinceMergeBase returns the number of commits of the given commit in the
+// repository which are not reachable from the merge base, or 0 if the merge base is unknown.
+func commitsCountSinceMergeBase(repo *models.Repository, mergeBase, commitID string) int {
+	if mergeBase == "" {
+		return 0
+	}
+	count, err := repo_module.GetCommitsCountBetween(repo, mergeBase, commitID)
+	if err != nil {
+		log.Debug("GetCommitsCountBetween[%s...%s]: %v", mergeBase, commitID, err)
+		return 0
+	}
+	return int(count)
+}
+
+// getRefCommitID returns the commit the given reference points to in the repository.
+func getRefCommitID(repo *models.Repository, ref string) (string, error) {
+	gitRepo, err := git.OpenRepository(repo.RepoPath())
+	if err != nil {
+		return "", err
+	}
+	defer gitRepo.Close()
+
+	return gitRepo.GetRefCommitID(ref)
+}
+
+// toMergeableState derives the mergeable state of an open pull request from its
+// status, the branch protection of its base branch and the current base commit.
+func toMergeableState(pr *models.PullRequest, baseCommitID string) string {
+	switch pr.Status {
+	case models.PullRequestStatusChecking:
+		return api.MergeableStateChecking
+	case models.PullRequestStatusConflict, models.PullRequestStatusError, models.PullRequestStatusPatchTooLarge:
+		return api.MergeableStateDirty
+	}
+	if pr.IsWorkInProgress() {
+		return api.MergeableStateDraft
+	}
+
+	if err := pr.LoadProtectedBranch(); err != nil {
+		log.Error("LoadProtectedBranch[%d]: %v", pr.ID, err)
+	} else if pr.ProtectedBranch != nil {
+		if !pr.ProtectedBranch.HasEnoughApprovals(pr) || pr.ProtectedBranch.MergeBlockedByRejectedReview(pr) {
+			return api.MergeableStateBlocked
+		}
+		allPassed, _, _, err := pr.RequiredStatusChecksState()
+		if err != nil {
+			log.Error("RequiredStatusChecksState[%d]: %v", pr.ID, err)
+		} else if !allPassed {
+			return api.MergeableStateBlocked
+		}
+	}
+
+	if baseCommitID != "" && pr.MergeBase != "" && pr.MergeBase != baseCommitID {
+		return api.MergeableStateBehind
+	}
+	return api.MergeableStateClean
+}
+
+// ToPullRequestTimeline converts the timeline of a pull request of the given repository
+// to its API format
+func ToPullRequestTimeline(repo *models.Repository, entries []models.TimelineEntry) []*api.PullRequestTimelineEntry {
+	result := make([]*api.PullRequestTimelineEntry, 0, len(entries))
+	for _, entry := range entries {
+		apiEntry := &api.PullRequestTimelineEntry{
+			Created: entry.Time.AsTime(),
+		}
+		switch entry.Type {
+		case models.TimelineEntryCommit:
+			apiEntry.Type = "commit"
+			apiEntry.Commit = ToCommit(repo, entry.Commit)
+		case models.TimelineEntryReview:
+			apiEntry.Type = "review"
+			apiEntry.Review = &api.PullRequestTimelineReview{
+				ID:       entry.Review.ID,
+				Reviewer: entry.Review.Reviewer.APIFormat(),
+				State:    toReviewState(entry.Review.Type),
+				Body:     entry.Review.Content,
+				Stale:    entry.Review.Stale,
+			}
+		case models.TimelineEntryStatus:
+			apiEntry.Type = "status"
+			apiEntry.Status = entry.Status.APIFormat()
+		case models.TimelineEntryMerged:
+			apiEntry.Type = "merged"
+		}
+		result = append(result, apiEntry)
+	}
+	return result
+}
+
+func toReviewState(reviewType models.ReviewType) string {
+	switch reviewType {
+	case models.ReviewTypeApprove:
+		return "APPROVED"
+	case models.ReviewTypeReject:
+		return "REQUEST_CHANGES"
+	default:
+		return "COMMENT"
+	}
+}
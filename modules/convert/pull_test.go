@@ -19,5 +19,53 @@ func TestPullRequest_APIFormat(t *testing.T) {
 	assert.NoError(t, pr.LoadIssue())
 	apiPullRequest := ToAPIPullRequest(pr)
 	assert.NotNil(t, apiPullRequest)
-	assert.Nil(t, apiPullRequest.Head)
+	assert.NotNil(t, apiPullRequest.Head)
+	assert.False(t, apiPullRequest.Head.Exists)
+	assert.EqualValues(t, "branch1", apiPullRequest.Head.Ref)
+	assert.Empty(t, apiPullRequest.MergeableState)
+}
+
+func TestPullRequest_APIFormat_Branches(t *testing.T) {
+	assert.NoError(t, models.PrepareTestDatabase())
+	pr := models.AssertExistsAndLoadBean(t, &models.PullRequest{ID: 2}).(*models.PullRequest)
+	assert.NoError(t, pr.LoadIssue())
+
+	// without a merge base there is nothing to count from
+	pr.MergeBase = ""
+	apiPullRequest := ToAPIPullRequest(pr)
+	assert.True(t, apiPullRequest.Head.Exists)
+	assert.EqualValues(t, "985f0301dba5e7b34be866819cd15ad3d8f508ee", apiPullRequest.Head.Sha)
+	assert.EqualValues(t, 0, apiPullRequest.Head.CommitsCount)
+	assert.True(t, apiPullRequest.Base.Exists)
+	assert.EqualValues(t, 0, apiPullRequest.Base.CommitsCount)
+
+	pr.MergeBase = "65f1bf27bc3bf70f64657658635e66094edbcb4d"
+	apiPullRequest = ToAPIPullRequest(pr)
+	assert.EqualValues(t, 2, apiPullRequest.Head.CommitsCount)
+	assert.EqualValues(t, 0, apiPullRequest.Base.CommitsCount)
+}
+
+func TestToPullRequestTimeline(t *testing.T) {
+	assert.NoError(t, models.PrepareTestDatabase())
+	pr := models.AssertExistsAndLoadBean(t, &models.PullRequest{ID: 2}).(*models.PullRequest)
+	assert.NoError(t, pr.GetBaseRepo())
+	pr.MergeBase = "65f1bf27bc3bf70f64657658635e66094edbcb4d"
+	pr.HasMerged = true
+
+	entries, err := pr.GetTimeline()
+	assert.NoError(t, err)
+	apiEntries := ToPullRequestTimeline(pr.BaseRepo, entries)
+	if assert.Len(t, apiEntries, len(entries)) {
+		// the merged entry has no time as the pull request was not really merged
+		assert.Equal(t, "merged", apiEntries[0].Type)
+		assert.Equal(t, "review", apiEntries[1].Type)
+		assert.EqualValues(t, 5, apiEntries[1].Review.ID)
+		assert.Equal(t, "COMMENT", apiEntries[1].Review.State)
+		assert.Equal(t, "user1", apiEntries[1].Review.Reviewer.UserName)
+		assert.Equal(t, "REQUEST_CHANGES", apiEntries[2].Review.State)
+		assert.Equal(t, "APPROVED", apiEntries[3].Review.State)
+		last := apiEntries[len(apiEntries)-1]
+		assert.Equal(t, "commit", last.Type)
+		assert.Equal(t, "4a357436d925b5c974181ff12a994538ddc5a269", last.Commit.ID)
+	}
 }
@@ -16,6 +16,7 @@ import (
 	"code.gitea.io/gitea/modules/setting"
 	"code.gitea.io/gitea/modules/sync"
 	mirror_service "code.gitea.io/gitea/services/mirror"
+	pull_service "code.gitea.io/gitea/services/pull"
 
 	"github.com/gogs/cron"
 )
@@ -28,6 +29,7 @@ const (
 	syncExternalUsers       = "sync_external_users"
 	deletedBranchesCleanup  = "deleted_branches_cleanup"
 	updateMigrationPosterID = "update_migration_post_id"
+	closeStalePullRequests  = "close_stale_pull_requests"
 )
 
 var c = cron.New()
@@ -123,6 +125,17 @@ func NewContext() {
 			go WithUnique(deletedBranchesCleanup, models.RemoveOldDeletedBranches)()
 		}
 	}
+	if setting.Cron.CloseStalePullRequests.Enabled {
+		entry, err = c.AddFunc("Close stale pull requests", setting.Cron.CloseStalePullRequests.Schedule, WithUnique(closeStalePullRequests, pull_service.CloseStalePullRequests))
+		if err != nil {
+			log.Fatal("Cron[Close stale pull requests]: %v", err)
+		}
+		if setting.Cron.CloseStalePullRequests.RunAtStart {
+			entry.Prev = time.Now()
+			entry.ExecTimes++
+			go WithUnique(closeStalePullRequests, pull_service.CloseStalePullRequests)()
+		}
+	}
 
 	entry, err = c.AddFunc("Update migrated repositories' issues and comments' posterid", setting.Cron.UpdateMigrationPosterID.Schedule, WithUnique(updateMigrationPosterID, migrations.UpdateMigrationPosterID))
 	if err != nil {
@@ -6,6 +6,7 @@ package git
 
 import (
 	"io/ioutil"
+	"time"
 
 	"gopkg.in/src-d/go-git.v4/plumbing/object"
 )
@@ -17,7 +18,21 @@ const NotesRef = "refs/notes/commits"
 // Note stores information about a note created using git-notes.
 type Note struct {
 	Message []byte
-	Commit  *Commit
+	Commit  *Commit // commit of the notes ref which last changed the note
+
+	NoteAuthor *Signature // author of the note, read from Commit
+	NoteWhen   time.Time  // when the note was added or last changed
+}
+
+// setCommit sets the commit of the notes ref which last changed the note and the note metadata read from it.
+func (note *Note) setCommit(c *object.Commit) {
+	note.Commit = convertCommit(c)
+	note.NoteAuthor = &Signature{
+		Name:  c.Author.Name,
+		Email: c.Author.Email,
+		When:  c.Author.When,
+	}
+	note.NoteWhen = c.Author.When
 }
 
 // GetNote retrieves the git-notes data for a given commit.
@@ -27,15 +42,87 @@ func GetNote(repo *Repository, commitID string, note *Note) error {
 		return err
 	}
 
+	file, path, err := findNoteFile(notes.Tree.gogitTree, commitID)
+	if err != nil {
+		return err
+	}
+
+	note.Message, err = readNoteMessage(file)
+	if err != nil {
+		return err
+	}
+
+	lastCommits, err := getLastNoteCommits(repo, notes, []string{path})
+	if err != nil {
+		return err
+	}
+	note.setCommit(lastCommits[path])
+
+	return nil
+}
+
+// GetNotes retrieves the git-notes data for the given commits, reading the notes tree only once.
+// Commits without a note are absent from the returned map.
+func GetNotes(repo *Repository, commitIDs []string) (map[string]*Note, error) {
+	result := make(map[string]*Note, len(commitIDs))
+
+	notes, err := repo.GetCommit(NotesRef)
+	if err != nil {
+		if IsErrNotExist(err) {
+			return result, nil
+		}
+		return nil, err
+	}
+
+	paths := make(map[string]string, len(commitIDs))
+	for _, commitID := range commitIDs {
+		if _, ok := result[commitID]; ok {
+			continue
+		}
+		file, path, err := findNoteFile(notes.Tree.gogitTree, commitID)
+		if err == object.ErrFileNotFound || err == object.ErrDirectoryNotFound {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		message, err := readNoteMessage(file)
+		if err != nil {
+			return nil, err
+		}
+		result[commitID] = &Note{Message: message}
+		paths[commitID] = path
+	}
+
+	if len(paths) == 0 {
+		return result, nil
+	}
+
+	treePaths := make([]string, 0, len(paths))
+	for _, path := range paths {
+		treePaths = append(treePaths, path)
+	}
+	lastCommits, err := getLastNoteCommits(repo, notes, treePaths)
+	if err != nil {
+		return nil, err
+	}
+	for commitID, path := range paths {
+		result[commitID].setCommit(lastCommits[path])
+	}
+
+	return result, nil
+}
+
+// findNoteFile looks up the note of commitID in the notes tree, descending into
+// fanout subtrees as needed. It returns the note file and its path in the tree.
+func findNoteFile(tree *object.Tree, commitID string) (*object.File, string, error) {
 	remainingCommitID := commitID
 	path := ""
-	currentTree := notes.Tree.gogitTree
-	var file *object.File
+	currentTree := tree
 	for len(remainingCommitID) > 2 {
-		file, err = currentTree.File(remainingCommitID)
+		file, err := currentTree.File(remainingCommitID)
 		if err == nil {
-			path += remainingCommitID
-			break
+			return file, path + remainingCommitID, nil
 		}
 		if err == object.ErrFileNotFound {
 			currentTree, err = currentTree.Tree(remainingCommitID[0:2])
@@ -43,23 +130,22 @@ func GetNote(repo *Repository, commitID string, note *Note) error {
 			remainingCommitID = remainingCommitID[2:]
 		}
 		if err != nil {
-			return err
+			return nil, "", err
 		}
 	}
+	return nil, "", object.ErrFileNotFound
+}
 
-	blob := file.Blob
-	dataRc, err := blob.Reader()
+func readNoteMessage(file *object.File) ([]byte, error) {
+	dataRc, err := file.Blob.Reader()
 	if err != nil {
-		return err
+		return nil, err
 	}
-
 	defer dataRc.Close()
-	d, err := ioutil.ReadAll(dataRc)
-	if err != nil {
-		return err
-	}
-	note.Message = d
+	return ioutil.ReadAll(dataRc)
+}
 
+func getLastNoteCommits(repo *Repository, notes *Commit, paths []string) (map[string]*object.Commit, error) {
 	commitNodeIndex, commitGraphFile := repo.CommitNodeIndex()
 	if commitGraphFile != nil {
 		defer commitGraphFile.Close()
@@ -67,14 +153,8 @@ func GetNote(repo *Repository, commitID string, note *Note) error {
 
 	commitNode, err := commitNodeIndex.Get(notes.ID)
 	if err != nil {
-		return err
-	}
-
-	lastCommits, err := getLastCommitForPaths(commitNode, "", []string{path})
-	if err != nil {
-		return err
+		return nil, err
 	}
-	note.Commit = convertCommit(lastCommits[path])
 
-	return nil
+	return getLastCommitForPaths(commitNode, "", paths)
 }
@@ -22,6 +22,8 @@ func TestGetNotes(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, []byte("Note contents\n"), note.Message)
 	assert.Equal(t, "Vladimir Panteleev", note.Commit.Author.Name)
+	assert.Equal(t, "Vladimir Panteleev", note.NoteAuthor.Name)
+	assert.Equal(t, note.Commit.Author.When, note.NoteWhen)
 }
 
 func TestGetNestedNotes(t *testing.T) {
@@ -38,3 +40,25 @@ func TestGetNestedNotes(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, []byte("Note 1"), note.Message)
 }
+
+func TestGetNotesBatch(t *testing.T) {
+	repoPath := filepath.Join(testReposDir, "repo3_notes")
+	repo, err := OpenRepository(repoPath)
+	assert.NoError(t, err)
+	defer repo.Close()
+
+	notes, err := GetNotes(repo, []string{
+		"3e668dbfac39cbc80a9ff9c61eb565d944453ba4",
+		"ba0a96fa63532d6c5087ecef070b0250ed72fa47",
+		"0000000000000000000000000000000000000000",
+	})
+	assert.NoError(t, err)
+	assert.Len(t, notes, 2)
+	if assert.Contains(t, notes, "3e668dbfac39cbc80a9ff9c61eb565d944453ba4") {
+		assert.Equal(t, []byte("Note 2"), notes["3e668dbfac39cbc80a9ff9c61eb565d944453ba4"].Message)
+		assert.NotNil(t, notes["3e668dbfac39cbc80a9ff9c61eb565d944453ba4"].Commit)
+	}
+	if assert.Contains(t, notes, "ba0a96fa63532d6c5087ecef070b0250ed72fa47") {
+		assert.Equal(t, []byte("Note 1"), notes["ba0a96fa63532d6c5087ecef070b0250ed72fa47"].Message)
+	}
+}
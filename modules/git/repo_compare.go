@@ -107,6 +107,52 @@ func (repo *Repository) GetDiff(base, head string, w io.Writer) error {
 		RunInDirPipeline(repo.Path, w, nil)
 }
 
+// GetCherryPickedCommits returns the IDs of the commits reachable from head but not from
+// upstream whose changes already exist in upstream since their merge base, e.g. because they
+// were cherry-picked. Commits are compared by their patch-id, oldest first.
+func (repo *Repository) GetCherryPickedCommits(upstream, head string) ([]string, error) {
+	stdout, err := NewCommand("cherry", upstream, head).RunInDir(repo.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	var commitIDs []string
+	for _, line := range strings.Split(stdout, "\n") {
+		if strings.HasPrefix(line, "- ") {
+			commitIDs = append(commitIDs, strings.TrimSpace(line[2:]))
+		}
+	}
+	return commitIDs, nil
+}
+
+// GetChangedFilesBetween returns the paths of the files added, modified or deleted between
+// base and head. Both the old and the new path of a renamed file are returned.
+func (repo *Repository) GetChangedFilesBetween(base, head string) ([]string, error) {
+	stdout, err := NewCommand("diff", "--name-status", "-z", "-M", base, head).RunInDir(repo.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	// every entry is its status followed by its path, renames are followed by the old and the new path
+	fields := strings.Split(strings.TrimSuffix(stdout, "\x00"), "\x00")
+	var files []string
+	for i := 0; i < len(fields); i++ {
+		status := fields[i]
+		if len(status) == 0 {
+			continue
+		}
+		paths := 1
+		if status[0] == 'R' {
+			paths = 2
+		}
+		for ; paths > 0 && i+1 < len(fields); paths-- {
+			i++
+			files = append(files, fields[i])
+		}
+	}
+	return files, nil
+}
+
 // GetPatch generates and returns format-patch data between given revisions.
 func (repo *Repository) GetPatch(base, head string, w io.Writer) error {
 	return NewCommand("format-patch", "--binary", "--stdout", base+"..."+head).
@@ -31,3 +31,55 @@ func TestGetFormatPatch(t *testing.T) {
 	assert.Regexp(t, "^From 8d92fc95", patch)
 	assert.Contains(t, patch, "Subject: [PATCH] Add file2.txt")
 }
+
+func TestGetCherryPickedCommits(t *testing.T) {
+	bareRepo1Path := filepath.Join(testReposDir, "repo1_bare")
+	clonedPath, err := cloneRepo(bareRepo1Path, testReposDir, "repo1_TestGetCherryPickedCommits")
+	assert.NoError(t, err)
+	defer os.RemoveAll(clonedPath)
+	repo, err := OpenRepository(clonedPath)
+	assert.NoError(t, err)
+	defer repo.Close()
+
+	commitIDs, err := repo.GetCherryPickedCommits("master", "origin/branch1")
+	assert.NoError(t, err)
+	assert.Empty(t, commitIDs)
+
+	// "Add branch1.txt" is the first of the two commits of branch1
+	_, err = NewCommand("-c", "user.name=Gitea", "-c", "user.email=gitea@fake.local",
+		"cherry-pick", "9c9aef8dd84e02bc7ec12641deb4c930a7c30185").RunInDir(clonedPath)
+	assert.NoError(t, err)
+
+	commitIDs, err = repo.GetCherryPickedCommits("master", "origin/branch1")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"9c9aef8dd84e02bc7ec12641deb4c930a7c30185"}, commitIDs)
+}
+
+func TestGetChangedFilesBetween(t *testing.T) {
+	bareRepo1Path := filepath.Join(testReposDir, "repo1_bare")
+	clonedPath, err := cloneRepo(bareRepo1Path, testReposDir, "repo1_TestGetChangedFilesBetween")
+	assert.NoError(t, err)
+	defer os.RemoveAll(clonedPath)
+	repo, err := OpenRepository(clonedPath)
+	assert.NoError(t, err)
+	defer repo.Close()
+
+	files, err := repo.GetChangedFilesBetween("master", "master")
+	assert.NoError(t, err)
+	assert.Empty(t, files)
+
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(clonedPath, "file3.txt"), []byte("file3\n"), 0644))
+	_, err = NewCommand("add", "file3.txt").RunInDir(clonedPath)
+	assert.NoError(t, err)
+	_, err = NewCommand("rm", "file2.txt").RunInDir(clonedPath)
+	assert.NoError(t, err)
+	_, err = NewCommand("mv", "file1.txt", "renamed.txt").RunInDir(clonedPath)
+	assert.NoError(t, err)
+	_, err = NewCommand("-c", "user.name=Gitea", "-c", "user.email=gitea@fake.local",
+		"commit", "-m", "Add, delete and rename files").RunInDir(clonedPath)
+	assert.NoError(t, err)
+
+	files, err = repo.GetChangedFilesBetween("origin/master", "HEAD")
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"file3.txt", "file2.txt", "file1.txt", "renamed.txt"}, files)
+}
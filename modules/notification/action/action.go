@@ -86,6 +86,30 @@ func (a *actionNotifier) NotifyIssueChangeStatus(doer *models.User, issue *model
 	}
 }
 
+// NotifyPullRequestAutoClosed notifies the watchers about a pull request closed automatically by doer
+func (a *actionNotifier) NotifyPullRequestAutoClosed(doer *models.User, pr *models.PullRequest, reason string) {
+	if err := pr.LoadIssue(); err != nil {
+		log.Error("LoadIssue: %v", err)
+		return
+	}
+	if err := pr.Issue.LoadRepo(); err != nil {
+		log.Error("LoadRepo: %v", err)
+		return
+	}
+
+	if err := models.NotifyWatchers(&models.Action{
+		ActUserID: doer.ID,
+		ActUser:   doer,
+		OpType:    models.ActionClosePullRequest,
+		Content:   fmt.Sprintf("%d|%s", pr.Issue.Index, ""),
+		RepoID:    pr.Issue.Repo.ID,
+		Repo:      pr.Issue.Repo,
+		IsPrivate: pr.Issue.Repo.IsPrivate,
+	}); err != nil {
+		log.Error("NotifyWatchers: %v", err)
+	}
+}
+
 // NotifyCreateIssueComment notifies comment on an issue to notifiers
 func (a *actionNotifier) NotifyCreateIssueComment(doer *models.User, repo *models.Repository,
 	issue *models.Issue, comment *models.Comment) {
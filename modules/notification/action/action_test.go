@@ -45,3 +45,24 @@ func TestRenameRepoAction(t *testing.T) {
 	models.AssertExistsAndLoadBean(t, actionBean)
 	models.CheckConsistencyFor(t, &models.Action{})
 }
+
+func TestPullRequestAutoClosedAction(t *testing.T) {
+	assert.NoError(t, models.PrepareTestDatabase())
+
+	doer := models.NewGhostUser()
+	pr := models.AssertExistsAndLoadBean(t, &models.PullRequest{ID: 2}).(*models.PullRequest)
+
+	actionBean := &models.Action{
+		OpType:    models.ActionClosePullRequest,
+		UserID:    4,
+		ActUserID: doer.ID,
+		RepoID:    pr.BaseRepoID,
+	}
+	models.AssertNotExistsBean(t, actionBean)
+
+	NewNotifier().NotifyPullRequestAutoClosed(doer, pr, "stale")
+
+	models.AssertExistsAndLoadBean(t, actionBean)
+	// the repository owner did not close it
+	models.AssertNotExistsBean(t, &models.Action{OpType: models.ActionClosePullRequest, ActUserID: 2})
+}
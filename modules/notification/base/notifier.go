@@ -7,6 +7,7 @@ package base
 import (
 	"code.gitea.io/gitea/models"
 	"code.gitea.io/gitea/modules/repository"
+	"code.gitea.io/gitea/modules/timeutil"
 )
 
 // Notifier defines an interface to notify receiver
@@ -27,6 +28,7 @@ type Notifier interface {
 	NotifyIssueChangeContent(doer *models.User, issue *models.Issue, oldContent string)
 	NotifyIssueClearLabels(doer *models.User, issue *models.Issue)
 	NotifyIssueChangeTitle(doer *models.User, issue *models.Issue, oldTitle string)
+	NotifyIssueChangePin(doer *models.User, issue *models.Issue, pinned bool)
 	NotifyIssueChangeLabels(doer *models.User, issue *models.Issue,
 		addedLabels []*models.Label, removedLabels []*models.Label)
 
@@ -35,6 +37,13 @@ type Notifier interface {
 	NotifyPullRequestSynchronized(doer *models.User, pr *models.PullRequest)
 	NotifyPullRequestReview(*models.PullRequest, *models.Review, *models.Comment)
 	NotifyPullRequestChangeTargetBranch(doer *models.User, pr *models.PullRequest, oldBranch string)
+	NotifyPullRequestChangeDeadline(doer *models.User, pr *models.PullRequest, oldDeadline timeutil.TimeStamp)
+	NotifyPullRequestConvertToDraft(doer *models.User, pr *models.PullRequest)
+	NotifyPullRequestApprovalsSatisfied(pr *models.PullRequest)
+	NotifyPullRequestAutoClosed(doer *models.User, pr *models.PullRequest, reason string)
+	NotifyPullRequestMergeableChanged(pr *models.PullRequest, oldStatus models.PullRequestStatus)
+
+	NotifyCreateCommitStatus(repo *models.Repository, sha string, status *models.CommitStatus, doer *models.User)
 
 	NotifyCreateIssueComment(*models.User, *models.Repository,
 		*models.Issue, *models.Comment)
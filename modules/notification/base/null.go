@@ -7,6 +7,7 @@ package base
 import (
 	"code.gitea.io/gitea/models"
 	"code.gitea.io/gitea/modules/repository"
+	"code.gitea.io/gitea/modules/timeutil"
 )
 
 // NullNotifier implements a blank notifier
@@ -54,6 +55,30 @@ func (*NullNotifier) NotifyPullRequestSynchronized(doer *models.User, pr *models
 func (*NullNotifier) NotifyPullRequestChangeTargetBranch(doer *models.User, pr *models.PullRequest, oldBranch string) {
 }
 
+// NotifyPullRequestChangeDeadline places a place holder function
+func (*NullNotifier) NotifyPullRequestChangeDeadline(doer *models.User, pr *models.PullRequest, oldDeadline timeutil.TimeStamp) {
+}
+
+// NotifyPullRequestConvertToDraft places a place holder function
+func (*NullNotifier) NotifyPullRequestConvertToDraft(doer *models.User, pr *models.PullRequest) {
+}
+
+// NotifyPullRequestApprovalsSatisfied places a place holder function
+func (*NullNotifier) NotifyPullRequestApprovalsSatisfied(pr *models.PullRequest) {
+}
+
+// NotifyPullRequestAutoClosed places a place holder function
+func (*NullNotifier) NotifyPullRequestAutoClosed(doer *models.User, pr *models.PullRequest, reason string) {
+}
+
+// NotifyPullRequestMergeableChanged places a place holder function
+func (*NullNotifier) NotifyPullRequestMergeableChanged(pr *models.PullRequest, oldStatus models.PullRequestStatus) {
+}
+
+// NotifyCreateCommitStatus places a place holder function
+func (*NullNotifier) NotifyCreateCommitStatus(repo *models.Repository, sha string, status *models.CommitStatus, doer *models.User) {
+}
+
 // NotifyUpdateComment places a place holder function
 func (*NullNotifier) NotifyUpdateComment(doer *models.User, c *models.Comment, oldContent string) {
 }
@@ -94,6 +119,10 @@ func (*NullNotifier) NotifyIssueClearLabels(doer *models.User, issue *models.Iss
 func (*NullNotifier) NotifyIssueChangeTitle(doer *models.User, issue *models.Issue, oldTitle string) {
 }
 
+// NotifyIssueChangePin places a place holder function
+func (*NullNotifier) NotifyIssueChangePin(doer *models.User, issue *models.Issue, pinned bool) {
+}
+
 // NotifyIssueChangeLabels places a place holder function
 func (*NullNotifier) NotifyIssueChangeLabels(doer *models.User, issue *models.Issue,
 	addedLabels []*models.Label, removedLabels []*models.Label) {
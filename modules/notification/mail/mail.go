@@ -72,6 +72,18 @@ func (m *mailNotifier) NotifyIssueChangeStatus(doer *models.User, issue *models.
 	}
 }
 
+func (m *mailNotifier) NotifyPullRequestAutoClosed(doer *models.User, pr *models.PullRequest, reason string) {
+	if err := pr.LoadIssue(); err != nil {
+		log.Error("LoadIssue: %v", err)
+		return
+	}
+	if err := pr.Issue.LoadRepo(); err != nil {
+		log.Error("LoadRepo: %v", err)
+		return
+	}
+	m.NotifyIssueChangeStatus(doer, pr.Issue, nil, true)
+}
+
 func (m *mailNotifier) NotifyNewPullRequest(pr *models.PullRequest) {
 	if err := mailer.MailParticipants(pr.Issue, pr.Issue.Poster, models.ActionCreatePullRequest); err != nil {
 		log.Error("MailParticipants: %v", err)
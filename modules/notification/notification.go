@@ -14,6 +14,7 @@ import (
 	"code.gitea.io/gitea/modules/notification/webhook"
 	"code.gitea.io/gitea/modules/repository"
 	"code.gitea.io/gitea/modules/setting"
+	"code.gitea.io/gitea/modules/timeutil"
 )
 
 var (
@@ -87,6 +88,34 @@ func NotifyPullRequestReview(pr *models.PullRequest, review *models.Review, comm
 	}
 }
 
+// NotifyPullRequestConvertToDraft notifies when a pull request was marked as a work in progress
+func NotifyPullRequestConvertToDraft(doer *models.User, pr *models.PullRequest) {
+	for _, notifier := range notifiers {
+		notifier.NotifyPullRequestConvertToDraft(doer, pr)
+	}
+}
+
+// NotifyPullRequestApprovalsSatisfied notifies when a pull request got the approvals required by its base branch
+func NotifyPullRequestApprovalsSatisfied(pr *models.PullRequest) {
+	for _, notifier := range notifiers {
+		notifier.NotifyPullRequestApprovalsSatisfied(pr)
+	}
+}
+
+// NotifyPullRequestAutoClosed notifies when a pull request was closed automatically by doer, e.g. for inactivity
+func NotifyPullRequestAutoClosed(doer *models.User, pr *models.PullRequest, reason string) {
+	for _, notifier := range notifiers {
+		notifier.NotifyPullRequestAutoClosed(doer, pr, reason)
+	}
+}
+
+// NotifyPullRequestMergeableChanged notifies when a conflict check changed the status of a pull request
+func NotifyPullRequestMergeableChanged(pr *models.PullRequest, oldStatus models.PullRequestStatus) {
+	for _, notifier := range notifiers {
+		notifier.NotifyPullRequestMergeableChanged(pr, oldStatus)
+	}
+}
+
 // NotifyPullRequestChangeTargetBranch notifies when a pull request's target branch was changed
 func NotifyPullRequestChangeTargetBranch(doer *models.User, pr *models.PullRequest, oldBranch string) {
 	for _, notifier := range notifiers {
@@ -94,6 +123,20 @@ func NotifyPullRequestChangeTargetBranch(doer *models.User, pr *models.PullReque
 	}
 }
 
+// NotifyPullRequestChangeDeadline notifies when the deadline of a pull request was set, changed or removed
+func NotifyPullRequestChangeDeadline(doer *models.User, pr *models.PullRequest, oldDeadline timeutil.TimeStamp) {
+	for _, notifier := range notifiers {
+		notifier.NotifyPullRequestChangeDeadline(doer, pr, oldDeadline)
+	}
+}
+
+// NotifyCreateCommitStatus notifies when a commit status was created
+func NotifyCreateCommitStatus(repo *models.Repository, sha string, status *models.CommitStatus, doer *models.User) {
+	for _, notifier := range notifiers {
+		notifier.NotifyCreateCommitStatus(repo, sha, status, doer)
+	}
+}
+
 // NotifyUpdateComment notifies update comment to notifiers
 func NotifyUpdateComment(doer *models.User, c *models.Comment, oldContent string) {
 	for _, notifier := range notifiers {
@@ -164,6 +207,13 @@ func NotifyIssueChangeTitle(doer *models.User, issue *models.Issue, oldTitle str
 	}
 }
 
+// NotifyIssueChangePin notifies pin or unpin issue to notifiers
+func NotifyIssueChangePin(doer *models.User, issue *models.Issue, pinned bool) {
+	for _, notifier := range notifiers {
+		notifier.NotifyIssueChangePin(doer, issue, pinned)
+	}
+}
+
 // NotifyIssueChangeLabels notifies change labels to notifiers
 func NotifyIssueChangeLabels(doer *models.User, issue *models.Issue,
 	addedLabels []*models.Label, removedLabels []*models.Label) {
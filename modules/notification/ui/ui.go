@@ -68,6 +68,21 @@ func (ns *notificationService) NotifyIssueChangeStatus(doer *models.User, issue
 	}
 }
 
+func (ns *notificationService) NotifyPullRequestAutoClosed(doer *models.User, pr *models.PullRequest, reason string) {
+	if err := pr.LoadIssue(); err != nil {
+		log.Error("LoadIssue: %v", err)
+		return
+	}
+	if err := pr.Issue.LoadRepo(); err != nil {
+		log.Error("LoadRepo: %v", err)
+		return
+	}
+	ns.issueQueue <- issueNotificationOpts{
+		issueID:              pr.Issue.ID,
+		notificationAuthorID: doer.ID,
+	}
+}
+
 func (ns *notificationService) NotifyMergePullRequest(pr *models.PullRequest, doer *models.User) {
 	ns.issueQueue <- issueNotificationOpts{
 		issueID:              pr.Issue.ID,
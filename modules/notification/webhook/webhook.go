@@ -5,6 +5,10 @@
 package webhook
 
 import (
+	"strconv"
+	"strings"
+	"time"
+
 	"code.gitea.io/gitea/models"
 	"code.gitea.io/gitea/modules/convert"
 	"code.gitea.io/gitea/modules/git"
@@ -13,9 +17,13 @@ import (
 	"code.gitea.io/gitea/modules/repository"
 	"code.gitea.io/gitea/modules/setting"
 	api "code.gitea.io/gitea/modules/structs"
+	"code.gitea.io/gitea/modules/timeutil"
 	webhook_module "code.gitea.io/gitea/modules/webhook"
 )
 
+// pullRefPrefix is the prefix of the refs kept for the heads of pull requests
+const pullRefPrefix = "refs/pull/"
+
 type webhookNotifier struct {
 	base.NullNotifier
 }
@@ -253,6 +261,46 @@ func (m *webhookNotifier) NotifyIssueChangeStatus(doer *models.User, issue *mode
 	}
 }
 
+func (m *webhookNotifier) NotifyIssueChangePin(doer *models.User, issue *models.Issue, pinned bool) {
+	if err := issue.LoadRepo(); err != nil {
+		log.Error("LoadRepo: %v", err)
+		return
+	}
+
+	action := api.HookIssueUnpinned
+	if pinned {
+		action = api.HookIssuePinned
+	}
+
+	mode, _ := models.AccessLevel(doer, issue.Repo)
+	var err error
+	if issue.IsPull {
+		if err = issue.LoadPullRequest(); err != nil {
+			log.Error("LoadPullRequest: %v", err)
+			return
+		}
+		issue.PullRequest.Issue = issue
+		err = webhook_module.PrepareWebhooks(issue.Repo, models.HookEventPullRequest, &api.PullRequestPayload{
+			Action:      action,
+			Index:       issue.Index,
+			PullRequest: convert.ToAPIPullRequest(issue.PullRequest),
+			Repository:  issue.Repo.APIFormat(mode),
+			Sender:      doer.APIFormat(),
+		})
+	} else {
+		err = webhook_module.PrepareWebhooks(issue.Repo, models.HookEventIssues, &api.IssuePayload{
+			Action:     action,
+			Index:      issue.Index,
+			Issue:      issue.APIFormat(),
+			Repository: issue.Repo.APIFormat(mode),
+			Sender:     doer.APIFormat(),
+		})
+	}
+	if err != nil {
+		log.Error("PrepareWebhooks [is_pull: %v, pinned: %v]: %v", issue.IsPull, pinned, err)
+	}
+}
+
 func (m *webhookNotifier) NotifyNewIssue(issue *models.Issue) {
 	if err := issue.LoadRepo(); err != nil {
 		log.Error("issue.LoadRepo: %v", err)
@@ -365,6 +413,8 @@ func (m *webhookNotifier) NotifyUpdateComment(doer *models.User, c *models.Comme
 		Repository: c.Issue.Repo.APIFormat(mode),
 		Sender:     doer.APIFormat(),
 		IsPull:     c.Issue.IsPull,
+		IsReview:   c.ReviewID > 0,
+		ReviewID:   c.ReviewID,
 	}); err != nil {
 		log.Error("PrepareWebhooks [comment_id: %d]: %v", c.ID, err)
 	}
@@ -380,6 +430,8 @@ func (m *webhookNotifier) NotifyCreateIssueComment(doer *models.User, repo *mode
 		Repository: repo.APIFormat(mode),
 		Sender:     doer.APIFormat(),
 		IsPull:     issue.IsPull,
+		IsReview:   comment.ReviewID > 0,
+		ReviewID:   comment.ReviewID,
 	}); err != nil {
 		log.Error("PrepareWebhooks [comment_id: %d]: %v", comment.ID, err)
 	}
@@ -409,6 +461,8 @@ func (m *webhookNotifier) NotifyDeleteComment(doer *models.User, comment *models
 		Repository: comment.Issue.Repo.APIFormat(mode),
 		Sender:     doer.APIFormat(),
 		IsPull:     comment.Issue.IsPull,
+		IsReview:   comment.ReviewID > 0,
+		ReviewID:   comment.ReviewID,
 	}); err != nil {
 		log.Error("PrepareWebhooks [comment_id: %d]: %v", comment.ID, err)
 	}
@@ -592,6 +646,246 @@ func (m *webhookNotifier) NotifyPullRequestChangeTargetBranch(doer *models.User,
 	}
 }
 
+func (m *webhookNotifier) NotifyCreateCommitStatus(repo *models.Repository, sha string, status *models.CommitStatus, doer *models.User) {
+	gitRepo, err := git.OpenRepository(repo.RepoPath())
+	if err != nil {
+		log.Error("OpenRepository[%s]: %v", repo.RepoPath(), err)
+		return
+	}
+	defer gitRepo.Close()
+
+	commit, err := gitRepo.GetCommit(sha)
+	if err != nil {
+		log.Error("GetCommit[%s]: %v", sha, err)
+		return
+	}
+
+	pullIndex, err := openPullRequestIndexByHead(repo, gitRepo, sha)
+	if err != nil {
+		log.Error("openPullRequestIndexByHead[%s]: %v", sha, err)
+		return
+	}
+
+	mode, _ := models.AccessLevel(doer, repo)
+	if err := webhook_module.PrepareWebhooks(repo, models.HookEventStatus, &api.StatusPayload{
+		SHA:              sha,
+		State:            api.StatusState(status.State),
+		Context:          status.Context,
+		Description:      status.Description,
+		TargetURL:        status.TargetURL,
+		Commit:           convert.ToCommit(repo, commit),
+		PullRequestIndex: pullIndex,
+		Repository:       repo.APIFormat(mode),
+		Sender:           doer.APIFormat(),
+	}); err != nil {
+		log.Error("PrepareWebhooks: %v", err)
+	}
+}
+
+// openPullRequestIndexByHead returns the index of an open pull request of the repository
+// whose head is at the given commit, or 0 if there is none.
+func openPullRequestIndexByHead(repo *models.Repository, gitRepo *git.Repository, sha string) (int64, error) {
+	refs, err := gitRepo.GetRefsFiltered(pullRefPrefix)
+	if err != nil {
+		return 0, err
+	}
+	for _, ref := range refs {
+		if ref.Object.String() != sha || !strings.HasSuffix(ref.Name, "/head") {
+			continue
+		}
+		index, err := strconv.ParseInt(strings.TrimSuffix(strings.TrimPrefix(ref.Name, pullRefPrefix), "/head"), 10, 64)
+		if err != nil {
+			continue
+		}
+		pr, err := models.GetPullRequestByIndex(repo.ID, index)
+		if err != nil {
+			if models.IsErrPullRequestNotExist(err) {
+				continue
+			}
+			return 0, err
+		}
+		if !pr.HasMerged && !pr.Issue.IsClosed {
+			return index, nil
+		}
+	}
+	return 0, nil
+}
+
+// formatDeadline formats a deadline for a changes payload, an unset deadline is formatted as empty string
+func formatDeadline(deadline timeutil.TimeStamp) string {
+	if deadline.IsZero() {
+		return ""
+	}
+	return deadline.Format(time.RFC3339)
+}
+
+func (m *webhookNotifier) NotifyPullRequestChangeDeadline(doer *models.User, pr *models.PullRequest, oldDeadline timeutil.TimeStamp) {
+	if err := pr.LoadIssue(); err != nil {
+		log.Error("LoadIssue: %v", err)
+		return
+	}
+	issue := pr.Issue
+	if err := issue.LoadRepo(); err != nil {
+		log.Error("LoadRepo: %v", err)
+		return
+	}
+	if err := issue.LoadPoster(); err != nil {
+		log.Error("LoadPoster: %v", err)
+		return
+	}
+
+	mode, _ := models.AccessLevel(issue.Poster, issue.Repo)
+	err := webhook_module.PrepareWebhooks(issue.Repo, models.HookEventPullRequest, &api.PullRequestPayload{
+		Action: api.HookIssueEdited,
+		Index:  issue.Index,
+		Changes: &api.ChangesPayload{
+			Deadline: &api.ChangesFromPayload{
+				From: formatDeadline(oldDeadline),
+				To:   formatDeadline(issue.DeadlineUnix),
+			},
+		},
+		PullRequest: convert.ToAPIPullRequest(pr),
+		Repository:  issue.Repo.APIFormat(mode),
+		Sender:      doer.APIFormat(),
+	})
+	if err != nil {
+		log.Error("PrepareWebhooks [pull_id: %v]: %v", pr.ID, err)
+	}
+}
+
+func (m *webhookNotifier) NotifyPullRequestConvertToDraft(doer *models.User, pr *models.PullRequest) {
+	if err := pr.LoadIssue(); err != nil {
+		log.Error("LoadIssue: %v", err)
+		return
+	}
+	issue := pr.Issue
+	if err := issue.LoadRepo(); err != nil {
+		log.Error("LoadRepo: %v", err)
+		return
+	}
+
+	reviews, err := models.GetReviewersByIssueID(issue.ID)
+	if err != nil {
+		log.Error("GetReviewersByIssueID: %v", err)
+		return
+	}
+	reviewers := make([]*api.User, 0, len(reviews))
+	for _, review := range reviews {
+		reviewers = append(reviewers, review.Reviewer.APIFormat())
+	}
+
+	mode, _ := models.AccessLevel(doer, issue.Repo)
+	if err = webhook_module.PrepareWebhooks(issue.Repo, models.HookEventPullRequest, &api.PullRequestPayload{
+		Action:      api.HookIssueConvertedToDraft,
+		Index:       issue.Index,
+		PullRequest: convert.ToAPIPullRequest(pr),
+		Repository:  issue.Repo.APIFormat(mode),
+		Sender:      doer.APIFormat(),
+		Reviewers:   reviewers,
+	}); err != nil {
+		log.Error("PrepareWebhooks [pull_id: %v]: %v", pr.ID, err)
+	}
+}
+
+func (m *webhookNotifier) NotifyPullRequestApprovalsSatisfied(pr *models.PullRequest) {
+	if err := pr.LoadIssue(); err != nil {
+		log.Error("LoadIssue: %v", err)
+		return
+	}
+	if err := pr.Issue.LoadRepo(); err != nil {
+		log.Error("LoadRepo: %v", err)
+		return
+	}
+	if err := pr.LoadProtectedBranch(); err != nil {
+		log.Error("LoadProtectedBranch: %v", err)
+		return
+	}
+	if pr.ProtectedBranch == nil {
+		return
+	}
+
+	approvers, err := pr.ProtectedBranch.GetGrantedApprovers(pr)
+	if err != nil {
+		log.Error("GetGrantedApprovers [pull_id: %v]: %v", pr.ID, err)
+		return
+	}
+	if len(approvers) == 0 {
+		return
+	}
+	apiApprovers := make([]*api.User, len(approvers))
+	for i, approver := range approvers {
+		apiApprovers[i] = approver.APIFormat()
+	}
+	// the approval which satisfied the requirement is the latest one
+	sender := approvers[len(approvers)-1]
+
+	mode, _ := models.AccessLevel(sender, pr.Issue.Repo)
+	if err := webhook_module.PrepareWebhooks(pr.Issue.Repo, models.HookEventPullRequest, &api.PullRequestPayload{
+		Action:            api.HookIssueApprovalsSatisfied,
+		Index:             pr.Issue.Index,
+		PullRequest:       convert.ToAPIPullRequest(pr),
+		Repository:        pr.Issue.Repo.APIFormat(mode),
+		Sender:            sender.APIFormat(),
+		Approvers:         apiApprovers,
+		RequiredApprovals: pr.ProtectedBranch.RequiredApprovals,
+	}); err != nil {
+		log.Error("PrepareWebhooks [pull_id: %v]: %v", pr.ID, err)
+	}
+}
+
+func (m *webhookNotifier) NotifyPullRequestAutoClosed(doer *models.User, pr *models.PullRequest, reason string) {
+	if err := pr.LoadIssue(); err != nil {
+		log.Error("LoadIssue: %v", err)
+		return
+	}
+	if err := pr.Issue.LoadRepo(); err != nil {
+		log.Error("LoadRepo: %v", err)
+		return
+	}
+
+	mode, _ := models.AccessLevel(doer, pr.Issue.Repo)
+	if err := webhook_module.PrepareWebhooks(pr.Issue.Repo, models.HookEventPullRequest, &api.PullRequestPayload{
+		Action:          api.HookIssueClosed,
+		Index:           pr.Issue.Index,
+		PullRequest:     convert.ToAPIPullRequest(pr),
+		Repository:      pr.Issue.Repo.APIFormat(mode),
+		Sender:          doer.APIFormat(),
+		AutoClosed:      true,
+		AutoCloseReason: reason,
+	}); err != nil {
+		log.Error("PrepareWebhooks [pull_id: %v]: %v", pr.ID, err)
+	}
+}
+
+func (m *webhookNotifier) NotifyPullRequestMergeableChanged(pr *models.PullRequest, oldStatus models.PullRequestStatus) {
+	if err := pr.LoadIssue(); err != nil {
+		log.Error("LoadIssue: %v", err)
+		return
+	}
+	if err := pr.Issue.LoadRepo(); err != nil {
+		log.Error("LoadRepo: %v", err)
+		return
+	}
+	if err := pr.Issue.Repo.GetOwner(); err != nil {
+		log.Error("GetOwner: %v", err)
+		return
+	}
+	// the check runs in the background, so it is reported on behalf of the repository owner
+	sender := pr.Issue.Repo.Owner
+
+	mode, _ := models.AccessLevel(sender, pr.Issue.Repo)
+	if err := webhook_module.PrepareWebhooks(pr.Issue.Repo, models.HookEventPullRequest, &api.PullRequestPayload{
+		Action:          api.HookIssueMergeableChanged,
+		Index:           pr.Issue.Index,
+		PullRequest:     convert.ToAPIPullRequest(pr),
+		Repository:      pr.Issue.Repo.APIFormat(mode),
+		Sender:          sender.APIFormat(),
+		ConflictedFiles: pr.ConflictedFiles,
+	}); err != nil {
+		log.Error("PrepareWebhooks [pull_id: %v]: %v", pr.ID, err)
+	}
+}
+
 func (m *webhookNotifier) NotifyPullRequestReview(pr *models.PullRequest, review *models.Review, comment *models.Comment) {
 	var reviewHookType models.HookEventType
 
@@ -691,12 +985,12 @@ func (m *webhookNotifier) NotifyDeleteRef(pusher *models.User, repo *models.Repo
 
 	if err := webhook_module.PrepareWebhooks(repo, models.HookEventDelete, &api.DeletePayload{
 		Ref:        refName,
-		RefType:    "branch",
+		RefType:    refType,
 		PusherType: api.PusherTypeUser,
 		Repo:       apiRepo,
 		Sender:     apiPusher,
 	}); err != nil {
-		log.Error("PrepareWebhooks.(delete branch): %v", err)
+		log.Error("PrepareWebhooks.(delete %s): %v", refType, err)
 	}
 }
 
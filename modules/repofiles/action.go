@@ -17,6 +17,8 @@ import (
 	"code.gitea.io/gitea/modules/references"
 	"code.gitea.io/gitea/modules/repository"
 	"code.gitea.io/gitea/modules/setting"
+	issue_service "code.gitea.io/gitea/services/issue"
+	pull_service "code.gitea.io/gitea/services/pull"
 )
 
 // getIssueFromRef returns the issue referenced by a ref. Returns a nil *Issue
@@ -45,7 +47,12 @@ func changeIssueStatus(repo *models.Repository, issue *models.Issue, doer *model
 	}
 
 	issue.Repo = repo
-	comment, err := issue.ChangeStatus(doer, closed)
+	var err error
+	if issue.IsPull {
+		err = pull_service.ChangeStatus(issue, doer, closed)
+	} else {
+		err = issue_service.ChangeStatus(issue, doer, closed)
+	}
 	if err != nil {
 		// Don't return an error when dependencies are open as this would let the push fail
 		if models.IsErrDependenciesLeft(err) {
@@ -54,8 +61,6 @@ func changeIssueStatus(repo *models.Repository, issue *models.Issue, doer *model
 		return err
 	}
 
-	notification.NotifyIssueChangeStatus(doer, issue, comment, closed)
-
 	return stopTimerIfAvailable(doer, issue)
 }
 
@@ -9,6 +9,7 @@ import (
 
 	"code.gitea.io/gitea/models"
 	"code.gitea.io/gitea/modules/git"
+	"code.gitea.io/gitea/modules/notification"
 )
 
 // CreateCommitStatus creates a new CommitStatus given a bunch of parameters
@@ -22,7 +23,8 @@ func CreateCommitStatus(repo *models.Repository, creator *models.User, sha strin
 	if err != nil {
 		return fmt.Errorf("OpenRepository[%s]: %v", repoPath, err)
 	}
-	if _, err := gitRepo.GetCommit(sha); err != nil {
+	commit, err := gitRepo.GetCommit(sha)
+	if err != nil {
 		gitRepo.Close()
 		return fmt.Errorf("GetCommit[%s]: %v", sha, err)
 	}
@@ -37,5 +39,7 @@ func CreateCommitStatus(repo *models.Repository, creator *models.User, sha strin
 		return fmt.Errorf("NewCommitStatus[repo_id: %d, user_id: %d, sha: %s]: %v", repo.ID, creator.ID, sha, err)
 	}
 
+	notification.NotifyCreateCommitStatus(repo, commit.ID.String(), status, creator)
+
 	return nil
 }
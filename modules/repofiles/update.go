@@ -570,6 +570,10 @@ func createCommitRepoActions(repo *models.Repository, gitRepo *git.Repository, o
 			return nil, fmt.Errorf("Old and new revisions are both %s", git.EmptySHA)
 		}
 		var commits = &repo_module.PushCommits{}
+		if strings.HasPrefix(opts.RefFullName, git.BranchPrefix) {
+			// Clear cache for branch head commit
+			cache.Remove(repo.GetBranchCommitIDCacheKey(opts.RefFullName[len(git.BranchPrefix):]))
+		}
 		if strings.HasPrefix(opts.RefFullName, git.TagPrefix) {
 			// If is tag reference
 			tagName := opts.RefFullName[len(git.TagPrefix):]
@@ -630,6 +634,10 @@ func createCommitRepoActionOption(repo *models.Repository, gitRepo *git.Reposito
 	}
 
 	var commits = &repo_module.PushCommits{}
+	if strings.HasPrefix(opts.RefFullName, git.BranchPrefix) {
+		// Clear cache for branch head commit
+		cache.Remove(repo.GetBranchCommitIDCacheKey(opts.RefFullName[len(git.BranchPrefix):]))
+	}
 	if strings.HasPrefix(opts.RefFullName, git.TagPrefix) {
 		// If is tag reference
 		tagName := opts.RefFullName[len(git.TagPrefix):]
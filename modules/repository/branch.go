@@ -6,10 +6,13 @@ package repository
 
 import (
 	"fmt"
+	"time"
 
 	"code.gitea.io/gitea/models"
+	"code.gitea.io/gitea/modules/cache"
 	"code.gitea.io/gitea/modules/git"
 	"code.gitea.io/gitea/modules/log"
+	"code.gitea.io/gitea/modules/setting"
 )
 
 // GetBranch returns a branch by its name
@@ -23,6 +26,53 @@ func GetBranch(repo *models.Repository, branch string) (*git.Branch, error) {
 	return gitRepo.GetBranch(branch)
 }
 
+// GetBranchCommitID returns the head commit ID of the branch, the result is cached
+// for a short time as it is resolved repeatedly when formatting pull requests
+func GetBranchCommitID(repo *models.Repository, branch string) (string, error) {
+	var ttl time.Duration
+	if setting.CacheService != nil {
+		ttl = setting.CacheService.BranchCommitTTL
+	}
+	return cache.GetStringWithTTL(repo.GetBranchCommitIDCacheKey(branch), ttl, func() (string, error) {
+		gitRepo, err := git.OpenRepository(repo.RepoPath())
+		if err != nil {
+			return "", err
+		}
+		defer gitRepo.Close()
+
+		if !gitRepo.IsBranchExist(branch) {
+			return "", git.ErrBranchNotExist{Name: branch}
+		}
+		return gitRepo.GetBranchCommitID(branch)
+	})
+}
+
+// GetCommitsCountBetween returns the number of commits reachable from end but not from start,
+// both given as commit IDs. The result never changes for them, so it is cached like commit counts.
+func GetCommitsCountBetween(repo *models.Repository, start, end string) (int64, error) {
+	return cache.GetInt64(repo.GetCommitsCountBetweenCacheKey(start, end), func() (int64, error) {
+		gitRepo, err := git.OpenRepository(repo.RepoPath())
+		if err != nil {
+			return 0, err
+		}
+		defer gitRepo.Close()
+
+		return gitRepo.CommitsCountBetween(start, end)
+	})
+}
+
+// DeleteBranch deletes the branch from the given git repository of repo and drops its
+// cached head commit, branches must not be deleted without it.
+func DeleteBranch(repo *models.Repository, gitRepo *git.Repository, branch string) error {
+	if err := gitRepo.DeleteBranch(branch, git.DeleteBranchOptions{
+		Force: true,
+	}); err != nil {
+		return err
+	}
+	cache.Remove(repo.GetBranchCommitIDCacheKey(branch))
+	return nil
+}
+
 // GetBranches returns all the branches of a repository
 func GetBranches(repo *models.Repository) ([]*git.Branch, error) {
 	return git.GetBranchesByPath(repo.RepoPath())
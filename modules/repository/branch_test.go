@@ -0,0 +1,46 @@
+// Copyright 2020 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"code.gitea.io/gitea/models"
+	"code.gitea.io/gitea/modules/cache"
+	"code.gitea.io/gitea/modules/git"
+	"code.gitea.io/gitea/modules/setting"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeleteBranch_DropsCachedCommit(t *testing.T) {
+	models.PrepareTestEnv(t)
+
+	setting.CacheService = &setting.Cache{
+		Adapter:         "memory",
+		Interval:        60,
+		TTL:             time.Hour,
+		BranchCommitTTL: time.Hour,
+	}
+	defer func() {
+		// the cache can not be closed again, bypass it for the other tests
+		setting.CacheService = &setting.Cache{}
+	}()
+	assert.NoError(t, cache.NewContext())
+
+	repo := models.AssertExistsAndLoadBean(t, &models.Repository{ID: 1}).(*models.Repository)
+	commitID, err := GetBranchCommitID(repo, "branch2")
+	assert.NoError(t, err)
+	assert.EqualValues(t, "985f0301dba5e7b34be866819cd15ad3d8f508ee", commitID)
+
+	gitRepo, err := git.OpenRepository(repo.RepoPath())
+	assert.NoError(t, err)
+	defer gitRepo.Close()
+	assert.NoError(t, DeleteBranch(repo, gitRepo, "branch2"))
+
+	_, err = GetBranchCommitID(repo, "branch2")
+	assert.True(t, git.IsErrBranchNotExist(err))
+}
@@ -20,6 +20,9 @@ type Cache struct {
 	Interval int
 	Conn     string
 	TTL      time.Duration
+
+	// BranchCommitTTL is the time a resolved branch commit is kept in cache
+	BranchCommitTTL time.Duration
 }
 
 var (
@@ -41,6 +44,7 @@ func newCacheService() {
 		log.Fatal("Unknown cache adapter: %s", CacheService.Adapter)
 	}
 	CacheService.TTL = sec.Key("ITEM_TTL").MustDuration(16 * time.Hour)
+	CacheService.BranchCommitTTL = sec.Key("BRANCH_COMMIT_TTL").MustDuration(time.Minute)
 
 	log.Info("Cache Service Enabled")
 }
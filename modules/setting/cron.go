@@ -25,6 +25,8 @@ var (
 			Schedule   string
 			Timeout    time.Duration
 			Args       []string `delim:" "`
+
+			DeleteOrphanedPullRequests bool
 		} `ini:"cron.repo_health_check"`
 		CheckRepoStats struct {
 			Enabled    bool
@@ -52,6 +54,13 @@ var (
 		UpdateMigrationPosterID struct {
 			Schedule string
 		} `ini:"cron.update_migration_poster_id"`
+		CloseStalePullRequests struct {
+			Enabled            bool
+			RunAtStart         bool
+			Schedule           string
+			OlderThan          time.Duration
+			DeleteHeadBranches bool
+		} `ini:"cron.close_stale_pull_requests"`
 	}{
 		UpdateMirror: struct {
 			Enabled    bool
@@ -68,6 +77,8 @@ var (
 			Schedule   string
 			Timeout    time.Duration
 			Args       []string `delim:" "`
+
+			DeleteOrphanedPullRequests bool
 		}{
 			Enabled:    true,
 			RunAtStart: false,
@@ -122,6 +133,19 @@ var (
 		}{
 			Schedule: "@every 24h",
 		},
+		CloseStalePullRequests: struct {
+			Enabled            bool
+			RunAtStart         bool
+			Schedule           string
+			OlderThan          time.Duration
+			DeleteHeadBranches bool
+		}{
+			Enabled:            false,
+			RunAtStart:         false,
+			Schedule:           "@every 24h",
+			OlderThan:          90 * 24 * time.Hour,
+			DeleteHeadBranches: true,
+		},
 	}
 )
 
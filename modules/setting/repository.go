@@ -8,6 +8,7 @@ import (
 	"path"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"code.gitea.io/gitea/modules/log"
 
@@ -70,6 +71,11 @@ var (
 			DefaultMergeMessageAllAuthors            bool
 			DefaultMergeMessageMaxApprovers          int
 			DefaultMergeMessageOfficialApproversOnly bool
+			MaxPatchSize                             int64
+			MaxPatchSeriesCommits                    int
+			MaxConflictedHunks                       int
+			PushRetryAttempts                        int
+			PushRetryBackoff                         time.Duration
 		} `ini:"repository.pull-request"`
 
 		// Issue Setting
@@ -144,6 +150,11 @@ var (
 			DefaultMergeMessageAllAuthors            bool
 			DefaultMergeMessageMaxApprovers          int
 			DefaultMergeMessageOfficialApproversOnly bool
+			MaxPatchSize                             int64
+			MaxPatchSeriesCommits                    int
+			MaxConflictedHunks                       int
+			PushRetryAttempts                        int
+			PushRetryBackoff                         time.Duration
 		}{
 			WorkInProgressPrefixes: []string{"WIP:", "[WIP]"},
 			// Same as GitHub. See
@@ -155,6 +166,11 @@ var (
 			DefaultMergeMessageAllAuthors:            false,
 			DefaultMergeMessageMaxApprovers:          10,
 			DefaultMergeMessageOfficialApproversOnly: true,
+			MaxPatchSize:                             0,
+			MaxPatchSeriesCommits:                    250,
+			MaxConflictedHunks:                       50,
+			PushRetryAttempts:                        3,
+			PushRetryBackoff:                         500 * time.Millisecond,
 		},
 
 		// Issue settings
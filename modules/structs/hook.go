@@ -241,6 +241,9 @@ type IssueCommentPayload struct {
 	Repository *Repository            `json:"repository"`
 	Sender     *User                  `json:"sender"`
 	IsPull     bool                   `json:"is_pull"`
+	// IsReview is true when the comment belongs to a pull request review thread
+	IsReview bool  `json:"is_review"`
+	ReviewID int64 `json:"review_id,omitempty"`
 }
 
 // SetSecret modifies the secret of the IssueCommentPayload
@@ -374,6 +377,16 @@ const (
 	HookIssueMilestoned HookIssueAction = "milestoned"
 	// HookIssueDemilestoned is an issue action for when a milestone is cleared on an issue.
 	HookIssueDemilestoned HookIssueAction = "demilestoned"
+	// HookIssuePinned is an issue action for when an issue is pinned.
+	HookIssuePinned HookIssueAction = "pinned"
+	// HookIssueUnpinned is an issue action for when an issue is unpinned.
+	HookIssueUnpinned HookIssueAction = "unpinned"
+	// HookIssueConvertedToDraft is a pull request action for when a pull request is marked as a work in progress.
+	HookIssueConvertedToDraft HookIssueAction = "converted_to_draft"
+	// HookIssueApprovalsSatisfied is a pull request action for when the approvals required by the protected base branch are granted.
+	HookIssueApprovalsSatisfied HookIssueAction = "approvals_satisfied"
+	// HookIssueMergeableChanged is a pull request action for when a conflict check changed whether the pull request can be merged.
+	HookIssueMergeableChanged HookIssueAction = "mergeable_changed"
 )
 
 // IssuePayload represents the payload information that is sent along with an issue event.
@@ -400,13 +413,15 @@ func (p *IssuePayload) JSONPayload() ([]byte, error) {
 // ChangesFromPayload FIXME
 type ChangesFromPayload struct {
 	From string `json:"from"`
+	To   string `json:"to,omitempty"`
 }
 
 // ChangesPayload represents the payload information of issue change
 type ChangesPayload struct {
-	Title *ChangesFromPayload `json:"title,omitempty"`
-	Body  *ChangesFromPayload `json:"body,omitempty"`
-	Ref   *ChangesFromPayload `json:"ref,omitempty"`
+	Title    *ChangesFromPayload `json:"title,omitempty"`
+	Body     *ChangesFromPayload `json:"body,omitempty"`
+	Ref      *ChangesFromPayload `json:"ref,omitempty"`
+	Deadline *ChangesFromPayload `json:"due_date,omitempty"`
 }
 
 // __________      .__  .__    __________                                     __
@@ -426,6 +441,15 @@ type PullRequestPayload struct {
 	Repository  *Repository     `json:"repository"`
 	Sender      *User           `json:"sender"`
 	Review      *ReviewPayload  `json:"review"`
+	Reviewers   []*User         `json:"reviewers,omitempty"`
+	Approvers   []*User         `json:"approvers,omitempty"`
+	// number of approvals required by the protected base branch
+	RequiredApprovals int64 `json:"required_approvals,omitempty"`
+	// set when the pull request was closed automatically rather than by a user
+	AutoClosed      bool   `json:"auto_closed,omitempty"`
+	AutoCloseReason string `json:"auto_close_reason,omitempty"`
+	// files conflicting with the base branch, set when the mergeability changed
+	ConflictedFiles []string `json:"conflicted_files,omitempty"`
 }
 
 // SetSecret modifies the secret of the PullRequestPayload.
@@ -479,3 +503,35 @@ func (p *RepositoryPayload) SetSecret(secret string) {
 func (p *RepositoryPayload) JSONPayload() ([]byte, error) {
 	return json.MarshalIndent(p, "", " ")
 }
+
+//   _________ __          __
+//  /   _____//  |______ _/  |_ __ __  ______
+//  \_____  \\   __\__  \\   __\  |  \/  ___/
+//  /        \|  |  / __ \|  | |  |  /\___ \
+// /_______  /|__| (____  /__| |____//____  >
+//         \/           \/                \/
+
+// StatusPayload represents a payload information of a commit status event.
+type StatusPayload struct {
+	Secret      string         `json:"secret"`
+	SHA         string         `json:"sha"`
+	State       StatusState    `json:"state"`
+	Context     string         `json:"context"`
+	Description string         `json:"description"`
+	TargetURL   string         `json:"target_url"`
+	Commit      *PayloadCommit `json:"commit"`
+	// index of the open pull request whose head is the commit, if any
+	PullRequestIndex int64       `json:"pull_request_index,omitempty"`
+	Repository       *Repository `json:"repository"`
+	Sender           *User       `json:"sender"`
+}
+
+// SetSecret modifies the secret of the StatusPayload
+func (p *StatusPayload) SetSecret(secret string) {
+	p.Secret = secret
+}
+
+// JSONPayload JSON representation of the payload
+func (p *StatusPayload) JSONPayload() ([]byte, error) {
+	return json.MarshalIndent(p, "", "  ")
+}
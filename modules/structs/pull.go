@@ -28,11 +28,18 @@ type PullRequest struct {
 	PatchURL string `json:"patch_url"`
 
 	Mergeable bool `json:"mergeable"`
-	HasMerged bool `json:"merged"`
+	// enum: clean,dirty,checking,blocked,behind,draft
+	MergeableState string `json:"mergeable_state,omitempty"`
+	HasMerged      bool   `json:"merged"`
 	// swagger:strfmt date-time
-	Merged         *time.Time `json:"merged_at"`
-	MergedCommitID *string    `json:"merge_commit_sha"`
-	MergedBy       *User      `json:"merged_by"`
+	Merged          *time.Time `json:"merged_at"`
+	MergedCommitID  *string    `json:"merge_commit_sha"`
+	MergedBy        *User      `json:"merged_by"`
+	MergedReviewers []int64    `json:"merged_reviewers"`
+	// commit reverting the merged pull request
+	RevertedByCommitID string `json:"reverted_by_commit_sha,omitempty"`
+	// number of the pull request which merged the reverting commit
+	RevertedByPullIndex int64 `json:"reverted_by_pull_number,omitempty"`
 
 	Base      *PRBranchInfo `json:"base"`
 	Head      *PRBranchInfo `json:"head"`
@@ -49,6 +56,22 @@ type PullRequest struct {
 	Closed *time.Time `json:"closed_at"`
 }
 
+// Mergeable states of an open pull request
+const (
+	// MergeableStateClean the pull request can be merged
+	MergeableStateClean = "clean"
+	// MergeableStateDirty the pull request has conflicts or could not be checked
+	MergeableStateDirty = "dirty"
+	// MergeableStateChecking the pull request is being checked for conflicts
+	MergeableStateChecking = "checking"
+	// MergeableStateBlocked merging is blocked by missing approvals, rejected reviews or failing status checks
+	MergeableStateBlocked = "blocked"
+	// MergeableStateBehind the base branch has commits the head branch does not contain
+	MergeableStateBehind = "behind"
+	// MergeableStateDraft the pull request is a work in progress
+	MergeableStateDraft = "draft"
+)
+
 // PRBranchInfo information about a branch
 type PRBranchInfo struct {
 	Name       string      `json:"label"`
@@ -56,6 +79,32 @@ type PRBranchInfo struct {
 	Sha        string      `json:"sha"`
 	RepoID     int64       `json:"repo_id"`
 	Repository *Repository `json:"repo"`
+	// whether the branch still exists
+	Exists bool `json:"exists"`
+	// number of commits on the branch since the merge base of the pull request
+	CommitsCount int `json:"commits_count"`
+}
+
+// PullRequestTimelineEntry represents an event in the history of a pull request
+type PullRequestTimelineEntry struct {
+	// one of commit, review, status or merged
+	Type string `json:"type"`
+	// swagger:strfmt date-time
+	Created time.Time                  `json:"created_at"`
+	Commit  *PayloadCommit             `json:"commit,omitempty"`
+	Review  *PullRequestTimelineReview `json:"review,omitempty"`
+	Status  *Status                    `json:"status,omitempty"`
+}
+
+// PullRequestTimelineReview represents a submitted review of a pull request
+type PullRequestTimelineReview struct {
+	ID       int64 `json:"id"`
+	Reviewer *User `json:"reviewer"`
+	// one of APPROVED, REQUEST_CHANGES or COMMENT
+	State string `json:"state"`
+	Body  string `json:"body"`
+	// whether the review was submitted for an older head of the pull request
+	Stale bool `json:"stale"`
 }
 
 // ListPullRequestsOptions options for listing pull requests
@@ -68,6 +68,7 @@ type Repository struct {
 	Watchers      int         `json:"watchers_count"`
 	OpenIssues    int         `json:"open_issues_count"`
 	OpenPulls     int         `json:"open_pr_counter"`
+	MergedPulls   int         `json:"merged_pr_counter"`
 	Releases      int         `json:"release_counter"`
 	DefaultBranch string      `json:"default_branch"`
 	Archived      bool        `json:"archived"`
@@ -87,6 +88,7 @@ type Repository struct {
 	AllowRebase               bool             `json:"allow_rebase"`
 	AllowRebaseMerge          bool             `json:"allow_rebase_explicit"`
 	AllowSquash               bool             `json:"allow_squash_merge"`
+	AllowFastForwardOnly      bool             `json:"allow_fast_forward_only_merge"`
 	AvatarURL                 string           `json:"avatar_url"`
 }
 
@@ -154,6 +156,8 @@ type EditRepoOption struct {
 	AllowRebaseMerge *bool `json:"allow_rebase_explicit,omitempty"`
 	// either `true` to allow squash-merging pull requests, or `false` to prevent squash-merging. `has_pull_requests` must be `true`.
 	AllowSquash *bool `json:"allow_squash_merge,omitempty"`
+	// either `true` to allow fast-forwarding the base branch to the head of pull requests without a merge commit, or `false` to prevent it. `has_pull_requests` must be `true`.
+	AllowFastForwardOnly *bool `json:"allow_fast_forward_only_merge,omitempty"`
 	// set to `true` to archive this repository.
 	Archived *bool `json:"archived,omitempty"`
 }
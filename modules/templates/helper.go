@@ -94,6 +94,7 @@ func NewFuncMap() []template.FuncMap {
 		"RawTimeSince":  timeutil.RawTimeSince,
 		"FileSize":      base.FileSize,
 		"Subtract":      base.Subtract,
+		"Join":          strings.Join,
 		"EntryIcon":     base.EntryIcon,
 		"MigrationIcon": MigrationIcon,
 		"Add": func(a, b int) int {
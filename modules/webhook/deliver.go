@@ -6,7 +6,10 @@ package webhook
 
 import (
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
 	"crypto/tls"
+	"encoding/hex"
 	"fmt"
 	"io/ioutil"
 	"net"
@@ -24,6 +27,22 @@ import (
 	"github.com/unknwon/com"
 )
 
+// signPayload returns the hex encoded HMAC-SHA256 of content keyed with secret
+func signPayload(secret string, content []byte) string {
+	sig := hmac.New(sha256.New, []byte(secret))
+	_, _ = sig.Write(content)
+	return hex.EncodeToString(sig.Sum(nil))
+}
+
+// requestBody returns the raw body of the request delivering payload, which is
+// the payload itself for requests without a body.
+func requestBody(httpMethod string, contentType models.HookContentType, payload string) string {
+	if httpMethod == http.MethodGet || contentType != models.ContentTypeForm {
+		return payload
+	}
+	return url.Values{"payload": []string{payload}}.Encode()
+}
+
 // Deliver deliver hook task
 func Deliver(t *models.HookTask) error {
 	t.IsDelivered = true
@@ -45,11 +64,7 @@ func Deliver(t *models.HookTask) error {
 
 			req.Header.Set("Content-Type", "application/json")
 		case models.ContentTypeForm:
-			var forms = url.Values{
-				"payload": []string{t.PayloadContent},
-			}
-
-			req, err = http.NewRequest("POST", t.URL, strings.NewReader(forms.Encode()))
+			req, err = http.NewRequest("POST", t.URL, strings.NewReader(requestBody(http.MethodPost, t.ContentType, t.PayloadContent)))
 			if err != nil {
 
 				return err
@@ -81,6 +96,9 @@ func Deliver(t *models.HookTask) error {
 	req.Header.Add("X-Gogs-Signature", t.Signature)
 	req.Header["X-GitHub-Delivery"] = []string{t.UUID}
 	req.Header["X-GitHub-Event"] = []string{string(t.EventType)}
+	if len(t.BodySignature) > 0 {
+		req.Header["X-Hub-Signature-256"] = []string{"sha256=" + t.BodySignature}
+	}
 
 	// Record delivery information.
 	t.RequestInfo = &models.HookRequest{
@@ -194,7 +212,8 @@ func DeliverHooks(ctx context.Context) {
 				log.Error("Get repository [%d] hook tasks: %v", repoID, err)
 				continue
 			}
-			for _, t := range tasks {
+			// Tasks of webhooks with a pending batch are delivered once their window expired
+			for _, t := range dueHookTasks(repoID, tasks) {
 				select {
 				case <-ctx.Done():
 					return
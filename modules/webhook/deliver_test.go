@@ -5,11 +5,16 @@
 package webhook
 
 import (
+	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"testing"
 
+	"code.gitea.io/gitea/models"
 	"code.gitea.io/gitea/modules/setting"
+	api "code.gitea.io/gitea/modules/structs"
+
 	"github.com/stretchr/testify/assert"
 )
 
@@ -37,3 +42,43 @@ func TestWebhookProxy(t *testing.T) {
 		}
 	}
 }
+
+func TestSignPayload(t *testing.T) {
+	assert.EqualValues(t, "f7bc83f430538424b13298e6aa6fb143ef4d59a14946175997479dbc2d1a3cd8",
+		signPayload("key", []byte("The quick brown fox jumps over the lazy dog")))
+}
+
+func TestDeliverSignsRawBody(t *testing.T) {
+	var body []byte
+	var header http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		header = req.Header
+		body, _ = ioutil.ReadAll(req.Body)
+	}))
+	defer server.Close()
+	webhookHTTPClient = server.Client()
+
+	for _, contentType := range []models.HookContentType{models.ContentTypeJSON, models.ContentTypeForm} {
+		assert.NoError(t, models.PrepareTestDatabase())
+		repo := models.AssertExistsAndLoadBean(t, &models.Repository{ID: 1}).(*models.Repository)
+		w := models.AssertExistsAndLoadBean(t, &models.Webhook{ID: 1}).(*models.Webhook)
+		w.URL = server.URL
+		w.Secret = "secret"
+		w.ContentType = contentType
+		assert.NoError(t, models.UpdateWebhook(w))
+
+		assert.NoError(t, prepareWebhook(w, repo, models.HookEventPush, &api.PushPayload{Ref: "refs/heads/master"}))
+		tasks, err := models.FindRepoUndeliveredHookTasks(repo.ID)
+		assert.NoError(t, err)
+		if !assert.Len(t, tasks, 1) {
+			continue
+		}
+		assert.NoError(t, Deliver(tasks[0]))
+
+		// the existing signatures keep covering the JSON payload
+		payloadSignature := signPayload("secret", []byte(tasks[0].PayloadContent))
+		assert.EqualValues(t, payloadSignature, header.Get("X-Gitea-Signature"))
+		assert.EqualValues(t, payloadSignature, header.Get("X-Gogs-Signature"))
+		assert.EqualValues(t, "sha256="+signPayload("secret", body), header.Get("X-Hub-Signature-256"))
+	}
+}
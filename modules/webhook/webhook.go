@@ -5,11 +5,10 @@
 package webhook
 
 import (
-	"crypto/hmac"
-	"crypto/sha256"
-	"encoding/hex"
 	"fmt"
 	"strings"
+	gosync "sync"
+	"time"
 
 	"code.gitea.io/gitea/models"
 	"code.gitea.io/gitea/modules/git"
@@ -23,6 +22,63 @@ import (
 // hookQueue is a global queue of web hooks
 var hookQueue = sync.NewUniqueQueue(setting.Webhook.QueueLength)
 
+// batchKey identifies the batched deliveries of a webhook to a repository,
+// as organization webhooks are delivered for several repositories.
+type batchKey struct {
+	repoID int64
+	hookID int64
+}
+
+var (
+	// batchTimers holds the pending delayed deliveries per repository and webhook
+	batchTimers     = make(map[batchKey]*time.Timer)
+	batchTimersLock gosync.Mutex
+)
+
+// queueRepoHooks queues the delivery of the hook tasks the webhooks ws just got for a
+// repository. Webhooks without a batching window are delivered right away. For the others
+// the delivery is delayed by their own window, and all hook tasks of the webhook for the
+// repository created until it expires are delivered together.
+func queueRepoHooks(repoID int64, ws []*models.Webhook) {
+	immediate := false
+	batchTimersLock.Lock()
+	for _, w := range ws {
+		if w.BatchWindow <= 0 {
+			immediate = true
+			continue
+		}
+		key := batchKey{repoID: repoID, hookID: w.ID}
+		if _, ok := batchTimers[key]; ok {
+			continue
+		}
+		batchTimers[key] = time.AfterFunc(time.Duration(w.BatchWindow)*time.Second, func() {
+			batchTimersLock.Lock()
+			delete(batchTimers, key)
+			batchTimersLock.Unlock()
+			hookQueue.Add(repoID)
+		})
+	}
+	batchTimersLock.Unlock()
+
+	if immediate {
+		go hookQueue.Add(repoID)
+	}
+}
+
+// dueHookTasks returns the hook tasks of a repository which are not held back
+// by a pending batched delivery of their webhook.
+func dueHookTasks(repoID int64, tasks []*models.HookTask) []*models.HookTask {
+	batchTimersLock.Lock()
+	defer batchTimersLock.Unlock()
+	due := make([]*models.HookTask, 0, len(tasks))
+	for _, t := range tasks {
+		if _, ok := batchTimers[batchKey{repoID: repoID, hookID: t.HookID}]; !ok {
+			due = append(due, t)
+		}
+	}
+	return due
+}
+
 // getPayloadBranch returns branch for hook event, if applicable.
 func getPayloadBranch(p api.Payloader) string {
 	switch pp := p.(type) {
@@ -85,6 +141,11 @@ func prepareWebhook(w *models.Webhook, repo *models.Repository, event models.Hoo
 		}
 	}
 
+	// Commit statuses are only delivered to Gitea and Gogs hooks, chat services have no message for them.
+	if event == models.HookEventStatus && w.HookTaskType != models.GITEA && w.HookTaskType != models.GOGS {
+		return nil
+	}
+
 	var payloader api.Payloader
 	var err error
 	// Use separate objects so modifications won't be made on payload on non-Gogs/Gitea type hooks.
@@ -119,31 +180,29 @@ func prepareWebhook(w *models.Webhook, repo *models.Repository, event models.Hoo
 		payloader = p
 	}
 
-	var signature string
+	// The existing signature covers the JSON payload, the body signature the raw request body
+	var signature, bodySignature string
 	if len(w.Secret) > 0 {
 		data, err := payloader.JSONPayload()
 		if err != nil {
 			log.Error("prepareWebhooks.JSONPayload: %v", err)
 		}
-		sig := hmac.New(sha256.New, []byte(w.Secret))
-		_, err = sig.Write(data)
-		if err != nil {
-			log.Error("prepareWebhooks.sigWrite: %v", err)
-		}
-		signature = hex.EncodeToString(sig.Sum(nil))
+		signature = signPayload(w.Secret, data)
+		bodySignature = signPayload(w.Secret, []byte(requestBody(w.HTTPMethod, w.ContentType, string(data))))
 	}
 
 	if err = models.CreateHookTask(&models.HookTask{
-		RepoID:      repo.ID,
-		HookID:      w.ID,
-		Type:        w.HookTaskType,
-		URL:         w.URL,
-		Signature:   signature,
-		Payloader:   payloader,
-		HTTPMethod:  w.HTTPMethod,
-		ContentType: w.ContentType,
-		EventType:   event,
-		IsSSL:       w.IsSSL,
+		RepoID:        repo.ID,
+		HookID:        w.ID,
+		Type:          w.HookTaskType,
+		URL:           w.URL,
+		Signature:     signature,
+		BodySignature: bodySignature,
+		Payloader:     payloader,
+		HTTPMethod:    w.HTTPMethod,
+		ContentType:   w.ContentType,
+		EventType:     event,
+		IsSSL:         w.IsSSL,
 	}); err != nil {
 		return fmt.Errorf("CreateHookTask: %v", err)
 	}
@@ -152,18 +211,21 @@ func prepareWebhook(w *models.Webhook, repo *models.Repository, event models.Hoo
 
 // PrepareWebhooks adds new webhooks to task queue for given payload.
 func PrepareWebhooks(repo *models.Repository, event models.HookEventType, p api.Payloader) error {
-	if err := prepareWebhooks(repo, event, p); err != nil {
+	ws, err := prepareWebhooks(repo, event, p)
+	if err != nil {
 		return err
 	}
 
-	go hookQueue.Add(repo.ID)
+	queueRepoHooks(repo.ID, ws)
 	return nil
 }
 
-func prepareWebhooks(repo *models.Repository, event models.HookEventType, p api.Payloader) error {
+// prepareWebhooks creates the hook tasks of all active webhooks of the repository
+// and returns these webhooks.
+func prepareWebhooks(repo *models.Repository, event models.HookEventType, p api.Payloader) ([]*models.Webhook, error) {
 	ws, err := models.GetActiveWebhooksByRepoID(repo.ID)
 	if err != nil {
-		return fmt.Errorf("GetActiveWebhooksByRepoID: %v", err)
+		return nil, fmt.Errorf("GetActiveWebhooksByRepoID: %v", err)
 	}
 
 	// check if repo belongs to org and append additional webhooks
@@ -171,19 +233,15 @@ func prepareWebhooks(repo *models.Repository, event models.HookEventType, p api.
 		// get hooks for org
 		orgHooks, err := models.GetActiveWebhooksByOrgID(repo.OwnerID)
 		if err != nil {
-			return fmt.Errorf("GetActiveWebhooksByOrgID: %v", err)
+			return nil, fmt.Errorf("GetActiveWebhooksByOrgID: %v", err)
 		}
 		ws = append(ws, orgHooks...)
 	}
 
-	if len(ws) == 0 {
-		return nil
-	}
-
 	for _, w := range ws {
 		if err = prepareWebhook(w, repo, event, p); err != nil {
-			return err
+			return nil, err
 		}
 	}
-	return nil
+	return ws, nil
 }
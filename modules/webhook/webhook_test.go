@@ -6,6 +6,7 @@ package webhook
 
 import (
 	"testing"
+	"time"
 
 	"code.gitea.io/gitea/models"
 	api "code.gitea.io/gitea/modules/structs"
@@ -77,3 +78,55 @@ func TestPrepareWebhooksBranchFilterNoMatch(t *testing.T) {
 // TODO TestHookTask_deliver
 
 // TODO TestDeliverHooks
+
+func TestQueueRepoHooksBatchWindow(t *testing.T) {
+	ws := []*models.Webhook{
+		{ID: 1, HookEvent: &models.HookEvent{BatchWindow: 0}},
+		{ID: 2, HookEvent: &models.HookEvent{BatchWindow: 3600}},
+		{ID: 3, HookEvent: &models.HookEvent{BatchWindow: 5}},
+	}
+	// other tests queue the deliveries of their repositories as well
+	waitForDelivery := func(timeout time.Duration) {
+		for {
+			select {
+			case id := <-hookQueue.Queue():
+				hookQueue.Remove(id)
+				if id == "42" {
+					return
+				}
+			case <-time.After(timeout):
+				assert.Fail(t, "Timeout: the delivery was not queued")
+				return
+			}
+		}
+	}
+	queueRepoHooks(42, ws)
+
+	// the webhook without a window is delivered right away
+	waitForDelivery(time.Second)
+	tasks := []*models.HookTask{{ID: 1, HookID: 1}, {ID: 2, HookID: 2}, {ID: 3, HookID: 3}}
+	assert.Equal(t, []*models.HookTask{tasks[0]}, dueHookTasks(42, tasks))
+	// batches are kept per repository
+	assert.Equal(t, tasks, dueHookTasks(43, tasks))
+
+	// each batched webhook has its own window
+	batchTimersLock.Lock()
+	timer := batchTimers[batchKey{repoID: 42, hookID: 2}]
+	assert.NotNil(t, timer)
+	assert.NotNil(t, batchTimers[batchKey{repoID: 42, hookID: 3}])
+	assert.Len(t, batchTimers, 2)
+	batchTimersLock.Unlock()
+
+	// further events within the window join the pending delivery
+	queueRepoHooks(42, ws[1:2])
+	batchTimersLock.Lock()
+	assert.True(t, timer == batchTimers[batchKey{repoID: 42, hookID: 2}])
+	assert.Len(t, batchTimers, 2)
+	timer.Stop()
+	delete(batchTimers, batchKey{repoID: 42, hookID: 2})
+	batchTimersLock.Unlock()
+
+	// the shorter window expires on its own
+	waitForDelivery(10 * time.Second)
+	assert.Equal(t, tasks, dueHookTasks(42, tasks))
+}
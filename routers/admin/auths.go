@@ -8,6 +8,8 @@ import (
 	"errors"
 	"fmt"
 	"regexp"
+	"strings"
+	"time"
 
 	"code.gitea.io/gitea/models"
 	"code.gitea.io/gitea/modules/auth"
@@ -84,6 +86,7 @@ func NewAuthSource(ctx *context.Context) {
 	ctx.Data["smtp_auth"] = "PLAIN"
 	ctx.Data["is_active"] = true
 	ctx.Data["is_sync_enabled"] = true
+	ctx.Data["pool_size"] = 1
 	ctx.Data["AuthSources"] = authSources
 	ctx.Data["SecurityProtocols"] = securityProtocols
 	ctx.Data["SMTPAuths"] = models.SMTPAuths
@@ -112,29 +115,57 @@ func parseLDAPConfig(form auth.AuthenticationForm) *models.LDAPConfig {
 	}
 	return &models.LDAPConfig{
 		Source: &ldap.Source{
-			Name:                  form.Name,
-			Host:                  form.Host,
-			Port:                  form.Port,
-			SecurityProtocol:      ldap.SecurityProtocol(form.SecurityProtocol),
-			SkipVerify:            form.SkipVerify,
-			BindDN:                form.BindDN,
-			UserDN:                form.UserDN,
-			BindPassword:          form.BindPassword,
-			UserBase:              form.UserBase,
-			AttributeUsername:     form.AttributeUsername,
-			AttributeName:         form.AttributeName,
-			AttributeSurname:      form.AttributeSurname,
-			AttributeMail:         form.AttributeMail,
-			AttributesInBind:      form.AttributesInBind,
-			AttributeSSHPublicKey: form.AttributeSSHPublicKey,
-			SearchPageSize:        pageSize,
-			Filter:                form.Filter,
-			AdminFilter:           form.AdminFilter,
-			Enabled:               true,
+			Name:                   form.Name,
+			Host:                   form.Host,
+			FailoverHosts:          splitLines(form.FailoverHosts),
+			Port:                   form.Port,
+			Timeout:                form.Timeout,
+			SecurityProtocol:       ldap.SecurityProtocol(form.SecurityProtocol),
+			SkipVerify:             form.SkipVerify,
+			BindDN:                 form.BindDN,
+			UserDN:                 form.UserDN,
+			BindPassword:           form.BindPassword,
+			UserBase:               form.UserBase,
+			UserBases:              splitLines(form.UserBases),
+			AttributeUsername:      form.AttributeUsername,
+			AttributeName:          form.AttributeName,
+			AttributeSurname:       form.AttributeSurname,
+			AttributeMail:          form.AttributeMail,
+			AttributesInBind:       form.AttributesInBind,
+			AttributeSSHPublicKey:  form.AttributeSSHPublicKey,
+			AttributeLanguage:      form.AttributeLanguage,
+			AttributeExternalID:    form.AttributeExternalID,
+			AttributeChanged:       form.AttributeChanged,
+			FullSyncInterval:       form.FullSyncInterval,
+			SearchPageSize:         pageSize,
+			SortResults:            form.SortResults,
+			PoolSize:               form.PoolSize,
+			Filter:                 form.Filter,
+			AdminFilter:            form.AdminFilter,
+			AdminFilters:           splitLines(form.AdminFilters),
+			GroupAttributeName:     form.GroupAttributeName,
+			SearchRetries:          form.SearchRetries,
+			SearchCacheTTL:         time.Duration(form.SearchCacheTTL) * time.Second,
+			MemberGroupFilter:      form.MemberGroupFilter,
+			RequireGroupMembership: form.RequireGroupMembership,
+			NestedGroupSearch:      form.NestedGroupSearch,
+			NestedGroupMaxDepth:    form.NestedGroupMaxDepth,
+			Enabled:                true,
 		},
 	}
 }
 
+// splitLines splits a list given one entry per line, e.g. the additional admin filters.
+func splitLines(list string) []string {
+	var result []string
+	for _, entry := range strings.Split(list, "\n") {
+		if entry = strings.TrimSpace(entry); len(entry) > 0 {
+			result = append(result, entry)
+		}
+	}
+	return result
+}
+
 func parseSMTPConfig(form auth.AuthenticationForm) *models.SMTPConfig {
 	return &models.SMTPConfig{
 		Auth:           form.SMTPAuth,
@@ -362,6 +393,26 @@ func EditAuthSourcePost(ctx *context.Context, form auth.AuthenticationForm) {
 	ctx.Redirect(setting.AppSubURL + "/admin/auths/" + com.ToStr(form.ID))
 }
 
+// TestAuthSource checks the connection to an LDAP auth source
+func TestAuthSource(ctx *context.Context) {
+	source, err := models.GetLoginSourceByID(ctx.ParamsInt64(":authid"))
+	if err != nil {
+		ctx.ServerError("GetLoginSourceByID", err)
+		return
+	}
+	if !source.IsLDAP() && !source.IsDLDAP() {
+		ctx.Error(400)
+		return
+	}
+
+	if err = source.LDAP().Source.Ping(); err != nil {
+		ctx.Flash.Error(ctx.Tr("admin.auths.test_connection_failed", err.Error()))
+	} else {
+		ctx.Flash.Success(ctx.Tr("admin.auths.test_connection_success"))
+	}
+	ctx.Redirect(setting.AppSubURL + "/admin/auths/" + com.ToStr(source.ID))
+}
+
 // DeleteAuthSource response for deleting an auth source
 func DeleteAuthSource(ctx *context.Context) {
 	source, err := models.GetLoginSourceByID(ctx.ParamsInt64(":authid"))
@@ -0,0 +1,61 @@
+// Copyright 2020 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package admin
+
+import (
+	"net"
+	"net/http"
+	"testing"
+
+	"code.gitea.io/gitea/models"
+	"code.gitea.io/gitea/modules/auth/ldap"
+	"code.gitea.io/gitea/modules/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/unknwon/com"
+)
+
+func TestTestAuthSource(t *testing.T) {
+	models.PrepareTestEnv(t)
+
+	// nothing listens on the port of a closed listener
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+
+	source := &models.LoginSource{
+		Type:      models.LoginLDAP,
+		Name:      "ldap",
+		IsActived: true,
+		Cfg: &models.LDAPConfig{Source: &ldap.Source{
+			Host:             "127.0.0.1",
+			Port:             port,
+			SecurityProtocol: ldap.SecurityProtocolUnencrypted,
+			Timeout:          1,
+		}},
+	}
+	assert.NoError(t, models.CreateLoginSource(source))
+
+	ctx := test.MockContext(t, "admin/auths/1/test")
+	ctx.SetParams(":authid", com.ToStr(source.ID))
+	TestAuthSource(ctx)
+	assert.NotEmpty(t, ctx.Flash.ErrorMsg)
+	assert.Empty(t, ctx.Flash.SuccessMsg)
+	assert.EqualValues(t, "/admin/auths/"+com.ToStr(source.ID), test.RedirectURL(ctx.Resp))
+
+	// only LDAP sources can be tested
+	source = &models.LoginSource{
+		Type: models.LoginPAM,
+		Name: "pam",
+		Cfg:  &models.PAMConfig{ServiceName: "gitea"},
+	}
+	assert.NoError(t, models.CreateLoginSource(source))
+
+	ctx = test.MockContext(t, "admin/auths/2/test")
+	ctx.SetParams(":authid", com.ToStr(source.ID))
+	TestAuthSource(ctx)
+	assert.EqualValues(t, http.StatusBadRequest, ctx.Resp.Status())
+}
@@ -20,6 +20,7 @@ import (
 	"code.gitea.io/gitea/modules/timeutil"
 	"code.gitea.io/gitea/modules/util"
 	issue_service "code.gitea.io/gitea/services/issue"
+	pull_service "code.gitea.io/gitea/services/pull"
 )
 
 // SearchIssues searches for issues across the repositories that the user has access to
@@ -489,11 +490,10 @@ func EditIssue(ctx *context.APIContext, form api.EditIssueOption) {
 			deadlineUnix = timeutil.TimeStamp(deadline.Unix())
 		}
 
-		if err := models.UpdateIssueDeadline(issue, deadlineUnix, ctx.User); err != nil {
+		if err := issue_service.ChangeDeadline(issue, ctx.User, deadlineUnix); err != nil {
 			ctx.Error(http.StatusInternalServerError, "UpdateIssueDeadline", err)
 			return
 		}
-		issue.DeadlineUnix = deadlineUnix
 	}
 
 	// Add/delete assignees
@@ -532,7 +532,13 @@ func EditIssue(ctx *context.APIContext, form api.EditIssueOption) {
 		return
 	}
 	if form.State != nil {
-		if err = issue_service.ChangeStatus(issue, ctx.User, api.StateClosed == api.StateType(*form.State)); err != nil {
+		isClosed := api.StateClosed == api.StateType(*form.State)
+		if issue.IsPull {
+			err = pull_service.ChangeStatus(issue, ctx.User, isClosed)
+		} else {
+			err = issue_service.ChangeStatus(issue, ctx.User, isClosed)
+		}
+		if err != nil {
 			if models.IsErrDependenciesLeft(err) {
 				ctx.Error(http.StatusPreconditionFailed, "DependenciesLeft", "cannot close this issue because it still has open dependencies")
 				return
@@ -616,7 +622,7 @@ func UpdateIssueDeadline(ctx *context.APIContext, form api.EditDeadlineOption) {
 		deadlineUnix = timeutil.TimeStamp(deadline.Unix())
 	}
 
-	if err := models.UpdateIssueDeadline(issue, deadlineUnix, ctx.User); err != nil {
+	if err := issue_service.ChangeDeadline(issue, ctx.User, deadlineUnix); err != nil {
 		ctx.Error(http.StatusInternalServerError, "UpdateIssueDeadline", err)
 		return
 	}
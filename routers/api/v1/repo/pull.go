@@ -314,6 +314,9 @@ func CreatePullRequest(ctx *context.APIContext, form api.CreatePullRequestOption
 		if models.IsErrUserDoesNotHaveAccessToRepo(err) {
 			ctx.Error(http.StatusBadRequest, "UserDoesNotHaveAccessToRepo", err)
 			return
+		} else if models.IsErrPullRequestSameRef(err) {
+			ctx.Error(http.StatusUnprocessableEntity, "PullRequestSameRef", err)
+			return
 		}
 		ctx.Error(http.StatusInternalServerError, "NewPullRequest", err)
 		return
@@ -388,6 +391,7 @@ func EditPullRequest(ctx *context.APIContext, form api.EditPullRequestOption) {
 		return
 	}
 
+	oldTitle := issue.Title
 	if len(form.Title) > 0 {
 		issue.Title = form.Title
 	}
@@ -404,11 +408,10 @@ func EditPullRequest(ctx *context.APIContext, form api.EditPullRequestOption) {
 			deadlineUnix = timeutil.TimeStamp(deadline.Unix())
 		}
 
-		if err := models.UpdateIssueDeadline(issue, deadlineUnix, ctx.User); err != nil {
+		if err := issue_service.ChangeDeadline(issue, ctx.User, deadlineUnix); err != nil {
 			ctx.Error(http.StatusInternalServerError, "UpdateIssueDeadline", err)
 			return
 		}
-		issue.DeadlineUnix = deadlineUnix
 	}
 
 	// Add/delete assignees
@@ -457,8 +460,12 @@ func EditPullRequest(ctx *context.APIContext, form api.EditPullRequestOption) {
 		ctx.Error(http.StatusInternalServerError, "UpdateIssueByAPI", err)
 		return
 	}
+	if !ctx.Repo.Repository.HasWorkInProgressPrefix(oldTitle) && ctx.Repo.Repository.HasWorkInProgressPrefix(issue.Title) {
+		notification.NotifyPullRequestConvertToDraft(ctx.User, pr)
+	}
 	if form.State != nil {
-		if err = issue_service.ChangeStatus(issue, ctx.User, api.StateClosed == api.StateType(*form.State)); err != nil {
+		isClosed := api.StateClosed == api.StateType(*form.State)
+		if err = pull_service.ChangeStatus(issue, ctx.User, isClosed); err != nil {
 			if models.IsErrDependenciesLeft(err) {
 				ctx.Error(http.StatusPreconditionFailed, "DependenciesLeft", "cannot close this pull request because it still has open dependencies")
 				return
@@ -529,6 +536,54 @@ func IsPullRequestMerged(ctx *context.APIContext) {
 	ctx.NotFound()
 }
 
+// GetPullRequestTimeline lists the commits, reviews, commit statuses and the merge of a pull request
+func GetPullRequestTimeline(ctx *context.APIContext) {
+	// swagger:operation GET /repos/{owner}/{repo}/pulls/{index}/timeline repository repoGetPullRequestTimeline
+	// ---
+	// summary: Get the timeline of a pull request, ordered by time
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: owner
+	//   in: path
+	//   description: owner of the repo
+	//   type: string
+	//   required: true
+	// - name: repo
+	//   in: path
+	//   description: name of the repo
+	//   type: string
+	//   required: true
+	// - name: index
+	//   in: path
+	//   description: index of the pull request
+	//   type: integer
+	//   format: int64
+	//   required: true
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/PullRequestTimeline"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+
+	pr, err := models.GetPullRequestByIndex(ctx.Repo.Repository.ID, ctx.ParamsInt64(":index"))
+	if err != nil {
+		if models.IsErrPullRequestNotExist(err) {
+			ctx.NotFound()
+		} else {
+			ctx.Error(http.StatusInternalServerError, "GetPullRequestByIndex", err)
+		}
+		return
+	}
+
+	entries, err := pr.GetTimeline()
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, "GetTimeline", err)
+		return
+	}
+	ctx.JSON(http.StatusOK, convert.ToPullRequestTimeline(ctx.Repo.Repository, entries))
+}
+
 // MergePullRequest merges a PR given an index
 func MergePullRequest(ctx *context.APIContext, form auth.MergePullRequestForm) {
 	// swagger:operation POST /repos/{owner}/{repo}/pulls/{index}/merge repository repoMergePullRequest
@@ -564,6 +619,8 @@ func MergePullRequest(ctx *context.APIContext, form auth.MergePullRequestForm) {
 	//     "$ref": "#/responses/empty"
 	//   "409":
 	//     "$ref": "#/responses/error"
+	//   "422":
+	//     "$ref": "#/responses/validationError"
 
 	pr, err := models.GetPullRequestByIndex(ctx.Repo.Repository.ID, ctx.ParamsInt64(":index"))
 	if err != nil {
@@ -600,22 +657,44 @@ func MergePullRequest(ctx *context.APIContext, form auth.MergePullRequestForm) {
 		return
 	}
 
-	allowedMerge, err := pull_service.IsUserAllowedToMerge(pr, ctx.Repo.Permission, ctx.User)
-	if err != nil {
-		ctx.Error(http.StatusInternalServerError, "IsUSerAllowedToMerge", err)
-		return
+	// All merge checks are run against the branch the pull request is merged into, using a
+	// copy aimed at it if that is not the recorded base branch
+	target := pr
+	targetBranch := strings.TrimSpace(form.TargetBranch)
+	retargeted := len(targetBranch) > 0 && targetBranch != pr.BaseBranch
+	if retargeted {
+		if form.ResolvedFiles != nil {
+			ctx.Error(http.StatusUnprocessableEntity, "Merge", "resolved_files can not be combined with another target_branch")
+			return
+		}
+		if !ctx.Repo.GitRepo.IsBranchExist(targetBranch) {
+			ctx.Error(http.StatusUnprocessableEntity, "MergeInto", git.ErrBranchNotExist{Name: targetBranch})
+			return
+		}
+		target = pull_service.CopyForTarget(pr, targetBranch)
 	}
-	if !allowedMerge {
-		ctx.Error(http.StatusMethodNotAllowed, "Merge", "User not allowed to merge PR")
+
+	if err := pull_service.CheckUserAllowedToMerge(target, ctx.Repo.Permission, ctx.User); err != nil {
+		if models.IsErrNotAllowedToMerge(err) {
+			ctx.Error(http.StatusMethodNotAllowed, "Merge", "User not allowed to merge PR")
+		} else if models.IsErrUnsignedCommits(err) || models.IsErrMissingSignoff(err) ||
+			models.IsErrBinaryFilesChanged(err) || models.IsErrPatchTooLarge(err) || models.IsErrUnresolvedConversations(err) {
+			ctx.Error(http.StatusMethodNotAllowed, "Merge", err)
+		} else {
+			ctx.Error(http.StatusInternalServerError, "CheckUserAllowedToMerge", err)
+		}
 		return
 	}
 
-	if !pr.CanAutoMerge() || pr.HasMerged || pr.IsWorkInProgress() {
+	// A conflicting pull request can be merged if the conflicts are resolved, MergeInto tests
+	// for conflicts against another target branch itself
+	resolving := form.ResolvedFiles != nil && pr.Status == models.PullRequestStatusConflict
+	if (!pr.CanAutoMerge() && !resolving && !retargeted) || pr.HasMerged || pr.IsWorkInProgress() {
 		ctx.Status(http.StatusMethodNotAllowed)
 		return
 	}
 
-	if err := pull_service.CheckPRReadyToMerge(pr); err != nil {
+	if err := pull_service.CheckPRReadyToMerge(target); err != nil {
 		if !models.IsErrNotAllowedToMerge(err) {
 			ctx.Error(http.StatusInternalServerError, "CheckPRReadyToMerge", err)
 			return
@@ -633,12 +712,12 @@ func MergePullRequest(ctx *context.APIContext, form auth.MergePullRequestForm) {
 		}
 	}
 
-	if _, err := pull_service.IsSignedIfRequired(pr, ctx.User); err != nil {
+	if _, err := pull_service.IsSignedIfRequired(target, ctx.User); err != nil {
 		if !models.IsErrWontSign(err) {
 			ctx.Error(http.StatusInternalServerError, "IsSignedIfRequired", err)
 			return
 		}
-		ctx.Error(http.StatusMethodNotAllowed, fmt.Sprintf("Protected branch %s requires signed commits but this merge would not be signed", pr.BaseBranch), err)
+		ctx.Error(http.StatusMethodNotAllowed, fmt.Sprintf("Protected branch %s requires signed commits but this merge would not be signed", target.BaseBranch), err)
 		return
 	}
 
@@ -649,10 +728,10 @@ func MergePullRequest(ctx *context.APIContext, form auth.MergePullRequestForm) {
 	message := strings.TrimSpace(form.MergeTitleField)
 	if len(message) == 0 {
 		if models.MergeStyle(form.Do) == models.MergeStyleMerge {
-			message = pr.GetDefaultMergeMessage()
+			message = target.GetDefaultMergeMessage()
 		}
 		if models.MergeStyle(form.Do) == models.MergeStyleSquash {
-			message = pr.GetDefaultSquashMessage()
+			message = target.GetDefaultSquashMessage()
 		}
 	}
 
@@ -661,22 +740,58 @@ func MergePullRequest(ctx *context.APIContext, form auth.MergePullRequestForm) {
 		message += "\n\n" + form.MergeMessageField
 	}
 
-	if err := pull_service.Merge(pr, ctx.User, ctx.Repo.GitRepo, models.MergeStyle(form.Do), message); err != nil {
+	identity := &pull_service.MergeIdentity{
+		AuthorName:     strings.TrimSpace(form.SquashAuthorName),
+		AuthorEmail:    strings.TrimSpace(form.SquashAuthorEmail),
+		CommitterName:  strings.TrimSpace(form.SquashCommitterName),
+		CommitterEmail: strings.TrimSpace(form.SquashCommitterEmail),
+	}
+	if form.ResolvedFiles != nil {
+		files := make(map[string][]byte, len(form.ResolvedFiles))
+		for file, content := range form.ResolvedFiles {
+			files[file] = []byte(content)
+		}
+		err = pull_service.ApplyConflictResolution(pr, ctx.User, ctx.Repo.GitRepo, files, models.MergeStyle(form.Do), message)
+	} else if retargeted {
+		err = pull_service.MergeInto(pr, ctx.User, ctx.Repo.GitRepo, ctx.Repo.Permission, targetBranch, models.MergeStyle(form.Do), message, identity)
+	} else {
+		err = pull_service.Merge(pr, ctx.User, ctx.Repo.GitRepo, models.MergeStyle(form.Do), message, identity)
+	}
+	if err != nil {
 		if models.IsErrInvalidMergeStyle(err) {
 			ctx.Status(http.StatusMethodNotAllowed)
 			return
+		} else if models.IsErrNotAllowedToMerge(err) {
+			ctx.Error(http.StatusMethodNotAllowed, "Merge", err)
+			return
+		} else if git.IsErrBranchNotExist(err) {
+			ctx.Error(http.StatusUnprocessableEntity, "MergeInto", err)
+			return
+		} else if models.IsErrConflictResolutionMismatch(err) {
+			ctx.Error(http.StatusUnprocessableEntity, "ApplyConflictResolution", err)
+			return
 		} else if models.IsErrMergeConflicts(err) {
 			conflictError := err.(models.ErrMergeConflicts)
 			ctx.JSON(http.StatusConflict, conflictError)
+			return
 		} else if models.IsErrRebaseConflicts(err) {
 			conflictError := err.(models.ErrRebaseConflicts)
 			ctx.JSON(http.StatusConflict, conflictError)
+			return
 		} else if models.IsErrMergeUnrelatedHistories(err) {
 			conflictError := err.(models.ErrMergeUnrelatedHistories)
 			ctx.JSON(http.StatusConflict, conflictError)
+			return
+		} else if models.IsErrMergeNotFastForward(err) {
+			conflictError := err.(models.ErrMergeNotFastForward)
+			ctx.JSON(http.StatusConflict, conflictError)
+			return
 		} else if models.IsErrMergePushOutOfDate(err) {
 			ctx.Error(http.StatusConflict, "Merge", "merge push out of date")
 			return
+		} else if models.IsErrPullRequestHasMerged(err) {
+			ctx.Status(http.StatusMethodNotAllowed)
+			return
 		}
 		ctx.Error(http.StatusInternalServerError, "Merge", err)
 		return
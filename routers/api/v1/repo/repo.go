@@ -870,13 +870,18 @@ func updateRepoUnits(ctx *context.APIContext, opts api.EditRepoOption) error {
 					AllowRebase:               true,
 					AllowRebaseMerge:          true,
 					AllowSquash:               true,
+					AllowFastForwardOnly:      true,
 				}
 			} else {
 				config = unit.PullRequestsConfig()
 			}
 
 			if opts.IgnoreWhitespaceConflicts != nil {
-				config.IgnoreWhitespaceConflicts = *opts.IgnoreWhitespaceConflicts
+				if *opts.IgnoreWhitespaceConflicts {
+					config.SetWhitespaceConflictMode(models.WhitespaceConflictsIgnoreAll)
+				} else if config.GetWhitespaceConflictMode() == models.WhitespaceConflictsIgnoreAll {
+					config.SetWhitespaceConflictMode(models.WhitespaceConflictsStrict)
+				}
 			}
 			if opts.AllowMerge != nil {
 				config.AllowMerge = *opts.AllowMerge
@@ -890,6 +895,9 @@ func updateRepoUnits(ctx *context.APIContext, opts api.EditRepoOption) error {
 			if opts.AllowSquash != nil {
 				config.AllowSquash = *opts.AllowSquash
 			}
+			if opts.AllowFastForwardOnly != nil {
+				config.AllowFastForwardOnly = *opts.AllowFastForwardOnly
+			}
 
 			units = append(units, models.RepoUnit{
 				RepoID: repo.ID,
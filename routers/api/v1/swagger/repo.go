@@ -127,6 +127,13 @@ type swaggerResponsePullRequestList struct {
 	Body []api.PullRequest `json:"body"`
 }
 
+// PullRequestTimeline
+// swagger:response PullRequestTimeline
+type swaggerResponsePullRequestTimeline struct {
+	// in:body
+	Body []api.PullRequestTimelineEntry `json:"body"`
+}
+
 // Status
 // swagger:response Status
 type swaggerResponseStatus struct {
@@ -112,6 +112,7 @@ func addHook(ctx *context.APIContext, form *api.CreateHookOption, orgID, repoID
 				PullRequest:  com.IsSliceContainsStr(form.Events, string(models.HookEventPullRequest)),
 				Repository:   com.IsSliceContainsStr(form.Events, string(models.HookEventRepository)),
 				Release:      com.IsSliceContainsStr(form.Events, string(models.HookEventRelease)),
+				Status:       com.IsSliceContainsStr(form.Events, string(models.HookEventStatus)),
 			},
 			BranchFilter: form.BranchFilter,
 		},
@@ -238,6 +239,7 @@ func editHook(ctx *context.APIContext, form *api.EditHookOption, w *models.Webho
 	w.PullRequest = com.IsSliceContainsStr(form.Events, string(models.HookEventPullRequest))
 	w.Repository = com.IsSliceContainsStr(form.Events, string(models.HookEventRepository))
 	w.Release = com.IsSliceContainsStr(form.Events, string(models.HookEventRelease))
+	w.Status = com.IsSliceContainsStr(form.Events, string(models.HookEventStatus))
 	w.BranchFilter = form.BranchFilter
 
 	if err := w.UpdateEvent(); err != nil {
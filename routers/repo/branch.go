@@ -75,6 +75,17 @@ func DeleteBranchPost(ctx *context.Context) {
 		return
 	}
 
+	openPRs, err := models.CountOpenPullRequestsByBaseBranch(ctx.Repo.Repository.ID, branchName)
+	if err != nil {
+		log.Error("CountOpenPullRequestsByBaseBranch: %v", err)
+		ctx.Flash.Error(ctx.Tr("repo.branch.deletion_failed", branchName))
+		return
+	}
+	if openPRs > 0 {
+		ctx.Flash.Error(ctx.Tr("repo.branch.delete_branch_has_open_pulls", branchName, openPRs))
+		return
+	}
+
 	if err := deleteBranch(ctx, branchName); err != nil {
 		ctx.Flash.Error(ctx.Tr("repo.branch.deletion_failed", branchName))
 		return
@@ -145,9 +156,7 @@ func deleteBranch(ctx *context.Context, branchName string) error {
 		return err
 	}
 
-	if err := ctx.Repo.GitRepo.DeleteBranch(branchName, git.DeleteBranchOptions{
-		Force: true,
-	}); err != nil {
+	if err := repo_module.DeleteBranch(ctx.Repo.Repository, ctx.Repo.GitRepo, branchName); err != nil {
 		log.Error("DeleteBranch: %v", err)
 		return err
 	}
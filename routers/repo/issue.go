@@ -216,6 +216,7 @@ func issues(ctx *context.Context, milestoneID int64, isPullOption util.OptionalB
 	}
 
 	var commitStatus = make(map[int64]*models.CommitStatus, len(issues))
+	var pullIDs = make([]int64, 0, len(issues))
 
 	// Get posters.
 	for i := range issues {
@@ -234,11 +235,19 @@ func issues(ctx *context.Context, milestoneID int64, isPullOption util.OptionalB
 			}
 
 			commitStatus[issues[i].PullRequest.ID], _ = issues[i].PullRequest.GetLastCommitStatus()
+			pullIDs = append(pullIDs, issues[i].PullRequest.ID)
 		}
 	}
 
+	reviewCounts, err := models.GetReviewCountsForPulls(pullIDs)
+	if err != nil {
+		ctx.ServerError("GetReviewCountsForPulls", err)
+		return
+	}
+
 	ctx.Data["Issues"] = issues
 	ctx.Data["CommitStatus"] = commitStatus
+	ctx.Data["ReviewCounts"] = reviewCounts
 
 	// Get assignees.
 	ctx.Data["Assignees"], err = repo.GetAssignees()
@@ -420,7 +429,7 @@ func NewIssue(ctx *context.Context) {
 	ctx.Data["RequireHighlightJS"] = true
 	ctx.Data["RequireSimpleMDE"] = true
 	ctx.Data["RequireTribute"] = true
-	ctx.Data["PullRequestWorkInProgressPrefixes"] = setting.Repository.PullRequest.WorkInProgressPrefixes
+	ctx.Data["PullRequestWorkInProgressPrefixes"] = ctx.Repo.Repository.GetWorkInProgressPrefixes()
 	body := ctx.Query("body")
 	ctx.Data["BodyQuery"] = body
 
@@ -534,7 +543,7 @@ func NewIssuePost(ctx *context.Context, form auth.CreateIssueForm) {
 	ctx.Data["RequireHighlightJS"] = true
 	ctx.Data["RequireSimpleMDE"] = true
 	ctx.Data["ReadOnly"] = false
-	ctx.Data["PullRequestWorkInProgressPrefixes"] = setting.Repository.PullRequest.WorkInProgressPrefixes
+	ctx.Data["PullRequestWorkInProgressPrefixes"] = ctx.Repo.Repository.GetWorkInProgressPrefixes()
 	renderAttachmentSettings(ctx)
 
 	var (
@@ -953,10 +962,20 @@ func ViewIssue(ctx *context.Context) {
 				ctx.Data["MergeStyle"] = models.MergeStyleRebaseMerge
 			} else if prConfig.AllowSquash {
 				ctx.Data["MergeStyle"] = models.MergeStyleSquash
+			} else if prConfig.AllowFastForwardOnly {
+				ctx.Data["MergeStyle"] = models.MergeStyleFastForwardOnly
 			} else {
 				ctx.Data["MergeStyle"] = ""
 			}
 		}
+		if !pull.HasMerged && !issue.IsClosed && pull.MergeBase != "" && !pull.IsPatchTooLarge() {
+			if binaries, err := pull.GetBinaryChangedFiles(); err != nil {
+				log.Error("GetBinaryChangedFiles[%d]: %v", pull.ID, err)
+			} else {
+				ctx.Data["BinaryChangedFiles"] = binaries
+				ctx.Data["IsBlockedByBinaryFiles"] = prConfig.BlockBinaryFiles && len(binaries) > 0
+			}
+		}
 		if err = pull.LoadProtectedBranch(); err != nil {
 			ctx.ServerError("LoadProtectedBranch", err)
 			return
@@ -967,6 +986,15 @@ func ViewIssue(ctx *context.Context) {
 			ctx.Data["IsBlockedByRejection"] = pull.ProtectedBranch.MergeBlockedByRejectedReview(pull)
 			ctx.Data["GrantedApprovals"] = cnt
 			ctx.Data["RequireSigned"] = pull.ProtectedBranch.RequireSignedCommits
+			if pull.ProtectedBranch.BlockOnUnresolvedConversations {
+				unresolved, err := pull.UnresolvedReviewThreadCount()
+				if err != nil {
+					ctx.ServerError("UnresolvedReviewThreadCount", err)
+					return
+				}
+				ctx.Data["IsBlockedByUnresolvedConversations"] = unresolved > 0
+				ctx.Data["UnresolvedConversations"] = unresolved
+			}
 		}
 		ctx.Data["WillSign"] = false
 		if ctx.User != nil {
@@ -1231,7 +1259,13 @@ func UpdateIssueStatus(ctx *context.Context) {
 	}
 	for _, issue := range issues {
 		if issue.IsClosed != isClosed {
-			if err := issue_service.ChangeStatus(issue, ctx.User, isClosed); err != nil {
+			var err error
+			if issue.IsPull {
+				err = pull_service.ChangeStatus(issue, ctx.User, isClosed)
+			} else {
+				err = issue_service.ChangeStatus(issue, ctx.User, isClosed)
+			}
+			if err != nil {
 				if models.IsErrDependenciesLeft(err) {
 					ctx.JSON(http.StatusPreconditionFailed, map[string]interface{}{
 						"error": "cannot close this issue because it still has open dependencies",
@@ -1314,18 +1348,19 @@ func NewComment(ctx *context.Context, form auth.CreateCommentForm) {
 						return
 					}
 				}
-
-				// Regenerate patch and test conflict.
-				if pr == nil {
-					pull_service.AddToTaskQueue(issue.PullRequest)
-				}
 			}
 
 			if pr != nil {
 				ctx.Flash.Info(ctx.Tr("repo.pulls.open_unmerged_pull_exists", pr.Index))
 			} else {
 				isClosed := form.Status == "close"
-				if err := issue_service.ChangeStatus(issue, ctx.User, isClosed); err != nil {
+				var err error
+				if issue.IsPull {
+					err = pull_service.ChangeStatus(issue, ctx.User, isClosed)
+				} else {
+					err = issue_service.ChangeStatus(issue, ctx.User, isClosed)
+				}
+				if err != nil {
 					log.Error("ChangeStatus: %v", err)
 
 					if models.IsErrDependenciesLeft(err) {
@@ -24,6 +24,7 @@ import (
 	"code.gitea.io/gitea/modules/log"
 	"code.gitea.io/gitea/modules/notification"
 	"code.gitea.io/gitea/modules/repofiles"
+	repo_module "code.gitea.io/gitea/modules/repository"
 	"code.gitea.io/gitea/modules/setting"
 	"code.gitea.io/gitea/modules/util"
 	"code.gitea.io/gitea/services/gitdiff"
@@ -321,6 +322,15 @@ func PrepareMergedViewPullInfo(ctx *context.Context, issue *models.Issue) *git.C
 		ctx.ServerError("GetCompareInfo", err)
 		return nil
 	}
+
+	contributors, unmatchedContributors, err := pull.GetContributors()
+	if err != nil {
+		ctx.ServerError("GetContributors", err)
+		return nil
+	}
+	ctx.Data["Contributors"] = contributors
+	ctx.Data["UnmatchedContributors"] = unmatchedContributors
+
 	ctx.Data["NumCommits"] = compareInfo.Commits.Len()
 	ctx.Data["NumFiles"] = compareInfo.NumFiles
 	return compareInfo
@@ -420,6 +430,17 @@ func PrepareViewPullInfo(ctx *context.Context, issue *models.Issue) *git.Compare
 		ctx.Data["IsRequiredStatusCheckSuccess"] = pull_service.IsCommitStatusContextSuccess(commitStatuses, pull.ProtectedBranch.StatusCheckContexts)
 	}
 
+	if headBranchExist {
+		passed, failed, pending, errored, err := pull.GetCheckRunSummary()
+		if err != nil {
+			ctx.ServerError("GetCheckRunSummary", err)
+			return nil
+		}
+		if passed+failed+pending+errored > 0 {
+			ctx.Data["CheckRunSummary"] = ctx.Tr("repo.pulls.check_run_summary", passed, failed, pending, errored)
+		}
+	}
+
 	ctx.Data["HeadBranchMovedOn"] = headBranchSha != sha
 	ctx.Data["HeadBranchCommitID"] = headBranchSha
 	ctx.Data["PullHeadCommitID"] = sha
@@ -494,6 +515,14 @@ func ViewPullCommits(ctx *context.Context) {
 	ctx.Data["Commits"] = commits
 	ctx.Data["CommitCount"] = commits.Len()
 
+	if !pull.HasMerged {
+		if duplicates, err := pull.GetDuplicateCommits(); err != nil {
+			log.Error("GetDuplicateCommits[%d]: %v", pull.ID, err)
+		} else {
+			ctx.Data["DuplicateCommits"] = duplicates
+		}
+	}
+
 	getBranchData(ctx, issue)
 	ctx.HTML(200, tplPullCommits)
 }
@@ -597,6 +626,10 @@ func ViewPullFiles(ctx *context.Context) {
 		ctx.ServerError("GetCurrentReview", err)
 		return
 	}
+	if ctx.Data["CanMarkConversation"], err = models.CanMarkConversation(issue, ctx.User); err != nil {
+		ctx.ServerError("CanMarkConversation", err)
+		return
+	}
 	getBranchData(ctx, issue)
 	ctx.HTML(200, tplPullFiles)
 }
@@ -641,7 +674,12 @@ func UpdatePullRequest(ctx *context.Context) {
 	// default merge commit message
 	message := fmt.Sprintf("Merge branch '%s' into %s", issue.PullRequest.BaseBranch, issue.PullRequest.HeadBranch)
 
-	if err = pull_service.Update(issue.PullRequest, ctx.User, message); err != nil {
+	style := models.MergeStyleMerge
+	if ctx.Query("style") == string(models.MergeStyleRebase) {
+		style = models.MergeStyleRebase
+	}
+
+	if err = pull_service.UpdateHeadFromBase(issue.PullRequest, ctx.User, style, message); err != nil {
 		sanitize := func(x string) string {
 			runes := []rune(x)
 
@@ -680,17 +718,46 @@ func MergePullRequest(ctx *context.Context, form auth.MergePullRequestForm) {
 
 	pr := issue.PullRequest
 
-	allowedMerge, err := pull_service.IsUserAllowedToMerge(pr, ctx.Repo.Permission, ctx.User)
-	if err != nil {
-		ctx.ServerError("IsUserAllowedToMerge", err)
-		return
+	// All merge checks are run against the branch the pull request is merged into, using a
+	// copy aimed at it if that is not the recorded base branch
+	target := pr
+	targetBranch := strings.TrimSpace(form.TargetBranch)
+	retargeted := len(targetBranch) > 0 && targetBranch != pr.BaseBranch
+	if retargeted {
+		if !ctx.Repo.GitRepo.IsBranchExist(targetBranch) {
+			ctx.Flash.Error(ctx.Tr("form.target_branch_not_exist"))
+			ctx.Redirect(ctx.Repo.RepoLink + "/pulls/" + com.ToStr(pr.Index))
+			return
+		}
+		target = pull_service.CopyForTarget(pr, targetBranch)
 	}
-	if !allowedMerge {
-		ctx.NotFound("MergePullRequest", nil)
+
+	if err := pull_service.CheckUserAllowedToMerge(target, ctx.Repo.Permission, ctx.User); err != nil {
+		if models.IsErrNotAllowedToMerge(err) {
+			ctx.NotFound("MergePullRequest", nil)
+		} else if models.IsErrUnsignedCommits(err) {
+			ctx.Flash.Error(ctx.Tr("repo.pulls.no_merge_unsigned_commits", strings.Join(err.(models.ErrUnsignedCommits).SHAs, ", ")))
+			ctx.Redirect(ctx.Repo.RepoLink + "/pulls/" + com.ToStr(pr.Index))
+		} else if models.IsErrMissingSignoff(err) {
+			ctx.Flash.Error(ctx.Tr("repo.pulls.no_merge_missing_signoff", strings.Join(err.(models.ErrMissingSignoff).SHAs, ", ")))
+			ctx.Redirect(ctx.Repo.RepoLink + "/pulls/" + com.ToStr(pr.Index))
+		} else if models.IsErrBinaryFilesChanged(err) {
+			ctx.Flash.Error(ctx.Tr("repo.pulls.no_merge_binary_files", strings.Join(err.(models.ErrBinaryFilesChanged).Files, ", ")))
+			ctx.Redirect(ctx.Repo.RepoLink + "/pulls/" + com.ToStr(pr.Index))
+		} else if models.IsErrPatchTooLarge(err) {
+			ctx.Flash.Error(ctx.Tr("repo.pulls.no_merge_binary_files_unchecked"))
+			ctx.Redirect(ctx.Repo.RepoLink + "/pulls/" + com.ToStr(pr.Index))
+		} else if models.IsErrUnresolvedConversations(err) {
+			ctx.Flash.Error(ctx.Tr("repo.pulls.no_merge_unresolved_conversations"))
+			ctx.Redirect(ctx.Repo.RepoLink + "/pulls/" + com.ToStr(pr.Index))
+		} else {
+			ctx.ServerError("CheckUserAllowedToMerge", err)
+		}
 		return
 	}
 
-	if !pr.CanAutoMerge() || pr.HasMerged {
+	// MergeInto tests for conflicts against another target branch itself
+	if (!pr.CanAutoMerge() && !retargeted) || pr.HasMerged {
 		ctx.NotFound("MergePullRequest", nil)
 		return
 	}
@@ -701,7 +768,7 @@ func MergePullRequest(ctx *context.Context, form auth.MergePullRequestForm) {
 		return
 	}
 
-	if err := pull_service.CheckPRReadyToMerge(pr); err != nil {
+	if err := pull_service.CheckPRReadyToMerge(target); err != nil {
 		if !models.IsErrNotAllowedToMerge(err) {
 			ctx.ServerError("Merge PR status", err)
 			return
@@ -725,13 +792,13 @@ func MergePullRequest(ctx *context.Context, form auth.MergePullRequestForm) {
 	message := strings.TrimSpace(form.MergeTitleField)
 	if len(message) == 0 {
 		if models.MergeStyle(form.Do) == models.MergeStyleMerge {
-			message = pr.GetDefaultMergeMessage()
+			message = target.GetDefaultMergeMessage()
 		}
 		if models.MergeStyle(form.Do) == models.MergeStyleRebaseMerge {
-			message = pr.GetDefaultMergeMessage()
+			message = target.GetDefaultMergeMessage()
 		}
 		if models.MergeStyle(form.Do) == models.MergeStyleSquash {
-			message = pr.GetDefaultSquashMessage()
+			message = target.GetDefaultSquashMessage()
 		}
 	}
 
@@ -754,7 +821,18 @@ func MergePullRequest(ctx *context.Context, form auth.MergePullRequestForm) {
 		return
 	}
 
-	if err = pull_service.Merge(pr, ctx.User, ctx.Repo.GitRepo, models.MergeStyle(form.Do), message); err != nil {
+	identity := &pull_service.MergeIdentity{
+		AuthorName:     strings.TrimSpace(form.SquashAuthorName),
+		AuthorEmail:    strings.TrimSpace(form.SquashAuthorEmail),
+		CommitterName:  strings.TrimSpace(form.SquashCommitterName),
+		CommitterEmail: strings.TrimSpace(form.SquashCommitterEmail),
+	}
+	if retargeted {
+		err = pull_service.MergeInto(pr, ctx.User, ctx.Repo.GitRepo, ctx.Repo.Permission, targetBranch, models.MergeStyle(form.Do), message, identity)
+	} else {
+		err = pull_service.Merge(pr, ctx.User, ctx.Repo.GitRepo, models.MergeStyle(form.Do), message, identity)
+	}
+	if err != nil {
 		sanitize := func(x string) string {
 			runes := []rune(x)
 
@@ -768,6 +846,13 @@ func MergePullRequest(ctx *context.Context, form auth.MergePullRequestForm) {
 			ctx.Flash.Error(ctx.Tr("repo.pulls.invalid_merge_option"))
 			ctx.Redirect(ctx.Repo.RepoLink + "/pulls/" + com.ToStr(pr.Index))
 			return
+		} else if models.IsErrNotAllowedToMerge(err) {
+			ctx.NotFound("MergePullRequest", nil)
+			return
+		} else if git.IsErrBranchNotExist(err) {
+			ctx.Flash.Error(ctx.Tr("form.target_branch_not_exist"))
+			ctx.Redirect(ctx.Repo.RepoLink + "/pulls/" + com.ToStr(pr.Index))
+			return
 		} else if models.IsErrMergeConflicts(err) {
 			conflictError := err.(models.ErrMergeConflicts)
 			ctx.Flash.Error(ctx.Tr("repo.pulls.merge_conflict", sanitize(conflictError.StdErr), sanitize(conflictError.StdOut)))
@@ -783,11 +868,20 @@ func MergePullRequest(ctx *context.Context, form auth.MergePullRequestForm) {
 			ctx.Flash.Error(ctx.Tr("repo.pulls.unrelated_histories"))
 			ctx.Redirect(ctx.Repo.RepoLink + "/pulls/" + com.ToStr(pr.Index))
 			return
+		} else if models.IsErrMergeNotFastForward(err) {
+			log.Debug("MergeNotFastForward error: %v", err)
+			ctx.Flash.Error(ctx.Tr("repo.pulls.merge_not_fast_forward"))
+			ctx.Redirect(ctx.Repo.RepoLink + "/pulls/" + com.ToStr(pr.Index))
+			return
 		} else if models.IsErrMergePushOutOfDate(err) {
 			log.Debug("MergePushOutOfDate error: %v", err)
 			ctx.Flash.Error(ctx.Tr("repo.pulls.merge_out_of_date"))
 			ctx.Redirect(ctx.Repo.RepoLink + "/pulls/" + com.ToStr(pr.Index))
 			return
+		} else if models.IsErrPullRequestHasMerged(err) {
+			ctx.Flash.Error(ctx.Tr("repo.pulls.has_merged"))
+			ctx.Redirect(ctx.Repo.RepoLink + "/pulls/" + com.ToStr(pr.Index))
+			return
 		}
 		ctx.ServerError("Merge", err)
 		return
@@ -819,7 +913,7 @@ func CompareAndPullRequestPost(ctx *context.Context, form auth.CreateIssueForm)
 	ctx.Data["PageIsComparePull"] = true
 	ctx.Data["IsDiffCompare"] = true
 	ctx.Data["RequireHighlightJS"] = true
-	ctx.Data["PullRequestWorkInProgressPrefixes"] = setting.Repository.PullRequest.WorkInProgressPrefixes
+	ctx.Data["PullRequestWorkInProgressPrefixes"] = ctx.Repo.Repository.GetWorkInProgressPrefixes()
 	renderAttachmentSettings(ctx)
 
 	var (
@@ -892,6 +986,9 @@ func CompareAndPullRequestPost(ctx *context.Context, form auth.CreateIssueForm)
 		if models.IsErrUserDoesNotHaveAccessToRepo(err) {
 			ctx.Error(400, "UserDoesNotHaveAccessToRepo", err.Error())
 			return
+		} else if models.IsErrPullRequestSameRef(err) {
+			ctx.Error(400, "PullRequestSameRef", err.Error())
+			return
 		}
 		ctx.ServerError("NewPullRequest", err)
 		return
@@ -1033,9 +1130,18 @@ func CleanUpPullRequest(ctx *context.Context) {
 		return
 	}
 
-	if err := gitRepo.DeleteBranch(pr.HeadBranch, git.DeleteBranchOptions{
-		Force: true,
-	}); err != nil {
+	openPRs, err := models.CountOpenPullRequestsByBaseBranch(pr.HeadRepoID, pr.HeadBranch)
+	if err != nil {
+		log.Error("CountOpenPullRequestsByBaseBranch: %v", err)
+		ctx.Flash.Error(ctx.Tr("repo.branch.deletion_failed", fullBranchName))
+		return
+	}
+	if openPRs > 0 {
+		ctx.Flash.Error(ctx.Tr("repo.branch.delete_branch_has_open_pulls", fullBranchName, openPRs))
+		return
+	}
+
+	if err := repo_module.DeleteBranch(pr.HeadRepo, gitRepo, pr.HeadBranch); err != nil {
 		log.Error("DeleteBranch: %v", err)
 		ctx.Flash.Error(ctx.Tr("repo.branch.deletion_failed", fullBranchName))
 		return
@@ -1100,6 +1206,35 @@ func DownloadPullDiffOrPatch(ctx *context.Context, patch bool) {
 
 	pr := issue.PullRequest
 
+	// Serve the diff against a given base, e.g. the base branch at the time of a review
+	if base := ctx.Query("base"); !patch && len(base) > 0 {
+		diff, err := pr.DiffAgainstBase(base)
+		if err != nil {
+			if models.IsErrPullRequestInvalidDiffBase(err) {
+				ctx.NotFound("DiffAgainstBase", err)
+			} else {
+				ctx.ServerError("DiffAgainstBase", err)
+			}
+			return
+		}
+		if _, err = ctx.Resp.Write([]byte(diff)); err != nil {
+			log.Error("Write: %v", err)
+		}
+		return
+	}
+
+	// Serve the commits as a series of patches which can be applied with git am
+	if patch && pr.MergeBase != "" {
+		if err := pr.GetPatchSeries(ctx.Resp); err != nil {
+			if models.IsErrPatchSeriesTooLarge(err) {
+				ctx.Error(http.StatusUnprocessableEntity, err.Error())
+			} else {
+				ctx.ServerError("GetPatchSeries", err)
+			}
+		}
+		return
+	}
+
 	if err := pull_service.DownloadDiffOrPatch(pr, ctx, patch); err != nil {
 		ctx.ServerError("DownloadDiffOrPatch", err)
 		return
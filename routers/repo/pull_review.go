@@ -14,6 +14,50 @@ import (
 	pull_service "code.gitea.io/gitea/services/pull"
 )
 
+// UpdateResolveConversation marks the conversation of a code comment as resolved or unresolved
+func UpdateResolveConversation(ctx *context.Context) {
+	comment, err := models.GetCommentByID(ctx.QueryInt64("comment_id"))
+	if err != nil {
+		ctx.NotFoundOrServerError("GetCommentByID", models.IsErrCommentNotExist, err)
+		return
+	}
+	if err = comment.LoadIssue(); err != nil {
+		ctx.ServerError("LoadIssue", err)
+		return
+	}
+	if comment.Issue.RepoID != ctx.Repo.Repository.ID || !comment.Issue.IsPull || comment.Type != models.CommentTypeCode {
+		ctx.NotFound("UpdateResolveConversation", nil)
+		return
+	}
+
+	canMark, err := models.CanMarkConversation(comment.Issue, ctx.User)
+	if err != nil {
+		ctx.ServerError("CanMarkConversation", err)
+		return
+	}
+	if !canMark {
+		ctx.Error(403)
+		return
+	}
+
+	var isResolved bool
+	switch ctx.Query("action") {
+	case "resolve":
+		isResolved = true
+	case "unresolve":
+		isResolved = false
+	default:
+		ctx.Error(400)
+		return
+	}
+	if err = models.MarkConversation(comment, isResolved); err != nil {
+		ctx.ServerError("MarkConversation", err)
+		return
+	}
+
+	ctx.Redirect(fmt.Sprintf("%s/pulls/%d/files#%s", ctx.Repo.RepoLink, comment.Issue.Index, comment.HashTag()))
+}
+
 // CreateCodeComment will create a code comment including an pending review if required
 func CreateCodeComment(ctx *context.Context, form auth.CodeCommentForm) {
 	issue := GetActionIssue(ctx)
@@ -0,0 +1,39 @@
+// Copyright 2020 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package repo
+
+import (
+	"net/http"
+	"testing"
+
+	"code.gitea.io/gitea/models"
+	"code.gitea.io/gitea/modules/test"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpdateResolveConversation(t *testing.T) {
+	models.PrepareTestEnv(t)
+
+	ctx := test.MockContext(t, "user2/repo1/issues/resolve_conversation")
+	test.LoadUser(t, ctx, 2)
+	test.LoadRepo(t, ctx, 1)
+	ctx.Req.Form.Set("comment_id", "5")
+	ctx.Req.Form.Set("action", "resolve")
+	UpdateResolveConversation(ctx)
+	assert.EqualValues(t, http.StatusFound, ctx.Resp.Status())
+	assert.Equal(t, "/user2/repo1/pulls/2/files#issuecomment-5", test.RedirectURL(ctx.Resp))
+	models.AssertExistsAndLoadBean(t, &models.Comment{ID: 5, IsResolved: true})
+
+	// users without write access to the pull requests can not unresolve it
+	ctx = test.MockContext(t, "user2/repo1/issues/resolve_conversation")
+	test.LoadUser(t, ctx, 4)
+	test.LoadRepo(t, ctx, 1)
+	ctx.Req.Form.Set("comment_id", "5")
+	ctx.Req.Form.Set("action", "unresolve")
+	UpdateResolveConversation(ctx)
+	assert.EqualValues(t, http.StatusForbidden, ctx.Resp.Status())
+	models.AssertExistsAndLoadBean(t, &models.Comment{ID: 5, IsResolved: true})
+}
@@ -0,0 +1,73 @@
+// Copyright 2020 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package repo
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+
+	"code.gitea.io/gitea/models"
+	"code.gitea.io/gitea/modules/setting"
+	"code.gitea.io/gitea/modules/test"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDownloadPullDiff_Base(t *testing.T) {
+	models.PrepareTestEnv(t)
+
+	ctx := test.MockContext(t, "user2/repo1/pulls/3.diff")
+	test.LoadUser(t, ctx, 2)
+	test.LoadRepo(t, ctx, 1)
+	ctx.SetParams(":index", "3")
+	ctx.Req.Form.Set("base", "65f1bf27bc3bf70f64657658635e66094edbcb4d")
+	DownloadPullDiff(ctx)
+	assert.EqualValues(t, http.StatusOK, ctx.Resp.Status())
+	pr := models.AssertExistsAndLoadBean(t, &models.PullRequest{ID: 2}).(*models.PullRequest)
+	diff, err := pr.DiffAgainstBase("65f1bf27bc3bf70f64657658635e66094edbcb4d")
+	assert.NoError(t, err)
+	assert.Contains(t, diff, "diff --git")
+	assert.Equal(t, len(diff), ctx.Resp.Size())
+
+	// the base must be an ancestor of the head of the pull request
+	ctx = test.MockContext(t, "user2/repo1/pulls/3.diff")
+	test.LoadUser(t, ctx, 2)
+	test.LoadRepo(t, ctx, 1)
+	ctx.SetParams(":index", "3")
+	ctx.Req.Form.Set("base", "985f0301dba5e7b34be866819cd15ad3d8f508ee")
+	DownloadPullDiff(ctx)
+	assert.EqualValues(t, http.StatusNotFound, ctx.Resp.Status())
+}
+
+func TestDownloadPullPatch_Series(t *testing.T) {
+	models.PrepareTestEnv(t)
+	// the head of pull request 5 is two commits ahead of this merge base
+	pr := models.AssertExistsAndLoadBean(t, &models.PullRequest{ID: 5}).(*models.PullRequest)
+	pr.MergeBase = "65f1bf27bc3bf70f64657658635e66094edbcb4d"
+	assert.NoError(t, pr.UpdateCols("merge_base"))
+
+	ctx := test.MockContext(t, "user2/repo1/pulls/5.patch")
+	test.LoadUser(t, ctx, 2)
+	test.LoadRepo(t, ctx, 1)
+	ctx.SetParams(":index", "5")
+	DownloadPullPatch(ctx)
+	assert.EqualValues(t, http.StatusOK, ctx.Resp.Status())
+	series := new(bytes.Buffer)
+	assert.NoError(t, pr.GetPatchSeries(series))
+	assert.Contains(t, series.String(), "Subject: [PATCH 2/2] add WoW File")
+	assert.Equal(t, series.Len(), ctx.Resp.Size())
+
+	defer func(maxCommits int) {
+		setting.Repository.PullRequest.MaxPatchSeriesCommits = maxCommits
+	}(setting.Repository.PullRequest.MaxPatchSeriesCommits)
+	setting.Repository.PullRequest.MaxPatchSeriesCommits = 1
+	ctx = test.MockContext(t, "user2/repo1/pulls/5.patch")
+	test.LoadUser(t, ctx, 2)
+	test.LoadRepo(t, ctx, 1)
+	ctx.SetParams(":index", "5")
+	DownloadPullPatch(ctx)
+	assert.EqualValues(t, http.StatusUnprocessableEntity, ctx.Resp.Status())
+}
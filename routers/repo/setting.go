@@ -284,16 +284,26 @@ func SettingsPost(ctx *context.Context, form auth.RepoSettingForm) {
 		}
 
 		if form.EnablePulls && !models.UnitTypePullRequests.UnitGlobalDisabled() {
+			config := &models.PullRequestsConfig{
+				AllowMerge:            form.PullsAllowMerge,
+				AllowRebase:           form.PullsAllowRebase,
+				AllowRebaseMerge:      form.PullsAllowRebaseMerge,
+				AllowSquash:           form.PullsAllowSquash,
+				AllowFastForwardOnly:  form.PullsAllowFastForwardOnly,
+				DisableStaleAutoClose: form.PullsDisableStaleAutoClose,
+				RequireSignoff:        form.PullsRequireSignoff,
+				BlockBinaryFiles:      form.PullsBlockBinaryFiles,
+			}
+			for _, prefix := range strings.Split(form.PullsWorkInProgressPrefixes, ",") {
+				if prefix = strings.TrimSpace(prefix); len(prefix) > 0 {
+					config.WorkInProgressPrefixes = append(config.WorkInProgressPrefixes, prefix)
+				}
+			}
+			config.SetWhitespaceConflictMode(models.WhitespaceConflictMode(form.PullsWhitespaceConflicts))
 			units = append(units, models.RepoUnit{
 				RepoID: repo.ID,
 				Type:   models.UnitTypePullRequests,
-				Config: &models.PullRequestsConfig{
-					IgnoreWhitespaceConflicts: form.PullsIgnoreWhitespace,
-					AllowMerge:                form.PullsAllowMerge,
-					AllowRebase:               form.PullsAllowRebase,
-					AllowRebaseMerge:          form.PullsAllowRebaseMerge,
-					AllowSquash:               form.PullsAllowSquash,
-				},
+				Config: config,
 			})
 		} else if !models.UnitTypePullRequests.UnitGlobalDisabled() {
 			deleteUnitTypes = append(deleteUnitTypes, models.UnitTypePullRequests)
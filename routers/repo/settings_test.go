@@ -16,6 +16,7 @@ import (
 	"code.gitea.io/gitea/modules/setting"
 	"code.gitea.io/gitea/modules/test"
 
+	"gitea.com/macaron/binding"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -91,6 +92,31 @@ func TestAddReadWriteOnlyDeployKey(t *testing.T) {
 	})
 }
 
+func TestSettingsPost_Update(t *testing.T) {
+	models.PrepareTestEnv(t)
+
+	ctx := test.MockContext(t, "user2/repo1/settings")
+	test.LoadUser(t, ctx, 2)
+	test.LoadRepo(t, ctx, 1)
+	assert.NoError(t, ctx.Repo.Repository.GetOwner())
+	ctx.Repo.Owner = ctx.Repo.Repository.Owner
+	ctx.Req.Form.Set("action", "update")
+
+	// the basic settings do not post any of the advanced settings
+	form := auth.RepoSettingForm{
+		RepoName:    "repo1",
+		Description: "new description",
+	}
+	form.Validate(ctx.Context, binding.RawValidate(form))
+	assert.False(t, ctx.HasError())
+
+	SettingsPost(ctx, form)
+	assert.EqualValues(t, http.StatusFound, ctx.Resp.Status())
+	assert.Equal(t, "/user2/repo1/settings", test.RedirectURL(ctx.Resp))
+
+	models.AssertExistsAndLoadBean(t, &models.Repository{ID: 1, Description: "new description"})
+}
+
 func TestCollaborationPost(t *testing.T) {
 
 	models.PrepareTestEnv(t)
@@ -145,8 +145,10 @@ func ParseHookEvent(form auth.WebhookForm) *models.HookEvent {
 			Push:         form.Push,
 			PullRequest:  form.PullRequest,
 			Repository:   form.Repository,
+			Status:       form.Status,
 		},
 		BranchFilter: form.BranchFilter,
+		BatchWindow:  form.BatchWindow,
 	}
 }
 
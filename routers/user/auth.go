@@ -10,6 +10,7 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
 	"code.gitea.io/gitea/models"
 	"code.gitea.io/gitea/modules/auth"
@@ -172,6 +173,9 @@ func SignInPost(ctx *context.Context, form auth.SignInForm) {
 		} else if models.IsErrEmailAlreadyUsed(err) {
 			ctx.RenderWithErr(ctx.Tr("form.email_been_used"), tplSignIn, &form)
 			log.Info("Failed authentication attempt for %s from %s", form.UserName, ctx.RemoteAddr())
+		} else if models.IsErrLDAPNotInRequiredGroup(err) {
+			ctx.RenderWithErr(ctx.Tr("form.ldap_not_in_required_group"), tplSignIn, &form)
+			log.Info("Failed authentication attempt for %s from %s: %v", form.UserName, ctx.RemoteAddr(), err)
 		} else if models.IsErrUserProhibitLogin(err) {
 			log.Info("Failed authentication attempt for %s from %s", form.UserName, ctx.RemoteAddr())
 			ctx.Data["Title"] = ctx.Tr("auth.prohibit_login")
@@ -190,6 +194,11 @@ func SignInPost(ctx *context.Context, form auth.SignInForm) {
 		}
 		return
 	}
+	// Warn users whose password expires soon in the login source, which only reports
+	// it shortly before
+	if u.PasswordExpiresIn != nil {
+		ctx.Flash.Warning(ctx.Tr("auth.password_expires_soon", passwordExpiryDays(*u.PasswordExpiresIn)))
+	}
 	// If this user is enrolled in 2FA, we can't sign the user in just yet.
 	// Instead, redirect them to the 2FA authentication page.
 	_, err = models.GetTwoFactorByUID(u.ID)
@@ -474,6 +483,15 @@ func U2FSign(ctx *context.Context, signResp u2f.SignResponse) {
 }
 
 // This handles the final part of the sign-in process of the user.
+// passwordExpiryDays returns the number of started days until a password expiring in d expires,
+// at least one.
+func passwordExpiryDays(d time.Duration) int64 {
+	if days := int64((d + 24*time.Hour - 1) / (24 * time.Hour)); days > 1 {
+		return days
+	}
+	return 1
+}
+
 func handleSignIn(ctx *context.Context, u *models.User, remember bool) {
 	handleSignInFull(ctx, u, remember, true)
 }
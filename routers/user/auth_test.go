@@ -0,0 +1,27 @@
+// Copyright 2020 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package user
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPasswordExpiryDays(t *testing.T) {
+	day := 24 * time.Hour
+	kases := map[time.Duration]int64{
+		0:               1,
+		time.Minute:     1,
+		day:             1,
+		day + time.Hour: 2,
+		14 * day:        14,
+		14*day + 1:      15,
+	}
+	for expiresIn, days := range kases {
+		assert.Equal(t, days, passwordExpiryDays(expiresIn), "expires in %v", expiresIn)
+	}
+}
@@ -6,7 +6,9 @@ package issue
 
 import (
 	"code.gitea.io/gitea/models"
+	"code.gitea.io/gitea/modules/log"
 	"code.gitea.io/gitea/modules/notification"
+	"code.gitea.io/gitea/modules/timeutil"
 )
 
 // NewIssue creates new issue with labels for repository.
@@ -36,6 +38,66 @@ func ChangeTitle(issue *models.Issue, doer *models.User, title string) (err erro
 	}
 
 	notification.NotifyIssueChangeTitle(doer, issue, oldTitle)
+	notifyIfConvertedToDraft(doer, issue, oldTitle)
+
+	return nil
+}
+
+// ChangeDeadline changes the deadline of an issue, a zero deadline removes it.
+func ChangeDeadline(issue *models.Issue, doer *models.User, deadlineUnix timeutil.TimeStamp) error {
+	oldDeadline := issue.DeadlineUnix
+	if oldDeadline == deadlineUnix {
+		return nil
+	}
+
+	if err := models.UpdateIssueDeadline(issue, deadlineUnix, doer); err != nil {
+		return err
+	}
+	issue.DeadlineUnix = deadlineUnix
+
+	if issue.IsPull {
+		if err := issue.LoadPullRequest(); err != nil {
+			log.Error("LoadPullRequest: %v", err)
+			return nil
+		}
+		issue.PullRequest.Issue = issue
+		notification.NotifyPullRequestChangeDeadline(doer, issue.PullRequest, oldDeadline)
+	}
+
+	return nil
+}
+
+// notifyIfConvertedToDraft notifies if a pull request became a work in progress by the change of its title
+func notifyIfConvertedToDraft(doer *models.User, issue *models.Issue, oldTitle string) {
+	if !issue.IsPull {
+		return
+	}
+	if err := issue.LoadRepo(); err != nil {
+		log.Error("LoadRepo: %v", err)
+		return
+	}
+	if issue.Repo.HasWorkInProgressPrefix(oldTitle) || !issue.Repo.HasWorkInProgressPrefix(issue.Title) {
+		return
+	}
+	if err := issue.LoadPullRequest(); err != nil {
+		log.Error("LoadPullRequest: %v", err)
+		return
+	}
+	issue.PullRequest.Issue = issue
+	notification.NotifyPullRequestConvertToDraft(doer, issue.PullRequest)
+}
+
+// ChangePinned pins or unpins this issue, as the given user.
+func ChangePinned(issue *models.Issue, doer *models.User, pinned bool) error {
+	if issue.IsPinned == pinned {
+		return nil
+	}
+
+	if err := issue.ChangePinned(pinned); err != nil {
+		return err
+	}
+
+	notification.NotifyIssueChangePin(doer, issue, pinned)
 
 	return nil
 }
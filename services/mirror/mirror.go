@@ -260,6 +260,7 @@ func runSync(m *models.Mirror) ([]*mirrorSyncResult, bool) {
 
 	for i := range branches {
 		cache.Remove(m.Repo.GetCommitsCountCacheKey(branches[i].Name, true))
+		cache.Remove(m.Repo.GetBranchCommitIDCacheKey(branches[i].Name))
 	}
 
 	m.UpdatedUnix = timeutil.TimeStampNow()
@@ -11,6 +11,7 @@ import (
 	"io/ioutil"
 	"os"
 	"strings"
+	gosync "sync"
 
 	"code.gitea.io/gitea/models"
 	"code.gitea.io/gitea/modules/git"
@@ -27,9 +28,23 @@ import (
 // pullRequestQueue represents a queue to handle update pull request tests
 var pullRequestQueue = sync.NewUniqueQueue(setting.Repository.PullRequestQueueLength)
 
+// settledStatuses holds the status pull requests had before they were queued for a test,
+// by pull request ID, to notify about a changed mergeability once the test finished.
+// It is not persisted, pull requests requeued by a restart are tested without notification.
+var settledStatuses gosync.Map
+
+// rememberSettledStatus keeps the current status of the pull request unless it is
+// already waiting for a test.
+func rememberSettledStatus(pr *models.PullRequest) {
+	if pr.Status != models.PullRequestStatusChecking {
+		settledStatuses.Store(pr.ID, pr.Status)
+	}
+}
+
 // AddToTaskQueue adds itself to pull request test task queue.
 func AddToTaskQueue(pr *models.PullRequest) {
 	go pullRequestQueue.AddFunc(pr.ID, func() {
+		rememberSettledStatus(pr)
 		pr.Status = models.PullRequestStatusChecking
 		if err := pr.UpdateCols("status"); err != nil {
 			log.Error("AddToTaskQueue.UpdateCols[%d].(add to queue): %v", pr.ID, err)
@@ -37,6 +52,13 @@ func AddToTaskQueue(pr *models.PullRequest) {
 	})
 }
 
+// RemoveFromTaskQueue removes the pull request from the test task queue, a pending test
+// of it is skipped. It is meant for pull requests which got closed.
+func RemoveFromTaskQueue(pr *models.PullRequest) {
+	pullRequestQueue.Remove(pr.ID)
+	settledStatuses.Delete(pr.ID)
+}
+
 // checkAndUpdateStatus checks if pull request is possible to leaving checking status,
 // and set to be either conflict or mergeable.
 func checkAndUpdateStatus(pr *models.PullRequest) {
@@ -46,13 +68,57 @@ func checkAndUpdateStatus(pr *models.PullRequest) {
 	}
 
 	// Make sure there is no waiting test to process before leaving the checking status.
-	if !pullRequestQueue.Exist(pr.ID) {
-		if err := pr.UpdateCols("status, conflicted_files"); err != nil {
-			log.Error("Update[%d]: %v", pr.ID, err)
+	if pullRequestQueue.Exist(pr.ID) {
+		return
+	}
+	if err := pr.UpdateCols("merge_base", "status", "conflicted_files", "conflicted_hunks"); err != nil {
+		log.Error("Update[%d]: %v", pr.ID, err)
+		return
+	}
+
+	// Only a transition is notified, repeated tests with the same outcome stay silent.
+	if oldStatus, ok := settledStatuses.Load(pr.ID); ok {
+		settledStatuses.Delete(pr.ID)
+		if oldStatus.(models.PullRequestStatus) != pr.Status {
+			notification.NotifyPullRequestMergeableChanged(pr, oldStatus.(models.PullRequestStatus))
 		}
 	}
 }
 
+// RefreshForBaseRebase recomputes the merge base of the pull request and tests it for
+// conflicts against the current state of its base branch right away, e.g. after the base
+// branch has been rebased. A pending test of it is superseded, a changed mergeability is
+// notified like for a queued test.
+func RefreshForBaseRebase(pr *models.PullRequest) error {
+	pullRequestQueue.Remove(pr.ID)
+	rememberSettledStatus(pr)
+	pr.Status = models.PullRequestStatusChecking
+	if err := pr.UpdateCols("status"); err != nil {
+		return fmt.Errorf("UpdateCols: %v", err)
+	}
+	checkPullRequest(pr)
+	return nil
+}
+
+// RefreshAllPRsForBase tests all open pull requests against the given branch of the base
+// repository. Usually they are added to the test task queue, but if the history of the
+// branch was rewritten their merge bases are stale and they are refreshed one after the
+// other by RefreshForBaseRebase.
+func RefreshAllPRsForBase(baseRepoID int64, branch string, rebased bool) error {
+	prs, err := models.GetUnmergedPullRequestsByBaseInfo(baseRepoID, branch)
+	if err != nil {
+		return fmt.Errorf("GetUnmergedPullRequestsByBaseInfo: %v", err)
+	}
+	for _, pr := range prs {
+		if !rebased {
+			AddToTaskQueue(pr)
+		} else if err := RefreshForBaseRebase(pr); err != nil {
+			log.Error("RefreshForBaseRebase[%d]: %v", pr.ID, err)
+		}
+	}
+	return nil
+}
+
 // getMergeCommit checks if a pull request got merged
 // Returns the git.Commit of the pull request if merged
 func getMergeCommit(pr *models.PullRequest) (*git.Commit, error) {
@@ -155,23 +221,53 @@ func manuallyMerged(pr *models.PullRequest) bool {
 	return false
 }
 
+// checkPullRequest tests the patch of the pull request and stores the resulting status.
+func checkPullRequest(pr *models.PullRequest) {
+	if manuallyMerged(pr) {
+		return
+	}
+	if err := TestPatch(pr); models.IsErrPatchTooLarge(err) {
+		if err := pr.UpdateCols("merge_base", "status", "conflicted_files", "conflicted_hunks"); err != nil {
+			log.Error("update pr [%d] status to PullRequestStatusPatchTooLarge failed: %v", pr.ID, err)
+		}
+		return
+	} else if err != nil {
+		log.Error("testPatch[%d]: %v", pr.ID, err)
+		pr.Status = models.PullRequestStatusError
+		if err := pr.UpdateCols("status"); err != nil {
+			log.Error("update pr [%d] status to PullRequestStatusError failed: %v", pr.ID, err)
+		}
+		return
+	}
+	checkAndUpdateStatus(pr)
+}
+
+// InitPullRequestQueue adds all pull requests which are still in checking status to the
+// test task queue. Tests are not persisted, so such pull requests were abandoned by a restart.
+// It blocks while the queue is full and returns early once the queue has been closed.
+func InitPullRequestQueue() error {
+	prs, err := models.GetPullRequestIDsByCheckStatus(models.PullRequestStatusChecking)
+	if err != nil {
+		return fmt.Errorf("GetPullRequestIDsByCheckStatus: %v", err)
+	}
+	for _, prID := range prs {
+		select {
+		case <-pullRequestQueue.IsClosed():
+			return nil
+		default:
+			pullRequestQueue.Add(prID)
+		}
+	}
+	return nil
+}
+
 // TestPullRequests checks and tests untested patches of pull requests.
 // TODO: test more pull requests at same time.
 func TestPullRequests(ctx context.Context) {
 
 	go func() {
-		prs, err := models.GetPullRequestIDsByCheckStatus(models.PullRequestStatusChecking)
-		if err != nil {
-			log.Error("Find Checking PRs: %v", err)
-			return
-		}
-		for _, prID := range prs {
-			select {
-			case <-ctx.Done():
-				return
-			default:
-				pullRequestQueue.Add(prID)
-			}
+		if err := InitPullRequestQueue(); err != nil {
+			log.Error("InitPullRequestQueue: %v", err)
 		}
 	}()
 
@@ -179,6 +275,10 @@ func TestPullRequests(ctx context.Context) {
 	for {
 		select {
 		case prID := <-pullRequestQueue.Queue():
+			if !pullRequestQueue.Exist(prID) {
+				log.Trace("TestPullRequests[%v]: skipping removed test task", prID)
+				continue
+			}
 			log.Trace("TestPullRequests[%v]: processing test task", prID)
 			pullRequestQueue.Remove(prID)
 
@@ -190,17 +290,8 @@ func TestPullRequests(ctx context.Context) {
 				continue
 			} else if pr.Status != models.PullRequestStatusChecking {
 				continue
-			} else if manuallyMerged(pr) {
-				continue
-			} else if err = TestPatch(pr); err != nil {
-				log.Error("testPatch[%d]: %v", pr.ID, err)
-				pr.Status = models.PullRequestStatusError
-				if err := pr.UpdateCols("status"); err != nil {
-					log.Error("update pr [%d] status to PullRequestStatusError failed: %v", pr.ID, err)
-				}
-				continue
 			}
-			checkAndUpdateStatus(pr)
+			checkPullRequest(pr)
 		case <-ctx.Done():
 			pullRequestQueue.Close()
 			log.Info("PID: %d Pull Request testing shutdown", os.Getpid())
@@ -6,11 +6,16 @@
 package pull
 
 import (
+	"os"
+	"path/filepath"
 	"strconv"
 	"testing"
 	"time"
 
 	"code.gitea.io/gitea/models"
+	"code.gitea.io/gitea/modules/git"
+	"code.gitea.io/gitea/modules/notification"
+	"code.gitea.io/gitea/modules/notification/base"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -32,3 +37,209 @@ func TestPullRequest_AddToTaskQueue(t *testing.T) {
 	pr = models.AssertExistsAndLoadBean(t, &models.PullRequest{ID: 1}).(*models.PullRequest)
 	assert.Equal(t, models.PullRequestStatusChecking, pr.Status)
 }
+
+func TestInitPullRequestQueue(t *testing.T) {
+	assert.NoError(t, models.PrepareTestDatabase())
+
+	pr := models.AssertExistsAndLoadBean(t, &models.PullRequest{ID: 2}).(*models.PullRequest)
+	pr.Status = models.PullRequestStatusChecking
+	assert.NoError(t, pr.UpdateCols("status"))
+
+	assert.NoError(t, InitPullRequestQueue())
+
+	select {
+	case id := <-pullRequestQueue.Queue():
+		assert.EqualValues(t, strconv.FormatInt(pr.ID, 10), id)
+	case <-time.After(time.Second):
+		assert.Fail(t, "Timeout: nothing was added to pullRequestQueue")
+	}
+	assert.True(t, pullRequestQueue.Exist(pr.ID))
+}
+
+func TestPullRequest_RemoveFromTaskQueue(t *testing.T) {
+	assert.NoError(t, models.PrepareTestDatabase())
+
+	pr := models.AssertExistsAndLoadBean(t, &models.PullRequest{ID: 2}).(*models.PullRequest)
+	RemoveFromTaskQueue(pr)
+	AddToTaskQueue(pr)
+
+	select {
+	case id := <-pullRequestQueue.Queue():
+		assert.EqualValues(t, strconv.FormatInt(pr.ID, 10), id)
+	case <-time.After(time.Second):
+		assert.Fail(t, "Timeout: nothing was added to pullRequestQueue")
+	}
+	assert.True(t, pullRequestQueue.Exist(pr.ID))
+
+	RemoveFromTaskQueue(pr)
+	assert.False(t, pullRequestQueue.Exist(pr.ID))
+}
+
+func TestChangeStatus_ClosingRemovesFromTaskQueue(t *testing.T) {
+	models.PrepareTestEnv(t)
+
+	doer := models.AssertExistsAndLoadBean(t, &models.User{ID: 2}).(*models.User)
+	pr := models.AssertExistsAndLoadBean(t, &models.PullRequest{ID: 2}).(*models.PullRequest)
+	AddToTaskQueue(pr)
+	select {
+	case <-pullRequestQueue.Queue():
+	case <-time.After(time.Second):
+		assert.Fail(t, "Timeout: nothing was added to pullRequestQueue")
+	}
+	assert.True(t, pullRequestQueue.Exist(pr.ID))
+
+	assert.NoError(t, pr.LoadIssue())
+	assert.NoError(t, ChangeStatus(pr.Issue, doer, true))
+	assert.False(t, pullRequestQueue.Exist(pr.ID))
+	models.AssertExistsAndLoadBean(t, &models.Issue{ID: pr.IssueID, IsClosed: true})
+}
+
+func TestRefreshAllPRsForBase(t *testing.T) {
+	assert.NoError(t, models.PrepareTestDatabase())
+
+	pr := models.AssertExistsAndLoadBean(t, &models.PullRequest{ID: 2}).(*models.PullRequest)
+	assert.NoError(t, RefreshAllPRsForBase(pr.BaseRepoID, pr.BaseBranch, false))
+
+	// the pull requests are tested by the queue instead of inline
+	select {
+	case id := <-pullRequestQueue.Queue():
+		assert.EqualValues(t, strconv.FormatInt(pr.ID, 10), id)
+	case <-time.After(time.Second):
+		assert.Fail(t, "Timeout: nothing was added to pullRequestQueue")
+	}
+	assert.True(t, pullRequestQueue.Exist(pr.ID))
+	pr = models.AssertExistsAndLoadBean(t, &models.PullRequest{ID: 2}).(*models.PullRequest)
+	assert.Equal(t, models.PullRequestStatusChecking, pr.Status)
+
+	RemoveFromTaskQueue(pr)
+}
+
+func TestRefreshAllPRsForBase_Rebased(t *testing.T) {
+	models.PrepareTestEnv(t)
+
+	notifier := &mergeableChangedNotifier{}
+	notification.RegisterNotifier(notifier)
+
+	pr := models.AssertExistsAndLoadBean(t, &models.PullRequest{ID: 2}).(*models.PullRequest)
+	assert.NotEqual(t, models.PullRequestStatusConflict, pr.Status)
+	AddToTaskQueue(pr)
+	select {
+	case <-pullRequestQueue.Queue():
+	case <-time.After(time.Second):
+		assert.Fail(t, "Timeout: nothing was added to pullRequestQueue")
+	}
+	assert.True(t, pullRequestQueue.Exist(pr.ID))
+
+	// the pull requests are tested inline and the queued test is superseded
+	assert.NoError(t, RefreshAllPRsForBase(pr.BaseRepoID, pr.BaseBranch, true))
+	assert.False(t, pullRequestQueue.Exist(pr.ID))
+	pr = models.AssertExistsAndLoadBean(t, &models.PullRequest{ID: 2}).(*models.PullRequest)
+	assert.NotEqual(t, models.PullRequestStatusChecking, pr.Status)
+	assert.NotEqual(t, "fedcba9876543210", pr.MergeBase)
+	assert.Empty(t, notifier.oldStatuses)
+}
+
+type mergeableChangedNotifier struct {
+	base.NullNotifier
+	oldStatuses []models.PullRequestStatus
+}
+
+func (n *mergeableChangedNotifier) NotifyPullRequestMergeableChanged(pr *models.PullRequest, oldStatus models.PullRequestStatus) {
+	n.oldStatuses = append(n.oldStatuses, oldStatus)
+}
+
+func TestCheckAndUpdateStatus_MergeableChanged(t *testing.T) {
+	assert.NoError(t, models.PrepareTestDatabase())
+
+	notifier := &mergeableChangedNotifier{}
+	notification.RegisterNotifier(notifier)
+
+	// simulates a queued test of the pull request ending up with the given status
+	test := func(pr *models.PullRequest, status models.PullRequestStatus) {
+		AddToTaskQueue(pr)
+		select {
+		case <-pullRequestQueue.Queue():
+		case <-time.After(time.Second):
+			assert.Fail(t, "Timeout: nothing was added to pullRequestQueue")
+		}
+		pullRequestQueue.Remove(pr.ID)
+
+		pr.Status = status
+		checkAndUpdateStatus(pr)
+	}
+
+	pr := models.AssertExistsAndLoadBean(t, &models.PullRequest{ID: 2}).(*models.PullRequest)
+	assert.Equal(t, models.PullRequestStatusMergeable, pr.Status)
+
+	test(pr, models.PullRequestStatusConflict)
+	assert.Equal(t, []models.PullRequestStatus{models.PullRequestStatusMergeable}, notifier.oldStatuses)
+
+	// an unchanged status is not notified again
+	pr = models.AssertExistsAndLoadBean(t, &models.PullRequest{ID: 2}).(*models.PullRequest)
+	test(pr, models.PullRequestStatusConflict)
+	assert.Len(t, notifier.oldStatuses, 1)
+
+	pr = models.AssertExistsAndLoadBean(t, &models.PullRequest{ID: 2}).(*models.PullRequest)
+	test(pr, models.PullRequestStatusChecking)
+	assert.Equal(t, []models.PullRequestStatus{models.PullRequestStatusMergeable, models.PullRequestStatusConflict}, notifier.oldStatuses)
+}
+
+type reopenNotifier struct {
+	base.NullNotifier
+	events   []string
+	statuses []models.PullRequestStatus
+	heads    []string
+}
+
+func (n *reopenNotifier) NotifyIssueChangeStatus(doer *models.User, issue *models.Issue, actionComment *models.Comment, isClosed bool) {
+	if !isClosed {
+		n.events = append(n.events, "reopened")
+		n.statuses = append(n.statuses, issue.PullRequest.Status)
+		head, _ := getHeadCommitID(issue.PullRequest)
+		n.heads = append(n.heads, head)
+	}
+}
+
+func (n *reopenNotifier) NotifyPullRequestSynchronized(doer *models.User, pr *models.PullRequest) {
+	n.events = append(n.events, "synchronized")
+}
+
+func (n *reopenNotifier) NotifyPullRequestMergeableChanged(pr *models.PullRequest, oldStatus models.PullRequestStatus) {
+	n.events = append(n.events, "mergeable:"+strconv.Itoa(int(pr.Status)))
+}
+
+func TestReopen_NotifiesCheckedHead(t *testing.T) {
+	models.PrepareTestEnv(t)
+
+	notifier := &reopenNotifier{}
+	notification.RegisterNotifier(notifier)
+
+	doer := models.AssertExistsAndLoadBean(t, &models.User{ID: 2}).(*models.User)
+	pr := models.AssertExistsAndLoadBean(t, &models.PullRequest{ID: 2}).(*models.PullRequest)
+	assert.NoError(t, pr.LoadIssue())
+	_, err := pr.Issue.ChangeStatus(doer, true)
+	assert.NoError(t, err)
+
+	// the head branch moved on while the pull request was closed
+	repo := models.AssertExistsAndLoadBean(t, &models.Repository{ID: pr.BaseRepoID}).(*models.Repository)
+	gitRepo, err := git.OpenRepository(repo.RepoPath())
+	assert.NoError(t, err)
+	defer gitRepo.Close()
+	branchCommitID, err := gitRepo.GetBranchCommitID(pr.HeadBranch)
+	assert.NoError(t, err)
+	headCommitID, err := gitRepo.GetRefCommitID(pr.GetGitRefName())
+	assert.NoError(t, err)
+	assert.NotEqual(t, branchCommitID, headCommitID)
+	// the hooks call a gitea binary which does not exist in unit tests
+	assert.NoError(t, os.RemoveAll(filepath.Join(repo.RepoPath(), "hooks")))
+
+	assert.NoError(t, ChangeStatus(pr.Issue, doer, false))
+
+	// the reopening carries the pushed head and the tested status, the synchronization follows
+	assert.Equal(t, []string{"reopened", "synchronized"}, notifier.events)
+	assert.Equal(t, []string{branchCommitID}, notifier.heads)
+	pr = models.AssertExistsAndLoadBean(t, &models.PullRequest{ID: 2}).(*models.PullRequest)
+	assert.NotEqual(t, models.PullRequestStatusChecking, pr.Status)
+	assert.Equal(t, []models.PullRequestStatus{pr.Status}, notifier.statuses)
+	assert.False(t, pullRequestQueue.Exist(pr.ID))
+}
@@ -7,7 +7,6 @@ package pull
 
 import (
 	"code.gitea.io/gitea/models"
-	"code.gitea.io/gitea/modules/git"
 	"github.com/pkg/errors"
 )
 
@@ -43,37 +42,9 @@ func IsCommitStatusContextSuccess(commitStatuses []*models.CommitStatus, require
 
 // IsPullCommitStatusPass returns if all required status checks PASS
 func IsPullCommitStatusPass(pr *models.PullRequest) (bool, error) {
-	if err := pr.LoadProtectedBranch(); err != nil {
-		return false, errors.Wrap(err, "GetLatestCommitStatus")
-	}
-	if pr.ProtectedBranch == nil || !pr.ProtectedBranch.EnableStatusCheck {
-		return true, nil
-	}
-
-	// check if all required status checks are successful
-	headGitRepo, err := git.OpenRepository(pr.HeadRepo.RepoPath())
-	if err != nil {
-		return false, errors.Wrap(err, "OpenRepository")
-	}
-	defer headGitRepo.Close()
-
-	if !headGitRepo.IsBranchExist(pr.HeadBranch) {
-		return false, errors.New("Head branch does not exist, can not merge")
-	}
-
-	sha, err := headGitRepo.GetBranchCommitID(pr.HeadBranch)
+	allPassed, _, _, err := pr.RequiredStatusChecksState()
 	if err != nil {
-		return false, errors.Wrap(err, "GetBranchCommitID")
-	}
-
-	if err := pr.LoadBaseRepo(); err != nil {
-		return false, errors.Wrap(err, "LoadBaseRepo")
+		return false, errors.Wrap(err, "RequiredStatusChecksState")
 	}
-
-	commitStatuses, err := models.GetLatestCommitStatus(pr.BaseRepo, sha, 0)
-	if err != nil {
-		return false, errors.Wrap(err, "GetLatestCommitStatus")
-	}
-
-	return IsCommitStatusContextSuccess(commitStatuses, pr.ProtectedBranch.StatusCheckContexts), nil
+	return allPassed, nil
 }
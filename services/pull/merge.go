@@ -13,6 +13,7 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
 	"time"
 
@@ -23,16 +24,92 @@ import (
 	"code.gitea.io/gitea/modules/notification"
 	"code.gitea.io/gitea/modules/references"
 	"code.gitea.io/gitea/modules/setting"
+	"code.gitea.io/gitea/modules/sync"
 	"code.gitea.io/gitea/modules/timeutil"
 	issue_service "code.gitea.io/gitea/services/issue"
 
 	"github.com/mcuadros/go-version"
+	"github.com/unknwon/com"
 )
 
+// pullWorkingPool represents a working pool to make sure the same pull request
+// is not merged more than once at the same time
+var pullWorkingPool = sync.NewExclusivePool()
+
+// MergeIdentity overrides the author and committer of the commit created by a squash merge.
+// Empty fields fall back to the pull request poster as author and the merger as committer.
+type MergeIdentity struct {
+	AuthorName     string
+	AuthorEmail    string
+	CommitterName  string
+	CommitterEmail string
+}
+
+// signatures returns the author and committer signatures to use for a squash commit
+func (identity *MergeIdentity) signatures(poster, doer *models.User) (author, committer *git.Signature) {
+	author = poster.NewGitSig()
+	committer = doer.NewGitSig()
+	if identity == nil {
+		return author, committer
+	}
+	if identity.AuthorName != "" {
+		author.Name = identity.AuthorName
+	}
+	if identity.AuthorEmail != "" {
+		author.Email = identity.AuthorEmail
+	}
+	if identity.CommitterName != "" {
+		committer.Name = identity.CommitterName
+	}
+	if identity.CommitterEmail != "" {
+		committer.Email = identity.CommitterEmail
+	}
+	return author, committer
+}
+
 // Merge merges pull request to base repository.
 // Caller should check PR is ready to be merged (review and status checks)
-// FIXME: add repoWorkingPull make sure two merges does not happen at same time.
-func Merge(pr *models.PullRequest, doer *models.User, baseGitRepo *git.Repository, mergeStyle models.MergeStyle, message string) (err error) {
+func Merge(pr *models.PullRequest, doer *models.User, baseGitRepo *git.Repository, mergeStyle models.MergeStyle, message string, identity *MergeIdentity) (err error) {
+	return merge(pr, doer, baseGitRepo, mergeStyle, message, identity, nil)
+}
+
+// ApplyConflictResolution merges the pull request like Merge, resolving the conflicts of the
+// merge with the given resolved contents of the conflicted files. The resolution must cover
+// exactly the conflicted files. Only the merge and squash styles are supported.
+// Caller should check PR is ready to be merged (review and status checks)
+func ApplyConflictResolution(pr *models.PullRequest, doer *models.User, baseGitRepo *git.Repository, files map[string][]byte, mergeStyle models.MergeStyle, message string) error {
+	if mergeStyle != models.MergeStyleMerge && mergeStyle != models.MergeStyleSquash {
+		if err := pr.GetBaseRepo(); err != nil {
+			return fmt.Errorf("GetBaseRepo: %v", err)
+		}
+		return models.ErrInvalidMergeStyle{ID: pr.BaseRepo.ID, Style: mergeStyle}
+	}
+	if files == nil {
+		files = map[string][]byte{}
+	}
+	return merge(pr, doer, baseGitRepo, mergeStyle, message, nil, files)
+}
+
+// merge merges pull request to base repository, resolving conflicts with resolution if it is not nil
+func merge(pr *models.PullRequest, doer *models.User, baseGitRepo *git.Repository, mergeStyle models.MergeStyle, message string, identity *MergeIdentity, resolution map[string][]byte) (err error) {
+	pullWorkingPool.CheckIn(com.ToStr(pr.ID))
+	defer pullWorkingPool.CheckOut(com.ToStr(pr.ID))
+
+	// A concurrent merge may have finished while we were waiting for the lock
+	merged, err := models.GetPullRequestByID(pr.ID)
+	if err != nil {
+		return fmt.Errorf("GetPullRequestByID: %v", err)
+	}
+	if merged.HasMerged {
+		return models.ErrPullRequestHasMerged{
+			ID:         pr.ID,
+			IssueID:    pr.IssueID,
+			HeadRepoID: pr.HeadRepoID,
+			BaseRepoID: pr.BaseRepoID,
+			HeadBranch: pr.HeadBranch,
+			BaseBranch: pr.BaseBranch,
+		}
+	}
 
 	if err = pr.GetHeadRepo(); err != nil {
 		log.Error("GetHeadRepo: %v", err)
@@ -58,7 +135,7 @@ func Merge(pr *models.PullRequest, doer *models.User, baseGitRepo *git.Repositor
 		go AddTestPullRequestTask(doer, pr.BaseRepo.ID, pr.BaseBranch, false, "", "")
 	}()
 
-	if err := rawMerge(pr, doer, mergeStyle, message); err != nil {
+	if err := rawMerge(pr, doer, mergeStyle, message, identity, resolution); err != nil {
 		return err
 	}
 
@@ -79,6 +156,7 @@ func Merge(pr *models.PullRequest, doer *models.User, baseGitRepo *git.Repositor
 
 	// Reset cached commit count
 	cache.Remove(pr.Issue.Repo.GetCommitsCountCacheKey(pr.BaseBranch, true))
+	cache.Remove(pr.Issue.Repo.GetBranchCommitIDCacheKey(pr.BaseBranch))
 
 	// Resolve cross references
 	refs, err := pr.ResolveCrossReferences()
@@ -96,7 +174,12 @@ func Merge(pr *models.PullRequest, doer *models.User, baseGitRepo *git.Repositor
 		}
 		close := (ref.RefAction == references.XRefActionCloses)
 		if close != ref.Issue.IsClosed {
-			if err = issue_service.ChangeStatus(ref.Issue, doer, close); err != nil {
+			if ref.Issue.IsPull {
+				err = ChangeStatus(ref.Issue, doer, close)
+			} else {
+				err = issue_service.ChangeStatus(ref.Issue, doer, close)
+			}
+			if err != nil {
 				return err
 			}
 		}
@@ -105,8 +188,66 @@ func Merge(pr *models.PullRequest, doer *models.User, baseGitRepo *git.Repositor
 	return nil
 }
 
-// rawMerge perform the merge operation without changing any pull information in database
-func rawMerge(pr *models.PullRequest, doer *models.User, mergeStyle models.MergeStyle, message string) (err error) {
+// CopyForTarget returns a copy of the pull request aimed at targetBranch of its base repository
+// instead of its recorded base branch, to run the merge checks against targetBranch. The pull
+// request itself is left unchanged.
+func CopyForTarget(pr *models.PullRequest, targetBranch string) *models.PullRequest {
+	target := *pr
+	target.BaseBranch = targetBranch
+	target.ProtectedBranch = nil
+	return &target
+}
+
+// MergeInto merges the pull request into targetBranch of its base repository instead of its
+// recorded base branch, which is left unchanged. A copy aimed at targetBranch is merged, see
+// CopyForTarget, once doer is found to be allowed to merge into targetBranch and the copy has
+// been tested for conflicts against it. The merge is recorded with targetBranch as MergedBranch
+// and notified with it as base branch.
+// Caller should check the copy is ready to be merged (review and status checks)
+func MergeInto(pr *models.PullRequest, doer *models.User, baseGitRepo *git.Repository, perm models.Permission, targetBranch string, mergeStyle models.MergeStyle, message string, identity *MergeIdentity) error {
+	if !baseGitRepo.IsBranchExist(targetBranch) {
+		return git.ErrBranchNotExist{Name: targetBranch}
+	}
+	if err := pr.GetBaseRepo(); err != nil {
+		return fmt.Errorf("GetBaseRepo: %v", err)
+	}
+
+	target := CopyForTarget(pr, targetBranch)
+	if allowed, err := IsUserAllowedToMerge(target, perm, doer); err != nil {
+		return fmt.Errorf("IsUserAllowedToMerge: %v", err)
+	} else if !allowed {
+		return models.ErrNotAllowedToMerge{
+			Reason: "User not allowed to merge PR",
+		}
+	}
+	if err := TestPatch(target); err != nil && !models.IsErrPatchTooLarge(err) {
+		return fmt.Errorf("TestPatch: %v", err)
+	}
+	if target.Status == models.PullRequestStatusConflict {
+		return models.ErrMergeConflicts{
+			Style:           mergeStyle,
+			ConflictedFiles: target.ConflictedFiles,
+		}
+	}
+
+	target.MergedBranch = targetBranch
+	if err := Merge(target, doer, baseGitRepo, mergeStyle, message, identity); err != nil {
+		return err
+	}
+
+	pr.HasMerged = target.HasMerged
+	pr.MergedBranch = target.MergedBranch
+	pr.MergedCommitID = target.MergedCommitID
+	pr.MergedUnix = target.MergedUnix
+	pr.Merger = target.Merger
+	pr.MergerID = target.MergerID
+	pr.MergedReviewers = target.MergedReviewers
+	return nil
+}
+
+// rawMerge perform the merge operation without changing any pull information in database.
+// If resolution is not nil, conflicts of the merge and squash styles are resolved with it.
+func rawMerge(pr *models.PullRequest, doer *models.User, mergeStyle models.MergeStyle, message string, identity *MergeIdentity, resolution map[string][]byte) (err error) {
 	binVersion, err := git.BinVersion()
 	if err != nil {
 		log.Error("git.BinVersion: %v", err)
@@ -233,7 +374,7 @@ func rawMerge(pr *models.PullRequest, doer *models.User, mergeStyle models.Merge
 	switch mergeStyle {
 	case models.MergeStyleMerge:
 		cmd := git.NewCommand("merge", "--no-ff", "--no-commit", trackingBranch)
-		if err := runMergeCommand(pr, mergeStyle, cmd, tmpBasePath); err != nil {
+		if err := runMergeCommandWithResolution(pr, mergeStyle, cmd, tmpBasePath, resolution); err != nil {
 			log.Error("Unable to merge tracking into base: %v", err)
 			return err
 		}
@@ -309,25 +450,44 @@ func rawMerge(pr *models.PullRequest, doer *models.User, mergeStyle models.Merge
 	case models.MergeStyleSquash:
 		// Merge with squash
 		cmd := git.NewCommand("merge", "--squash", trackingBranch)
-		if err := runMergeCommand(pr, mergeStyle, cmd, tmpBasePath); err != nil {
+		if err := runMergeCommandWithResolution(pr, mergeStyle, cmd, tmpBasePath, resolution); err != nil {
 			log.Error("Unable to merge --squash tracking into base: %v", err)
 			return err
 		}
 
-		sig := pr.Issue.Poster.NewGitSig()
+		if err = pr.Issue.LoadPoster(); err != nil {
+			log.Error("LoadPoster: %v", err)
+			return fmt.Errorf("LoadPoster: %v", err)
+		}
+		authorSig, committerSig := identity.signatures(pr.Issue.Poster, doer)
+		squashEnv := append(os.Environ(),
+			"GIT_AUTHOR_NAME="+authorSig.Name,
+			"GIT_AUTHOR_EMAIL="+authorSig.Email,
+			"GIT_AUTHOR_DATE="+commitTimeStr,
+			"GIT_COMMITTER_NAME="+committerSig.Name,
+			"GIT_COMMITTER_EMAIL="+committerSig.Email,
+			"GIT_COMMITTER_DATE="+commitTimeStr,
+		)
 		if signArg == "" {
-			if err := git.NewCommand("commit", fmt.Sprintf("--author='%s <%s>'", sig.Name, sig.Email), "-m", message).RunInDirTimeoutEnvPipeline(env, -1, tmpBasePath, &outbuf, &errbuf); err != nil {
+			if err := git.NewCommand("commit", "-m", message).RunInDirTimeoutEnvPipeline(squashEnv, -1, tmpBasePath, &outbuf, &errbuf); err != nil {
 				log.Error("git commit [%s:%s -> %s:%s]: %v\n%s\n%s", pr.HeadRepo.FullName(), pr.HeadBranch, pr.BaseRepo.FullName(), pr.BaseBranch, err, outbuf.String(), errbuf.String())
 				return fmt.Errorf("git commit [%s:%s -> %s:%s]: %v\n%s\n%s", pr.HeadRepo.FullName(), pr.HeadBranch, pr.BaseRepo.FullName(), pr.BaseBranch, err, outbuf.String(), errbuf.String())
 			}
 		} else {
-			if err := git.NewCommand("commit", signArg, fmt.Sprintf("--author='%s <%s>'", sig.Name, sig.Email), "-m", message).RunInDirTimeoutEnvPipeline(env, -1, tmpBasePath, &outbuf, &errbuf); err != nil {
+			if err := git.NewCommand("commit", signArg, "-m", message).RunInDirTimeoutEnvPipeline(squashEnv, -1, tmpBasePath, &outbuf, &errbuf); err != nil {
 				log.Error("git commit [%s:%s -> %s:%s]: %v\n%s\n%s", pr.HeadRepo.FullName(), pr.HeadBranch, pr.BaseRepo.FullName(), pr.BaseBranch, err, outbuf.String(), errbuf.String())
 				return fmt.Errorf("git commit [%s:%s -> %s:%s]: %v\n%s\n%s", pr.HeadRepo.FullName(), pr.HeadBranch, pr.BaseRepo.FullName(), pr.BaseBranch, err, outbuf.String(), errbuf.String())
 			}
 		}
 		outbuf.Reset()
 		errbuf.Reset()
+	case models.MergeStyleFastForwardOnly:
+		// Refuse to create a merge commit if the base branch has diverged
+		cmd := git.NewCommand("merge", "--ff-only", trackingBranch)
+		if err := runMergeCommand(pr, mergeStyle, cmd, tmpBasePath); err != nil {
+			log.Error("Unable to fast-forward base to tracking: %v", err)
+			return err
+		}
 	default:
 		return models.ErrInvalidMergeStyle{ID: pr.BaseRepo.ID, Style: mergeStyle}
 	}
@@ -409,11 +569,21 @@ func commitAndSignNoAuthor(pr *models.PullRequest, message, signArg, tmpBasePath
 func runMergeCommand(pr *models.PullRequest, mergeStyle models.MergeStyle, cmd *git.Command, tmpBasePath string) error {
 	var outbuf, errbuf strings.Builder
 	if err := cmd.RunInDirPipeline(tmpBasePath, &outbuf, &errbuf); err != nil {
-		// Merge will leave a MERGE_HEAD file in the .git folder if there is a conflict
-		if _, statErr := os.Stat(filepath.Join(tmpBasePath, ".git", "MERGE_HEAD")); statErr == nil {
+		// A conflicting merge leaves unmerged files in the index. Unlike MERGE_HEAD,
+		// which git merge --squash never writes, this holds for every merge style.
+		if conflictedFiles := getConflictedFiles(tmpBasePath); len(conflictedFiles) > 0 {
 			// We have a merge conflict error
 			log.Debug("MergeConflict [%s:%s -> %s:%s]: %v\n%s\n%s", pr.HeadRepo.FullName(), pr.HeadBranch, pr.BaseRepo.FullName(), pr.BaseBranch, err, outbuf.String(), errbuf.String())
 			return models.ErrMergeConflicts{
+				Style:           mergeStyle,
+				ConflictedFiles: conflictedFiles,
+				StdOut:          outbuf.String(),
+				StdErr:          errbuf.String(),
+				Err:             err,
+			}
+		} else if strings.Contains(errbuf.String(), "Not possible to fast-forward") {
+			log.Debug("MergeNotFastForward [%s:%s -> %s:%s]: %v\n%s\n%s", pr.HeadRepo.FullName(), pr.HeadBranch, pr.BaseRepo.FullName(), pr.BaseBranch, err, outbuf.String(), errbuf.String())
+			return models.ErrMergeNotFastForward{
 				Style:  mergeStyle,
 				StdOut: outbuf.String(),
 				StdErr: errbuf.String(),
@@ -435,6 +605,81 @@ func runMergeCommand(pr *models.PullRequest, mergeStyle models.MergeStyle, cmd *
 	return nil
 }
 
+// runMergeCommandWithResolution runs the merge command like runMergeCommand. If resolution is not nil,
+// it must cover exactly the files the merge conflicts in, which are then resolved with it.
+func runMergeCommandWithResolution(pr *models.PullRequest, mergeStyle models.MergeStyle, cmd *git.Command, tmpBasePath string, resolution map[string][]byte) error {
+	err := runMergeCommand(pr, mergeStyle, cmd, tmpBasePath)
+	if resolution == nil {
+		return err
+	}
+
+	var conflictedFiles []string
+	if conflictErr, ok := err.(models.ErrMergeConflicts); ok {
+		conflictedFiles = conflictErr.ConflictedFiles
+	} else if err != nil {
+		return err
+	}
+
+	if err := validateConflictResolution(conflictedFiles, resolution); err != nil {
+		return err
+	}
+	if len(conflictedFiles) == 0 {
+		return nil
+	}
+
+	// The conflicted files are part of the sparse checkout as they differ from the base branch
+	for _, file := range conflictedFiles {
+		if err := ioutil.WriteFile(filepath.Join(tmpBasePath, file), resolution[file], 0644); err != nil {
+			return fmt.Errorf("Unable to write resolved file %s: %v", file, err)
+		}
+	}
+	var outbuf, errbuf strings.Builder
+	if err := git.NewCommand(append([]string{"add", "--"}, conflictedFiles...)...).RunInDirPipeline(tmpBasePath, &outbuf, &errbuf); err != nil {
+		log.Error("git add resolved files [%s:%s -> %s:%s]: %v\n%s\n%s", pr.HeadRepo.FullName(), pr.HeadBranch, pr.BaseRepo.FullName(), pr.BaseBranch, err, outbuf.String(), errbuf.String())
+		return fmt.Errorf("git add resolved files [%s:%s -> %s:%s]: %v\n%s\n%s", pr.HeadRepo.FullName(), pr.HeadBranch, pr.BaseRepo.FullName(), pr.BaseBranch, err, outbuf.String(), errbuf.String())
+	}
+	return nil
+}
+
+// validateConflictResolution returns ErrConflictResolutionMismatch unless resolution contains
+// exactly the conflicted files
+func validateConflictResolution(conflictedFiles []string, resolution map[string][]byte) error {
+	mismatch := models.ErrConflictResolutionMismatch{}
+	conflicted := make(map[string]bool, len(conflictedFiles))
+	for _, file := range conflictedFiles {
+		conflicted[file] = true
+		if _, ok := resolution[file]; !ok {
+			mismatch.Missing = append(mismatch.Missing, file)
+		}
+	}
+	for file := range resolution {
+		if !conflicted[file] {
+			mismatch.Unexpected = append(mismatch.Unexpected, file)
+		}
+	}
+	if len(mismatch.Missing) > 0 || len(mismatch.Unexpected) > 0 {
+		sort.Strings(mismatch.Unexpected)
+		return mismatch
+	}
+	return nil
+}
+
+// getConflictedFiles returns the unmerged files of the working tree after a failed merge
+func getConflictedFiles(tmpBasePath string) []string {
+	stdout, err := git.NewCommand("diff", "--name-only", "--diff-filter=U").RunInDir(tmpBasePath)
+	if err != nil {
+		log.Error("git diff --diff-filter=U in %s: %v", tmpBasePath, err)
+		return nil
+	}
+	var files []string
+	for _, file := range strings.Split(stdout, "\n") {
+		if file = strings.TrimSpace(file); len(file) > 0 {
+			files = append(files, file)
+		}
+	}
+	return files
+}
+
 var escapedSymbols = regexp.MustCompile(`([*[?! \\])`)
 
 func getDiffTree(repoPath, baseBranch, headBranch string) (string, error) {
@@ -513,6 +758,74 @@ func IsUserAllowedToMerge(pr *models.PullRequest, p models.Permission, user *mod
 	return false, nil
 }
 
+// CheckUserAllowedToMerge checks whether the user may merge the PR at all. Unlike the checks
+// of CheckPRReadyToMerge these can not be overridden by a repository admin.
+func CheckUserAllowedToMerge(pr *models.PullRequest, p models.Permission, user *models.User) error {
+	allowed, err := IsUserAllowedToMerge(pr, p, user)
+	if err != nil {
+		return fmt.Errorf("IsUserAllowedToMerge: %v", err)
+	}
+	if !allowed {
+		return models.ErrNotAllowedToMerge{
+			Reason: "User not allowed to merge PR",
+		}
+	}
+
+	if pr.ProtectedBranch != nil && pr.ProtectedBranch.RequireSignedCommits {
+		hasUnsigned, unsigned, err := pr.HasUnsignedCommits()
+		if err != nil {
+			return fmt.Errorf("HasUnsignedCommits: %v", err)
+		}
+		if hasUnsigned {
+			return models.ErrUnsignedCommits{SHAs: unsigned}
+		}
+	}
+
+	if pr.BaseRepo == nil {
+		if err = pr.GetBaseRepo(); err != nil {
+			return fmt.Errorf("GetBaseRepo: %v", err)
+		}
+	}
+	prUnit, err := pr.BaseRepo.GetUnit(models.UnitTypePullRequests)
+	if err != nil {
+		return fmt.Errorf("GetUnit: %v", err)
+	}
+	if prUnit.PullRequestsConfig().RequireSignoff {
+		missing, err := pr.MissingSignoffCommits()
+		if err != nil {
+			return fmt.Errorf("MissingSignoffCommits: %v", err)
+		}
+		if len(missing) > 0 {
+			return models.ErrMissingSignoff{SHAs: missing}
+		}
+	}
+	if prUnit.PullRequestsConfig().BlockBinaryFiles {
+		// A patch too large to be checked for binary files is returned as ErrPatchTooLarge
+		binaries, err := pr.GetBinaryChangedFiles()
+		if err != nil {
+			if models.IsErrPatchTooLarge(err) {
+				return err
+			}
+			return fmt.Errorf("GetBinaryChangedFiles: %v", err)
+		}
+		if len(binaries) > 0 {
+			return models.ErrBinaryFilesChanged{Files: binaries}
+		}
+	}
+
+	if pr.ProtectedBranch != nil && pr.ProtectedBranch.BlockOnUnresolvedConversations {
+		unresolved, err := pr.HasUnresolvedConversations()
+		if err != nil {
+			return fmt.Errorf("HasUnresolvedConversations: %v", err)
+		}
+		if unresolved {
+			return models.ErrUnresolvedConversations{ID: pr.ID}
+		}
+	}
+
+	return nil
+}
+
 // CheckPRReadyToMerge checks whether the PR is ready to be merged (reviews and status checks)
 func CheckPRReadyToMerge(pr *models.PullRequest) (err error) {
 	if pr.BaseRepo == nil {
@@ -529,13 +842,20 @@ func CheckPRReadyToMerge(pr *models.PullRequest) (err error) {
 		}
 	}
 
-	isPass, err := IsPullCommitStatusPass(pr)
+	allPassed, missing, failing, err := pr.RequiredStatusChecksState()
 	if err != nil {
-		return err
+		return fmt.Errorf("RequiredStatusChecksState: %v", err)
 	}
-	if !isPass {
+	if !allPassed {
+		reason := "Not all required status checks successful"
+		if len(failing) > 0 {
+			reason += fmt.Sprintf(", failing: %s", strings.Join(failing, ", "))
+		}
+		if len(missing) > 0 {
+			reason += fmt.Sprintf(", missing: %s", strings.Join(missing, ", "))
+		}
 		return models.ErrNotAllowedToMerge{
-			Reason: "Not all required status checks successful",
+			Reason: reason,
 		}
 	}
 
@@ -0,0 +1,266 @@
+// Copyright 2020 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package pull
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"code.gitea.io/gitea/models"
+	"code.gitea.io/gitea/modules/git"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergeIdentity_signatures(t *testing.T) {
+	assert.NoError(t, models.PrepareTestDatabase())
+
+	poster := models.AssertExistsAndLoadBean(t, &models.User{ID: 2}).(*models.User)
+	doer := models.AssertExistsAndLoadBean(t, &models.User{ID: 1}).(*models.User)
+
+	var identity *MergeIdentity
+	author, committer := identity.signatures(poster, doer)
+	assert.Equal(t, poster.GitName(), author.Name)
+	assert.Equal(t, poster.GetEmail(), author.Email)
+	assert.Equal(t, doer.GitName(), committer.Name)
+	assert.Equal(t, doer.GetEmail(), committer.Email)
+
+	identity = &MergeIdentity{
+		AuthorName:     "Author",
+		AuthorEmail:    "author@example.com",
+		CommitterEmail: "committer@example.com",
+	}
+	author, committer = identity.signatures(poster, doer)
+	assert.Equal(t, "Author", author.Name)
+	assert.Equal(t, "author@example.com", author.Email)
+	assert.Equal(t, doer.GitName(), committer.Name)
+	assert.Equal(t, "committer@example.com", committer.Email)
+}
+
+func TestValidateConflictResolution(t *testing.T) {
+	conflicted := []string{"README.md", "src/main.go"}
+
+	assert.NoError(t, validateConflictResolution(conflicted, map[string][]byte{
+		"README.md":   []byte("resolved"),
+		"src/main.go": []byte("package main"),
+	}))
+	assert.NoError(t, validateConflictResolution(nil, map[string][]byte{}))
+
+	err := validateConflictResolution(conflicted, map[string][]byte{
+		"README.md": []byte("resolved"),
+		"LICENSE":   []byte("MIT"),
+	})
+	if assert.True(t, models.IsErrConflictResolutionMismatch(err)) {
+		mismatch := err.(models.ErrConflictResolutionMismatch)
+		assert.EqualValues(t, []string{"src/main.go"}, mismatch.Missing)
+		assert.EqualValues(t, []string{"LICENSE"}, mismatch.Unexpected)
+	}
+}
+
+func TestRunMergeCommand_SquashConflict(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "gitea-merge-conflict")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	commit := func(content string) {
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(tmpDir, "README.md"), []byte(content), 0644))
+		_, err := git.NewCommand("add", "README.md").RunInDir(tmpDir)
+		assert.NoError(t, err)
+		_, err = git.NewCommand("commit", "-m", content).RunInDir(tmpDir)
+		assert.NoError(t, err)
+	}
+	_, err = git.NewCommand("init").RunInDir(tmpDir)
+	assert.NoError(t, err)
+	_, err = git.NewCommand("config", "user.name", "Gitea").RunInDir(tmpDir)
+	assert.NoError(t, err)
+	_, err = git.NewCommand("config", "user.email", "gitea@example.com").RunInDir(tmpDir)
+	assert.NoError(t, err)
+	commit("base\n")
+	_, err = git.NewCommand("checkout", "-b", "head_repo").RunInDir(tmpDir)
+	assert.NoError(t, err)
+	commit("head\n")
+	_, err = git.NewCommand("checkout", "-b", "base", "HEAD~1").RunInDir(tmpDir)
+	assert.NoError(t, err)
+	commit("base changed\n")
+
+	pr := &models.PullRequest{
+		HeadRepo:   &models.Repository{OwnerName: "user2", Name: "repo1"},
+		HeadBranch: "branch1",
+		BaseRepo:   &models.Repository{OwnerName: "user2", Name: "repo1"},
+		BaseBranch: "master",
+	}
+
+	// git merge --squash does not write MERGE_HEAD, the conflict must still be detected
+	err = runMergeCommand(pr, models.MergeStyleSquash, git.NewCommand("merge", "--squash", "head_repo"), tmpDir)
+	if assert.True(t, models.IsErrMergeConflicts(err)) {
+		assert.EqualValues(t, []string{"README.md"}, err.(models.ErrMergeConflicts).ConflictedFiles)
+	}
+	_, err = os.Stat(filepath.Join(tmpDir, ".git", "MERGE_HEAD"))
+	assert.True(t, os.IsNotExist(err))
+
+	_, err = git.NewCommand("reset", "--hard").RunInDir(tmpDir)
+	assert.NoError(t, err)
+	err = runMergeCommandWithResolution(pr, models.MergeStyleSquash, git.NewCommand("merge", "--squash", "head_repo"), tmpDir, map[string][]byte{
+		"README.md": []byte("resolved\n"),
+	})
+	assert.NoError(t, err)
+	assert.Empty(t, getConflictedFiles(tmpDir))
+	stdout, err := git.NewCommand("diff", "--cached", "--name-only").RunInDir(tmpDir)
+	assert.NoError(t, err)
+	assert.Equal(t, "README.md\n", stdout)
+	content, err := ioutil.ReadFile(filepath.Join(tmpDir, "README.md"))
+	assert.NoError(t, err)
+	assert.Equal(t, "resolved\n", string(content))
+}
+
+func TestCheckUserAllowedToMerge(t *testing.T) {
+	models.PrepareTestEnv(t)
+	pr := models.AssertExistsAndLoadBean(t, &models.PullRequest{ID: 2}).(*models.PullRequest)
+	assert.NoError(t, pr.GetBaseRepo())
+	owner := models.AssertExistsAndLoadBean(t, &models.User{ID: 2}).(*models.User)
+	stranger := models.AssertExistsAndLoadBean(t, &models.User{ID: 5}).(*models.User)
+
+	perm, err := models.GetUserRepoPermission(pr.BaseRepo, stranger)
+	assert.NoError(t, err)
+	assert.True(t, models.IsErrNotAllowedToMerge(CheckUserAllowedToMerge(pr, perm, stranger)))
+
+	perm, err = models.GetUserRepoPermission(pr.BaseRepo, owner)
+	assert.NoError(t, err)
+	assert.NoError(t, CheckUserAllowedToMerge(pr, perm, owner))
+
+	// the commits of the fixture repository are not signed
+	pr.MergeBase = "65f1bf27bc3bf70f64657658635e66094edbcb4d"
+	pr.ProtectedBranch = &models.ProtectedBranch{RepoID: pr.BaseRepoID, BranchName: pr.BaseBranch, RequireSignedCommits: true}
+	err = CheckUserAllowedToMerge(pr, perm, owner)
+	assert.True(t, models.IsErrUnsignedCommits(err))
+	assert.NotEmpty(t, err.(models.ErrUnsignedCommits).SHAs)
+
+	// a failing signature requirement is not left to CheckPRReadyToMerge where admins could override it
+	assert.NoError(t, CheckPRReadyToMerge(pr))
+}
+
+func TestCheckUserAllowedToMerge_UnresolvedConversations(t *testing.T) {
+	assert.NoError(t, models.PrepareTestDatabase())
+	pr := models.AssertExistsAndLoadBean(t, &models.PullRequest{ID: 1}).(*models.PullRequest)
+	assert.NoError(t, pr.GetBaseRepo())
+	owner := models.AssertExistsAndLoadBean(t, &models.User{ID: 2}).(*models.User)
+	perm, err := models.GetUserRepoPermission(pr.BaseRepo, owner)
+	assert.NoError(t, err)
+
+	pr.ProtectedBranch = &models.ProtectedBranch{RepoID: pr.BaseRepoID, BranchName: pr.BaseBranch, BlockOnUnresolvedConversations: true}
+	assert.True(t, models.IsErrUnresolvedConversations(CheckUserAllowedToMerge(pr, perm, owner)))
+	// unresolved conversations are not left to CheckPRReadyToMerge where admins could override them
+	assert.NoError(t, CheckPRReadyToMerge(pr))
+
+	comment := models.AssertExistsAndLoadBean(t, &models.Comment{ID: 5}).(*models.Comment)
+	assert.NoError(t, models.MarkConversation(comment, true))
+	assert.NoError(t, CheckUserAllowedToMerge(pr, perm, owner))
+}
+
+func TestCheckUserAllowedToMerge_MissingSignoff(t *testing.T) {
+	models.PrepareTestEnv(t)
+	pr := models.AssertExistsAndLoadBean(t, &models.PullRequest{ID: 2}).(*models.PullRequest)
+	assert.NoError(t, pr.GetBaseRepo())
+	owner := models.AssertExistsAndLoadBean(t, &models.User{ID: 2}).(*models.User)
+	perm, err := models.GetUserRepoPermission(pr.BaseRepo, owner)
+	assert.NoError(t, err)
+	pr.MergeBase = "65f1bf27bc3bf70f64657658635e66094edbcb4d"
+
+	prUnit, err := pr.BaseRepo.GetUnit(models.UnitTypePullRequests)
+	assert.NoError(t, err)
+	prUnit.PullRequestsConfig().RequireSignoff = true
+
+	// the commit of the fixture pull request carries no Signed-off-by line
+	err = CheckUserAllowedToMerge(pr, perm, owner)
+	assert.True(t, models.IsErrMissingSignoff(err))
+	assert.Equal(t, []string{"4a357436d925b5c974181ff12a994538ddc5a269"}, err.(models.ErrMissingSignoff).SHAs)
+	// a missing sign-off is not left to CheckPRReadyToMerge where admins could override it
+	assert.NoError(t, CheckPRReadyToMerge(pr))
+
+	prUnit.PullRequestsConfig().RequireSignoff = false
+	assert.NoError(t, CheckUserAllowedToMerge(pr, perm, owner))
+}
+
+func TestCheckUserAllowedToMerge_BinaryFiles(t *testing.T) {
+	models.PrepareTestEnv(t)
+	pr := models.AssertExistsAndLoadBean(t, &models.PullRequest{ID: 2}).(*models.PullRequest)
+	assert.NoError(t, pr.GetBaseRepo())
+	owner := models.AssertExistsAndLoadBean(t, &models.User{ID: 2}).(*models.User)
+	perm, err := models.GetUserRepoPermission(pr.BaseRepo, owner)
+	assert.NoError(t, err)
+	pr.MergeBase = "65f1bf27bc3bf70f64657658635e66094edbcb4d"
+
+	prUnit, err := pr.BaseRepo.GetUnit(models.UnitTypePullRequests)
+	assert.NoError(t, err)
+	prUnit.PullRequestsConfig().BlockBinaryFiles = true
+	assert.NoError(t, CheckUserAllowedToMerge(pr, perm, owner))
+
+	// add a commit with a binary file to the head of the pull request
+	repoPath := pr.BaseRepo.RepoPath()
+	tmpDir, err := ioutil.TempDir("", "binary")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(tmpDir, "logo.bin"), []byte{0x89, 'P', 'N', 'G', 0, 0, 0, 0}, 0644))
+	env := append(os.Environ(), "GIT_INDEX_FILE="+filepath.Join(tmpDir, "index"),
+		"GIT_AUTHOR_NAME=User Two", "GIT_AUTHOR_EMAIL=user2@example.com",
+		"GIT_COMMITTER_NAME=User Two", "GIT_COMMITTER_EMAIL=user2@example.com")
+	head, err := git.NewCommand("rev-parse", pr.GetGitRefName()).RunInDir(repoPath)
+	assert.NoError(t, err)
+	head = strings.TrimSpace(head)
+	blob, err := git.NewCommand("hash-object", "-w", filepath.Join(tmpDir, "logo.bin")).RunInDir(repoPath)
+	assert.NoError(t, err)
+	_, err = git.NewCommand("read-tree", head).RunInDirWithEnv(repoPath, env)
+	assert.NoError(t, err)
+	_, err = git.NewCommand("update-index", "--add", "--cacheinfo", "100644,"+strings.TrimSpace(blob)+",logo.bin").RunInDirWithEnv(repoPath, env)
+	assert.NoError(t, err)
+	tree, err := git.NewCommand("write-tree").RunInDirWithEnv(repoPath, env)
+	assert.NoError(t, err)
+	commit, err := git.NewCommand("commit-tree", strings.TrimSpace(tree), "-p", head, "-m", "add a binary file").RunInDirWithEnv(repoPath, env)
+	assert.NoError(t, err)
+	_, err = git.NewCommand("update-ref", pr.GetGitRefName(), strings.TrimSpace(commit)).RunInDir(repoPath)
+	assert.NoError(t, err)
+
+	err = CheckUserAllowedToMerge(pr, perm, owner)
+	assert.True(t, models.IsErrBinaryFilesChanged(err))
+	assert.Equal(t, []string{"logo.bin"}, err.(models.ErrBinaryFilesChanged).Files)
+	// blocked binary files are not left to CheckPRReadyToMerge where admins could override them
+	assert.NoError(t, CheckPRReadyToMerge(pr))
+
+	// changes too large to be checked can not be merged either
+	pr.Status = models.PullRequestStatusPatchTooLarge
+	assert.True(t, models.IsErrPatchTooLarge(CheckUserAllowedToMerge(pr, perm, owner)))
+
+	prUnit.PullRequestsConfig().BlockBinaryFiles = false
+	assert.NoError(t, CheckUserAllowedToMerge(pr, perm, owner))
+}
+
+func TestMergeInto_Checks(t *testing.T) {
+	models.PrepareTestEnv(t)
+
+	doer := models.AssertExistsAndLoadBean(t, &models.User{ID: 2}).(*models.User)
+	pr := models.AssertExistsAndLoadBean(t, &models.PullRequest{ID: 2}).(*models.PullRequest)
+	assert.NoError(t, pr.GetBaseRepo())
+	gitRepo, err := git.OpenRepository(pr.BaseRepo.RepoPath())
+	assert.NoError(t, err)
+	defer gitRepo.Close()
+
+	target := CopyForTarget(pr, "develop")
+	assert.Equal(t, "develop", target.BaseBranch)
+	assert.Equal(t, "master", pr.BaseBranch)
+
+	err = MergeInto(pr, doer, gitRepo, models.Permission{}, "not-a-branch", models.MergeStyleMerge, "", nil)
+	assert.True(t, git.IsErrBranchNotExist(err))
+
+	err = MergeInto(pr, doer, gitRepo, models.Permission{}, "develop", models.MergeStyleMerge, "", nil)
+	assert.True(t, models.IsErrNotAllowedToMerge(err))
+
+	// the recorded base branch is left unchanged
+	assert.Equal(t, "master", pr.BaseBranch)
+	assert.False(t, pr.HasMerged)
+	models.AssertExistsAndLoadBean(t, &models.PullRequest{ID: pr.ID, BaseBranch: "master", HasMerged: false})
+}
@@ -10,13 +10,13 @@ import (
 	"context"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"os"
 	"strings"
 
 	"code.gitea.io/gitea/models"
 	"code.gitea.io/gitea/modules/git"
 	"code.gitea.io/gitea/modules/log"
+	"code.gitea.io/gitea/modules/setting"
 )
 
 // DownloadDiff will write the patch for the pr to the writer
@@ -68,7 +68,31 @@ var patchErrorSuffices = []string{
 	"unrecognized input",
 }
 
-// TestPatch will test whether a simple patch will apply
+// limitedWriter counts the bytes written through it and fails writes once more than
+// limit bytes have been written in total. A limit <= 0 disables the check.
+type limitedWriter struct {
+	w        io.Writer
+	limit    int64
+	written  int64
+	exceeded bool
+}
+
+func (l *limitedWriter) Write(p []byte) (int, error) {
+	if l.limit > 0 && l.written+int64(len(p)) > l.limit {
+		l.exceeded = true
+		return 0, fmt.Errorf("write exceeds limit of %d bytes", l.limit)
+	}
+	n, err := l.w.Write(p)
+	l.written += int64(n)
+	return n, err
+}
+
+// TestPatch will test whether a simple patch will apply. If the patch is larger than
+// setting.Repository.PullRequest.MaxPatchSize the pull request is not checked, its status
+// is set to PullRequestStatusPatchTooLarge and ErrPatchTooLarge is returned.
+//
+// The patch is never held in memory or written to disk: the diff is piped straight into
+// the stdin of git apply --check.
 func TestPatch(pr *models.PullRequest) error {
 	// Clone base repo.
 	tmpBasePath, err := createTemporaryRepo(pr)
@@ -97,36 +121,6 @@ func TestPatch(pr *models.PullRequest) error {
 		}
 	}
 	pr.MergeBase = strings.TrimSpace(pr.MergeBase)
-	tmpPatchFile, err := ioutil.TempFile("", "patch")
-	if err != nil {
-		log.Error("Unable to create temporary patch file! Error: %v", err)
-		return fmt.Errorf("Unable to create temporary patch file! Error: %v", err)
-	}
-	defer func() {
-		_ = os.Remove(tmpPatchFile.Name())
-	}()
-
-	if err := gitRepo.GetDiff(pr.MergeBase, "tracking", tmpPatchFile); err != nil {
-		tmpPatchFile.Close()
-		log.Error("Unable to get patch file from %s to %s in %s Error: %v", pr.MergeBase, pr.HeadBranch, pr.BaseRepo.FullName(), err)
-		return fmt.Errorf("Unable to get patch file from %s to %s in %s Error: %v", pr.MergeBase, pr.HeadBranch, pr.BaseRepo.FullName(), err)
-	}
-	stat, err := tmpPatchFile.Stat()
-	if err != nil {
-		tmpPatchFile.Close()
-		return fmt.Errorf("Unable to stat patch file: %v", err)
-	}
-	patchPath := tmpPatchFile.Name()
-	tmpPatchFile.Close()
-
-	if stat.Size() == 0 {
-		log.Debug("PullRequest[%d]: Patch is empty - ignoring", pr.ID)
-		pr.Status = models.PullRequestStatusMergeable
-		pr.ConflictedFiles = []string{}
-		return nil
-	}
-
-	log.Trace("PullRequest[%d].testPatch (patchPath): %s", pr.ID, patchPath)
 
 	pr.Status = models.PullRequestStatusChecking
 
@@ -142,31 +136,94 @@ func TestPatch(pr *models.PullRequest) error {
 	prConfig := prUnit.PullRequestsConfig()
 
 	args := []string{"apply", "--check", "--cached"}
-	if prConfig.IgnoreWhitespaceConflicts {
+	switch prConfig.GetWhitespaceConflictMode() {
+	case models.WhitespaceConflictsIgnoreAll:
 		args = append(args, "--ignore-whitespace")
+	case models.WhitespaceConflictsIgnoreEOL:
+		// When fixing whitespace errors git apply also matches context lines
+		// which only differ by such errors, e.g. trailing whitespace.
+		args = append(args, "--whitespace=fix")
+	}
+
+	maxPatchSize := setting.Repository.PullRequest.MaxPatchSize
+	patchReader, patchWriter := io.Pipe()
+	lw := &limitedWriter{w: patchWriter, limit: maxPatchSize}
+	diffErr := make(chan error, 1)
+	go func() {
+		err := gitRepo.GetDiff(pr.MergeBase, "tracking", lw)
+		_ = patchWriter.CloseWithError(err)
+		diffErr <- err
+	}()
+
+	log.Trace("PullRequest[%d].testPatch: streaming diff %s...tracking", pr.ID, pr.MergeBase)
+	conflictedFiles, conflictedHunks, conflict, applyErr := checkPatch(tmpBasePath, args, patchReader, setting.Repository.PullRequest.MaxConflictedHunks)
+	// git apply may exit without consuming the whole patch, unblock the diff
+	_ = patchReader.Close()
+	if err := <-diffErr; err != nil {
+		if lw.exceeded {
+			log.Debug("PullRequest[%d]: Patch exceeds %d bytes - not checking", pr.ID, maxPatchSize)
+			pr.Status = models.PullRequestStatusPatchTooLarge
+			pr.ConflictedFiles = []string{}
+			pr.ConflictedHunks = map[string][]string{}
+			return models.ErrPatchTooLarge{ID: pr.ID, MaxSize: maxPatchSize}
+		}
+		if applyErr == nil || lw.written == 0 {
+			log.Error("Unable to get patch file from %s to %s in %s Error: %v", pr.MergeBase, pr.HeadBranch, pr.BaseRepo.FullName(), err)
+			return fmt.Errorf("Unable to get patch file from %s to %s in %s Error: %v", pr.MergeBase, pr.HeadBranch, pr.BaseRepo.FullName(), err)
+		}
+		// git apply has already rejected the patch and closed its input
+	}
+
+	if lw.written == 0 {
+		log.Debug("PullRequest[%d]: Patch is empty - ignoring", pr.ID)
+		pr.Status = models.PullRequestStatusMergeable
+		pr.ConflictedFiles = []string{}
+		pr.ConflictedHunks = map[string][]string{}
+		return nil
 	}
-	args = append(args, patchPath)
-	pr.ConflictedFiles = make([]string, 0, 5)
+
+	pr.ConflictedFiles = conflictedFiles
+	pr.ConflictedHunks = conflictedHunks
+	if applyErr != nil {
+		if conflict {
+			pr.Status = models.PullRequestStatusConflict
+			log.Trace("Found %d files conflicted: %v", len(pr.ConflictedFiles), pr.ConflictedFiles)
+			return nil
+		}
+		return fmt.Errorf("git apply --check: %v", applyErr)
+	}
+	pr.Status = models.PullRequestStatusMergeable
+
+	return nil
+}
+
+// checkPatch runs the given git apply --check arguments in tmpBasePath, reading the patch
+// from patch, and returns up to 10 of the files which failed to apply. For these files the
+// line numbers of up to maxHunks failing hunks in total are returned as well.
+func checkPatch(tmpBasePath string, args []string, patch io.Reader, maxHunks int) (conflictedFiles []string, conflictedHunks map[string][]string, conflict bool, err error) {
+	args = append(args, "-")
+	conflictedFiles = make([]string, 0, 5)
+	conflictedHunks = map[string][]string{}
 
 	stderrReader, stderrWriter, err := os.Pipe()
 	if err != nil {
 		log.Error("Unable to open stderr pipe: %v", err)
-		return fmt.Errorf("Unable to open stderr pipe: %v", err)
+		return nil, nil, false, fmt.Errorf("Unable to open stderr pipe: %v", err)
 	}
 	defer func() {
 		_ = stderrReader.Close()
 		_ = stderrWriter.Close()
 	}()
-	conflict := false
 	err = git.NewCommand(args...).
 		RunInDirTimeoutEnvFullPipelineFunc(
 			nil, -1, tmpBasePath,
-			nil, stderrWriter, nil,
+			nil, stderrWriter, patch,
 			func(ctx context.Context, cancel context.CancelFunc) error {
 				_ = stderrWriter.Close()
 				const prefix = "error: patch failed:"
 				const errorPrefix = "error: "
 				conflictMap := map[string]bool{}
+				numHunks := 0
 
 				scanner := bufio.NewScanner(stderrReader)
 				for scanner.Scan() {
@@ -175,6 +232,11 @@ func TestPatch(pr *models.PullRequest) error {
 						conflict = true
 						filepath := strings.TrimSpace(strings.Split(line[len(prefix):], ":")[0])
 						conflictMap[filepath] = true
+						// the failing hunk is reported as "file:line"
+						if i := strings.LastIndexByte(line, ':'); numHunks < maxHunks && i > len(prefix) {
+							conflictedHunks[filepath] = append(conflictedHunks[filepath], line[i+1:])
+							numHunks++
+						}
 					} else if strings.HasPrefix(line, errorPrefix) {
 						conflict = true
 						for _, suffix := range patchErrorSuffices {
@@ -193,24 +255,13 @@ func TestPatch(pr *models.PullRequest) error {
 					}
 				}
 				if len(conflictMap) > 0 {
-					pr.ConflictedFiles = make([]string, 0, len(conflictMap))
+					conflictedFiles = make([]string, 0, len(conflictMap))
 					for key := range conflictMap {
-						pr.ConflictedFiles = append(pr.ConflictedFiles, key)
+						conflictedFiles = append(conflictedFiles, key)
 					}
 				}
 				_ = stderrReader.Close()
 				return nil
 			})
-
-	if err != nil {
-		if conflict {
-			pr.Status = models.PullRequestStatusConflict
-			log.Trace("Found %d files conflicted: %v", len(pr.ConflictedFiles), pr.ConflictedFiles)
-			return nil
-		}
-		return fmt.Errorf("git apply --check: %v", err)
-	}
-	pr.Status = models.PullRequestStatusMergeable
-
-	return nil
+	return conflictedFiles, conflictedHunks, conflict, err
 }
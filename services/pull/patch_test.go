@@ -0,0 +1,118 @@
+// Copyright 2020 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package pull
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"code.gitea.io/gitea/modules/git"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimitedWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := &limitedWriter{w: &buf, limit: 8}
+
+	n, err := w.Write([]byte("diff"))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 4, n)
+	assert.False(t, w.exceeded)
+
+	n, err = w.Write([]byte("--git"))
+	assert.Error(t, err)
+	assert.EqualValues(t, 0, n)
+	assert.True(t, w.exceeded)
+	assert.EqualValues(t, "diff", buf.String())
+}
+
+func TestCheckPatch(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "gitea-check-patch")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	_, err = git.NewCommand("init").RunInDir(tmpDir)
+	assert.NoError(t, err)
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(tmpDir, "README.md"), []byte("line 1\nline 2\nline 3\n"), 0644))
+	_, err = git.NewCommand("add", "README.md").RunInDir(tmpDir)
+	assert.NoError(t, err)
+
+	args := []string{"apply", "--check", "--cached"}
+	kases := []struct {
+		patch    string
+		conflict bool
+		files    []string
+		hunks    map[string][]string
+	}{
+		{
+			patch: `diff --git a/README.md b/README.md
+--- a/README.md
++++ b/README.md
+@@ -1,3 +1,3 @@
+ line 1
+-line 2
++line two
+ line 3
+`,
+		},
+		{
+			patch: `diff --git a/README.md b/README.md
+--- a/README.md
++++ b/README.md
+@@ -1,3 +1,3 @@
+ line 1
+-line 20
++line two
+ line 3
+`,
+			conflict: true,
+			files:    []string{"README.md"},
+			hunks:    map[string][]string{"README.md": {"1"}},
+		},
+		{
+			patch: `diff --git a/README.md b/README.md
+new file mode 100644
+--- /dev/null
++++ b/README.md
+@@ -0,0 +1 @@
++readme
+`,
+			conflict: true,
+			files:    []string{"README.md"},
+			hunks:    map[string][]string{},
+		},
+		{
+			patch:    "not a patch\n",
+			conflict: true,
+			files:    []string{},
+			hunks:    map[string][]string{},
+		},
+	}
+	for _, kase := range kases {
+		files, hunks, conflict, err := checkPatch(tmpDir, args, strings.NewReader(kase.patch), 10)
+		assert.Equal(t, kase.conflict, conflict, kase.patch)
+		if kase.conflict {
+			assert.Error(t, err)
+			assert.Equal(t, kase.files, files, kase.patch)
+			assert.Equal(t, kase.hunks, hunks, kase.patch)
+		} else {
+			assert.NoError(t, err)
+			assert.Empty(t, files)
+			assert.Empty(t, hunks)
+		}
+	}
+
+	// the failing hunks are capped, the conflicted files are not
+	files, hunks, conflict, err := checkPatch(tmpDir, args, strings.NewReader(kases[1].patch), 0)
+	assert.Error(t, err)
+	assert.True(t, conflict)
+	assert.Equal(t, []string{"README.md"}, files)
+	assert.Empty(t, hunks)
+}
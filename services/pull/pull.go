@@ -19,6 +19,8 @@ import (
 	"code.gitea.io/gitea/modules/graceful"
 	"code.gitea.io/gitea/modules/log"
 	"code.gitea.io/gitea/modules/notification"
+	repo_module "code.gitea.io/gitea/modules/repository"
+	"code.gitea.io/gitea/modules/setting"
 	issue_service "code.gitea.io/gitea/services/issue"
 
 	"github.com/unknwon/com"
@@ -26,7 +28,12 @@ import (
 
 // NewPullRequest creates new pull request with labels for repository.
 func NewPullRequest(repo *models.Repository, pull *models.Issue, labelIDs []int64, uuids []string, pr *models.PullRequest, assigneeIDs []int64) error {
-	if err := TestPatch(pr); err != nil {
+	if pr.IsSameRef() {
+		return models.ErrPullRequestSameRef{RepoID: pr.BaseRepoID, Branch: pr.BaseBranch}
+	}
+
+	// a pull request with a too large patch is still created, but not checked
+	if err := TestPatch(pr); err != nil && !models.IsErrPatchTooLarge(err) {
 		return err
 	}
 
@@ -111,7 +118,7 @@ func ChangeTargetBranch(pr *models.PullRequest, doer *models.User, targetBranch
 	pr.BaseBranch = targetBranch
 
 	// Refresh patch
-	if err := TestPatch(pr); err != nil {
+	if err := TestPatch(pr); err != nil && !models.IsErrPatchTooLarge(err) {
 		return err
 	}
 
@@ -120,7 +127,7 @@ func ChangeTargetBranch(pr *models.PullRequest, doer *models.User, targetBranch
 	if pr.Status == models.PullRequestStatusChecking {
 		pr.Status = models.PullRequestStatusMergeable
 	}
-	if err := pr.UpdateCols("status, conflicted_files, base_branch"); err != nil {
+	if err := pr.UpdateCols("status, conflicted_files, conflicted_hunks, base_branch"); err != nil {
 		return err
 	}
 
@@ -221,14 +228,18 @@ func AddTestPullRequestTask(doer *models.User, repoID int64, branch string, isSy
 
 		addHeadRepoTasks(prs)
 
-		log.Trace("AddTestPullRequestTask [base_repo_id: %d, base_branch: %s]: finding pull requests", repoID, branch)
-		prs, err = models.GetUnmergedPullRequestsByBaseInfo(repoID, branch)
-		if err != nil {
-			log.Error("Find pull requests [base_repo_id: %d, base_branch: %s]: %v", repoID, branch, err)
-			return
+		var rebased bool
+		if isSync {
+			if repo, err := models.GetRepositoryByID(repoID); err != nil {
+				log.Error("GetRepositoryByID[%d]: %v", repoID, err)
+			} else if rebased, err = models.IsForcePush(repo.RepoPath(), oldCommitID, newCommitID); err != nil {
+				log.Error("IsForcePush[%d, %s]: %v", repoID, branch, err)
+			}
 		}
-		for _, pr := range prs {
-			AddToTaskQueue(pr)
+
+		log.Trace("AddTestPullRequestTask [base_repo_id: %d, base_branch: %s]: finding pull requests", repoID, branch)
+		if err := RefreshAllPRsForBase(repoID, branch, rebased); err != nil {
+			log.Error("RefreshAllPRsForBase [base_repo_id: %d, base_branch: %s]: %v", repoID, branch, err)
 		}
 	})
 }
@@ -254,20 +265,27 @@ func checkIfPRContentChanged(pr *models.PullRequest, oldCommitID, newCommitID st
 	}
 	defer headGitRepo.Close()
 
-	// Add a temporary remote.
-	tmpRemote := "checkIfPRContentChanged-" + com.ToStr(time.Now().UnixNano())
-	if err = headGitRepo.AddRemote(tmpRemote, pr.BaseRepo.RepoPath(), true); err != nil {
-		return false, fmt.Errorf("AddRemote: %s/%s-%s: %v", pr.HeadRepo.OwnerName, pr.HeadRepo.Name, tmpRemote, err)
-	}
-	defer func() {
-		if err := headGitRepo.RemoveRemote(tmpRemote); err != nil {
-			log.Error("checkIfPRContentChanged: RemoveRemote: %s/%s-%s: %v", pr.HeadRepo.OwnerName, pr.HeadRepo.Name, tmpRemote, err)
+	var base string
+	if pr.IsSameRepo() {
+		// The base branch is in the head repository already, no remote is needed
+		if base, err = pr.GetMergeBaseDirect(); err != nil {
+			return false, fmt.Errorf("GetMergeBaseDirect: %v", err)
+		}
+	} else {
+		// Add a temporary remote, the new head has not been pushed to the base repository yet.
+		tmpRemote := "checkIfPRContentChanged-" + com.ToStr(time.Now().UnixNano())
+		if err = headGitRepo.AddRemote(tmpRemote, pr.BaseRepo.RepoPath(), true); err != nil {
+			return false, fmt.Errorf("AddRemote: %s/%s-%s: %v", pr.HeadRepo.OwnerName, pr.HeadRepo.Name, tmpRemote, err)
+		}
+		defer func() {
+			if err := headGitRepo.RemoveRemote(tmpRemote); err != nil {
+				log.Error("checkIfPRContentChanged: RemoveRemote: %s/%s-%s: %v", pr.HeadRepo.OwnerName, pr.HeadRepo.Name, tmpRemote, err)
+			}
+		}()
+		// To synchronize repo and get a base ref
+		if _, base, err = headGitRepo.GetMergeBase(tmpRemote, pr.BaseBranch, pr.HeadBranch); err != nil {
+			return false, fmt.Errorf("GetMergeBase: %v", err)
 		}
-	}()
-	// To synchronize repo and get a base ref
-	_, base, err := headGitRepo.GetMergeBase(tmpRemote, pr.BaseBranch, pr.HeadBranch)
-	if err != nil {
-		return false, fmt.Errorf("GetMergeBase: %v", err)
 	}
 
 	diffBefore := &bytes.Buffer{}
@@ -343,15 +361,252 @@ func PushToBaseRepo(pr *models.PullRequest) (err error) {
 		return fmt.Errorf("unable to load poster %d for pr %d: %v", pr.Issue.PosterID, pr.ID, err)
 	}
 
-	if err = git.Push(headRepoPath, git.PushOptions{
-		Remote: tmpRemoteName,
-		Branch: fmt.Sprintf("%s:%s", pr.HeadBranch, headFile),
-		Force:  true,
-		// Use InternalPushingEnvironment here because we know that pre-receive and post-receive do not run on a refs/pulls/...
-		Env: models.InternalPushingEnvironment(pr.Issue.Poster, pr.BaseRepo),
+	if err = retryPush(setting.Repository.PullRequest.PushRetryAttempts, setting.Repository.PullRequest.PushRetryBackoff, func() error {
+		return git.Push(headRepoPath, git.PushOptions{
+			Remote: tmpRemoteName,
+			Branch: fmt.Sprintf("%s:%s", pr.HeadBranch, headFile),
+			Force:  true,
+			// Use InternalPushingEnvironment here because we know that pre-receive and post-receive do not run on a refs/pulls/...
+			Env: models.InternalPushingEnvironment(pr.Issue.Poster, pr.BaseRepo),
+		})
 	}); err != nil {
 		return fmt.Errorf("Push: %v", err)
 	}
 
+	// Remember the pushed head so later updates can be compared against it
+	if pr.HeadCommitID, err = headGitRepo.GetBranchCommitID(pr.HeadBranch); err != nil {
+		return fmt.Errorf("GetBranchCommitID: %v", err)
+	}
+	if err = pr.UpdateCols("head_commit_id"); err != nil {
+		return fmt.Errorf("UpdateCols: %v", err)
+	}
+	if _, err = pr.UpdateCachedCommitStatus(); err != nil {
+		log.Error("UpdateCachedCommitStatus[%d]: %v", pr.ID, err)
+	}
+
+	return nil
+}
+
+// retryPush runs push up to attempts times as long as it fails transiently, waiting backoff
+// before the first retry and twice as long before each further one.
+func retryPush(attempts int, backoff time.Duration, push func() error) (err error) {
+	for attempt := 1; ; attempt++ {
+		if err = push(); err == nil || attempt >= attempts || !isTransientPushError(err) {
+			return err
+		}
+		log.Trace("retryPush: attempt %d of %d failed, retrying in %v: %v", attempt, attempts, backoff, err)
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// isTransientPushError returns true if the push failed because git could not lock a reference
+// or the repository, which is worth retrying, rather than e.g. because it was rejected.
+func isTransientPushError(err error) bool {
+	msg := err.Error()
+	return strings.Contains(msg, "cannot lock ref") ||
+		strings.Contains(msg, "Unable to create") && strings.Contains(msg, ".lock'")
+}
+
+// CloseWithComment posts the comment and closes the pull request, notifying about the new
+// comment first and the closing afterwards. An already closed pull request only gets the comment.
+func CloseWithComment(pr *models.PullRequest, doer *models.User, content string) error {
+	comment, closeComment, err := pr.CloseWithComment(doer, content)
+	if err != nil {
+		return err
+	}
+
+	notification.NotifyCreateIssueComment(doer, pr.Issue.Repo, pr.Issue, comment)
+	if closeComment != nil {
+		RemoveFromTaskQueue(pr)
+		notification.NotifyIssueChangeStatus(doer, pr.Issue, closeComment, true)
+	}
+	return nil
+}
+
+// AutoCloseReasonStale is the reason of pull requests closed automatically for inactivity
+const AutoCloseReasonStale = "stale"
+
+// AutoClose closes the given open pull request on behalf of the ghost user because it had no
+// activity for the given number of days, posting a comment telling so first. Neither the comment
+// nor the closing is attributed to a real user, and the closing is notified as automated, so it
+// can be told apart from a manual close. Afterwards the head branch of the pull request is
+// deleted if deleteHeadBranch is set and nothing else depends on it.
+func AutoClose(pr *models.PullRequest, days int64, deleteHeadBranch bool) error {
+	if err := pr.LoadIssue(); err != nil {
+		return err
+	}
+	if pr.HasMerged || pr.Issue.IsClosed {
+		return nil
+	}
+	doer := models.NewGhostUser()
+
+	comment, closeComment, err := pr.CloseAsStale(doer, days)
+	if err != nil {
+		return err
+	}
+	pr.Issue.PullRequest = pr
+
+	notification.NotifyCreateIssueComment(doer, pr.Issue.Repo, pr.Issue, comment)
+	if closeComment == nil {
+		return nil
+	}
+	RemoveFromTaskQueue(pr)
+	notification.NotifyPullRequestAutoClosed(doer, pr, AutoCloseReasonStale)
+
+	if deleteHeadBranch {
+		if err := deleteClosedHeadBranch(pr, doer); err != nil {
+			log.Error("deleteClosedHeadBranch[%d]: %v", pr.ID, err)
+		}
+	}
+	return nil
+}
+
+// deleteClosedHeadBranch deletes the head branch of the closed pull request on behalf of doer.
+// Only branches of the base repository are deleted, and only if they are neither the default
+// nor a protected branch, have no commits beyond the pull request and no other open pull
+// request uses them. The head reference of the pull request is kept, so it can still be viewed.
+func deleteClosedHeadBranch(pr *models.PullRequest, doer *models.User) error {
+	if pr.HeadRepoID != pr.BaseRepoID {
+		return nil
+	}
+	if err := pr.GetBaseRepo(); err != nil {
+		return err
+	}
+	repo := pr.BaseRepo
+	if pr.HeadBranch == repo.DefaultBranch {
+		return nil
+	}
+	if protected, err := repo.IsProtectedBranch(pr.HeadBranch, doer); err != nil || protected {
+		return err
+	}
+
+	headPRs, err := models.GetUnmergedPullRequestsByHeadInfo(repo.ID, pr.HeadBranch)
+	if err != nil {
+		return err
+	}
+	baseCount, err := models.CountOpenPullRequestsByBaseBranch(repo.ID, pr.HeadBranch)
+	if err != nil {
+		return err
+	}
+	if len(headPRs) > 0 || baseCount > 0 {
+		return nil
+	}
+
+	gitRepo, err := git.OpenRepository(repo.RepoPath())
+	if err != nil {
+		return err
+	}
+	defer gitRepo.Close()
+
+	if !gitRepo.IsBranchExist(pr.HeadBranch) {
+		return nil
+	}
+	headCommitID, err := gitRepo.GetRefCommitID(pr.GetGitRefName())
+	if err != nil {
+		return err
+	}
+	branchCommitID, err := gitRepo.GetBranchCommitID(pr.HeadBranch)
+	if err != nil {
+		return err
+	}
+	if headCommitID != branchCommitID {
+		return nil
+	}
+
+	if err := repo_module.DeleteBranch(repo, gitRepo, pr.HeadBranch); err != nil {
+		return err
+	}
+
+	// Don't return error below this
+	if err := repo.AddDeletedBranch(pr.HeadBranch, branchCommitID, doer.ID); err != nil {
+		log.Warn("AddDeletedBranch: %v", err)
+	}
+	if err := models.AddDeletePRBranchComment(doer, repo, pr.IssueID, pr.HeadBranch); err != nil {
+		log.Error("AddDeletePRBranchComment: %v", err)
+	}
+	notification.NotifyDeleteRef(doer, repo, "branch", git.BranchPrefix+pr.HeadBranch)
 	return nil
 }
+
+// ChangeStatus closes or reopens the pull request of the given issue on behalf of doer.
+// Every change of the status of a pull request goes through here: a closed pull request
+// is removed from the test task queue and a reopened one is handed to Reopen.
+func ChangeStatus(issue *models.Issue, doer *models.User, isClosed bool) error {
+	if err := issue.LoadPullRequest(); err != nil {
+		return err
+	}
+	pr := issue.PullRequest
+	pr.Issue = issue
+
+	if !isClosed {
+		return Reopen(pr, doer)
+	}
+	if err := issue_service.ChangeStatus(issue, doer, true); err != nil {
+		return err
+	}
+	RemoveFromTaskQueue(pr)
+	return nil
+}
+
+// Reopen reopens the given closed pull request on behalf of doer. Its head is brought up to date
+// with the head branch and its conflicts are checked before the reopening is notified about, so
+// the notification carries the current head and mergeable status. If the head branch moved while
+// the pull request was closed, a synchronized notification follows the reopened one.
+func Reopen(pr *models.PullRequest, doer *models.User) error {
+	if err := pr.LoadIssue(); err != nil {
+		return err
+	}
+	if pr.HasMerged || !pr.Issue.IsClosed {
+		return nil
+	}
+	if err := pr.GetBaseRepo(); err != nil {
+		return err
+	}
+
+	// a missing head reference is recreated below, which counts as a synchronization
+	oldHeadCommitID, _ := getHeadCommitID(pr)
+
+	comment, err := pr.Issue.ChangeStatus(doer, false)
+	if err != nil {
+		return err
+	}
+	pr.Issue.PullRequest = pr
+
+	var synchronized bool
+	if err = pr.GetHeadRepo(); err != nil {
+		log.Error("GetHeadRepo[%d]: %v", pr.ID, err)
+	} else if err = PushToBaseRepo(pr); err != nil {
+		log.Error("PushToBaseRepo[%d]: %v", pr.ID, err)
+	} else if newHeadCommitID, err := getHeadCommitID(pr); err != nil {
+		log.Error("getHeadCommitID[%d]: %v", pr.ID, err)
+	} else {
+		synchronized = oldHeadCommitID != newHeadCommitID
+	}
+
+	// The reopened notification carries the status, so a test queued while the pull request
+	// was closed is superseded and no separate mergeable change is notified.
+	RemoveFromTaskQueue(pr)
+	pr.Status = models.PullRequestStatusChecking
+	if err = pr.UpdateCols("status"); err != nil {
+		log.Error("update pr [%d] status to PullRequestStatusChecking failed: %v", pr.ID, err)
+	}
+	checkPullRequest(pr)
+
+	notification.NotifyIssueChangeStatus(doer, pr.Issue, comment, false)
+	if synchronized {
+		notification.NotifyPullRequestSynchronized(doer, pr)
+	}
+	return nil
+}
+
+// getHeadCommitID returns the commit the head reference of the pull request points to in the base repository.
+func getHeadCommitID(pr *models.PullRequest) (string, error) {
+	gitRepo, err := git.OpenRepository(pr.BaseRepo.RepoPath())
+	if err != nil {
+		return "", err
+	}
+	defer gitRepo.Close()
+
+	return gitRepo.GetRefCommitID(pr.GetGitRefName())
+}
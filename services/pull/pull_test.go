@@ -5,4 +5,100 @@
 
 package pull
 
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"code.gitea.io/gitea/models"
+	"code.gitea.io/gitea/modules/git"
+
+	"github.com/stretchr/testify/assert"
+)
+
 // TODO TestPullRequest_PushToBaseRepo
+
+func TestAutoClose(t *testing.T) {
+	models.PrepareTestEnv(t)
+
+	pr := models.AssertExistsAndLoadBean(t, &models.PullRequest{ID: 2}).(*models.PullRequest)
+	assert.NoError(t, AutoClose(pr, 90, true))
+
+	issue := models.AssertExistsAndLoadBean(t, &models.Issue{ID: pr.IssueID}).(*models.Issue)
+	assert.True(t, issue.IsClosed)
+	// the reason is posted by the ghost user before the pull request is closed
+	comment := models.AssertExistsAndLoadBean(t, &models.Comment{IssueID: issue.ID, Type: models.CommentTypeCloseStale}).(*models.Comment)
+	assert.Equal(t, "90", comment.Content)
+	assert.EqualValues(t, -1, comment.PosterID)
+	assert.NoError(t, comment.LoadPoster())
+	assert.Equal(t, "Ghost", comment.Poster.Name)
+	closeComment := models.AssertExistsAndLoadBean(t, &models.Comment{IssueID: issue.ID, Type: models.CommentTypeClose}).(*models.Comment)
+	assert.EqualValues(t, -1, closeComment.PosterID)
+	assert.True(t, comment.ID < closeComment.ID)
+
+	// the head branch has commits beyond the pull request and is kept
+	repo := models.AssertExistsAndLoadBean(t, &models.Repository{ID: pr.BaseRepoID}).(*models.Repository)
+	gitRepo, err := git.OpenRepository(repo.RepoPath())
+	assert.NoError(t, err)
+	defer gitRepo.Close()
+	assert.True(t, gitRepo.IsBranchExist(pr.HeadBranch))
+	models.AssertNotExistsBean(t, &models.Comment{IssueID: issue.ID, Type: models.CommentTypeDeleteBranch})
+
+	// already closed pull requests are left alone
+	pr = models.AssertExistsAndLoadBean(t, &models.PullRequest{ID: 2}).(*models.PullRequest)
+	assert.NoError(t, AutoClose(pr, 30, true))
+	models.AssertNotExistsBean(t, &models.Comment{IssueID: issue.ID, Content: "30"})
+}
+
+func TestAutoClose_DeletesHeadBranch(t *testing.T) {
+	models.PrepareTestEnv(t)
+
+	pr := models.AssertExistsAndLoadBean(t, &models.PullRequest{ID: 2}).(*models.PullRequest)
+	repo := models.AssertExistsAndLoadBean(t, &models.Repository{ID: pr.BaseRepoID}).(*models.Repository)
+	gitRepo, err := git.OpenRepository(repo.RepoPath())
+	assert.NoError(t, err)
+	defer gitRepo.Close()
+
+	// let the head branch end at the head of the pull request
+	branchCommitID, err := gitRepo.GetBranchCommitID(pr.HeadBranch)
+	assert.NoError(t, err)
+	_, err = git.NewCommand("update-ref", pr.GetGitRefName(), branchCommitID).RunInDir(repo.RepoPath())
+	assert.NoError(t, err)
+
+	assert.NoError(t, AutoClose(pr, 90, true))
+	assert.False(t, gitRepo.IsBranchExist(pr.HeadBranch))
+	models.AssertExistsAndLoadBean(t, &models.Comment{IssueID: pr.IssueID, Type: models.CommentTypeDeleteBranch, CommitSHA: pr.HeadBranch})
+	models.AssertExistsAndLoadBean(t, &models.DeletedBranch{RepoID: repo.ID, Name: pr.HeadBranch, Commit: branchCommitID})
+}
+
+func TestRetryPush(t *testing.T) {
+	transient := errors.New("error: cannot lock ref 'refs/pull/2/head': Unable to create '/repo.git/refs/pull/2/head.lock': File exists")
+	rejected := errors.New("! [rejected] branch2 -> refs/pull/2/head (non-fast-forward)")
+
+	// a transient failure is retried until the push succeeds
+	var calls int
+	assert.NoError(t, retryPush(3, time.Millisecond, func() error {
+		calls++
+		if calls == 1 {
+			return transient
+		}
+		return nil
+	}))
+	assert.Equal(t, 2, calls)
+
+	// other failures are returned immediately
+	calls = 0
+	assert.Equal(t, rejected, retryPush(3, time.Millisecond, func() error {
+		calls++
+		return rejected
+	}))
+	assert.Equal(t, 1, calls)
+
+	// the number of attempts is bounded
+	calls = 0
+	assert.Equal(t, transient, retryPush(3, time.Millisecond, func() error {
+		calls++
+		return transient
+	}))
+	assert.Equal(t, 3, calls)
+}
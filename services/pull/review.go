@@ -12,6 +12,7 @@ import (
 
 	"code.gitea.io/gitea/models"
 	"code.gitea.io/gitea/modules/git"
+	"code.gitea.io/gitea/modules/log"
 	"code.gitea.io/gitea/modules/notification"
 	"code.gitea.io/gitea/modules/setting"
 	"code.gitea.io/gitea/services/gitdiff"
@@ -185,6 +186,8 @@ func SubmitReview(doer *models.User, gitRepo *git.Repository, issue *models.Issu
 		}
 	}
 
+	approvalsSatisfied := hasEnoughApprovals(pr)
+
 	review, comm, err := models.SubmitReview(doer, issue, reviewType, content, commitID, stale)
 	if err != nil {
 		return nil, nil, err
@@ -192,5 +195,22 @@ func SubmitReview(doer *models.User, gitRepo *git.Repository, issue *models.Issu
 
 	notification.NotifyPullRequestReview(pr, review, comm)
 
+	if reviewType == models.ReviewTypeApprove && !approvalsSatisfied && hasEnoughApprovals(pr) {
+		notification.NotifyPullRequestApprovalsSatisfied(pr)
+	}
+
 	return review, comm, nil
 }
+
+// hasEnoughApprovals returns if the approvals required by the protected base branch of pr
+// are granted. It is false if the base branch does not require approvals.
+func hasEnoughApprovals(pr *models.PullRequest) bool {
+	if err := pr.LoadProtectedBranch(); err != nil {
+		log.Error("LoadProtectedBranch[%d]: %v", pr.ID, err)
+		return false
+	}
+	if pr.ProtectedBranch == nil || pr.ProtectedBranch.RequiredApprovals == 0 {
+		return false
+	}
+	return pr.ProtectedBranch.HasEnoughApprovals(pr)
+}
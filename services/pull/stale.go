@@ -0,0 +1,73 @@
+// Copyright 2020 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package pull
+
+import (
+	"context"
+	"time"
+
+	"code.gitea.io/gitea/models"
+	"code.gitea.io/gitea/modules/log"
+	"code.gitea.io/gitea/modules/setting"
+)
+
+// CloseStalePullRequests closes all open pull requests which have not been updated
+// for longer than the configured duration, skipping repositories that opted out,
+// and cleans up their head branches if configured.
+func CloseStalePullRequests(ctx context.Context) {
+	log.Trace("Doing: CloseStalePullRequests")
+
+	olderThan := time.Now().Add(-setting.Cron.CloseStalePullRequests.OlderThan)
+	repoIDs, err := models.GetStalePullRequestBaseRepoIDs(olderThan)
+	if err != nil {
+		log.Error("GetStalePullRequestBaseRepoIDs: %v", err)
+		return
+	}
+
+	for _, repoID := range repoIDs {
+		select {
+		case <-ctx.Done():
+			log.Warn("CloseStalePullRequests: Aborting due to shutdown")
+			return
+		default:
+		}
+
+		if err := closeStalePullRequestsOfRepo(repoID, olderThan); err != nil {
+			log.Error("CloseStalePullRequests [repo_id: %d]: %v", repoID, err)
+		}
+	}
+	log.Trace("Finished: CloseStalePullRequests")
+}
+
+func closeStalePullRequestsOfRepo(repoID int64, olderThan time.Time) error {
+	repo, err := models.GetRepositoryByID(repoID)
+	if err != nil {
+		return err
+	}
+
+	unit, err := repo.GetUnit(models.UnitTypePullRequests)
+	if err != nil {
+		if models.IsErrUnitTypeNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if unit.PullRequestsConfig().DisableStaleAutoClose {
+		return nil
+	}
+
+	prs, err := models.GetStalePullRequests(repoID, olderThan)
+	if err != nil {
+		return err
+	}
+	days := int64(setting.Cron.CloseStalePullRequests.OlderThan.Hours() / 24)
+	for _, pr := range prs {
+		pr.BaseRepo = repo
+		if err := AutoClose(pr, days, setting.Cron.CloseStalePullRequests.DeleteHeadBranches); err != nil {
+			log.Error("AutoClose [pr_id: %d]: %v", pr.ID, err)
+		}
+	}
+	return nil
+}
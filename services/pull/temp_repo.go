@@ -16,6 +16,11 @@ import (
 	"code.gitea.io/gitea/modules/log"
 )
 
+// createTemporaryRepo creates a temporary clone of the base repository with the
+// base branch as "base" and the head branch as "tracking". The patches tested and
+// served for a pull request are always generated afresh from these two branches,
+// so no patch is stored which could go missing. If the head or base repository
+// no longer exists an ErrRepoNotExist is returned.
 func createTemporaryRepo(pr *models.PullRequest) (string, error) {
 	if err := pr.GetHeadRepo(); err != nil {
 		log.Error("GetHeadRepo: %v", err)
@@ -6,12 +6,16 @@ package pull
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	"code.gitea.io/gitea/models"
 	"code.gitea.io/gitea/modules/git"
 	"code.gitea.io/gitea/modules/log"
+	"code.gitea.io/gitea/modules/setting"
 )
 
 // Update updates pull request with base branch.
@@ -43,7 +47,144 @@ func Update(pull *models.PullRequest, doer *models.User, message string) error {
 		go AddTestPullRequestTask(doer, pr.HeadRepo.ID, pr.HeadBranch, false, "", "")
 	}()
 
-	return rawMerge(pr, doer, models.MergeStyleMerge, message)
+	return rawMerge(pr, doer, models.MergeStyleMerge, message, nil, nil)
+}
+
+// UpdateHeadFromBase updates the head branch of the pull request with the current
+// tip of its base branch, either by merging the base branch into it or by
+// rebasing the head commits on top of it.
+func UpdateHeadFromBase(pull *models.PullRequest, doer *models.User, style models.MergeStyle, message string) error {
+	switch style {
+	case models.MergeStyleMerge:
+		return Update(pull, doer, message)
+	case models.MergeStyleRebase:
+	default:
+		return models.ErrInvalidMergeStyle{ID: pull.BaseRepoID, Style: style}
+	}
+
+	if err := pull.LoadHeadRepo(); err != nil {
+		log.Error("LoadHeadRepo: %v", err)
+		return fmt.Errorf("LoadHeadRepo: %v", err)
+	} else if err = pull.LoadBaseRepo(); err != nil {
+		log.Error("LoadBaseRepo: %v", err)
+		return fmt.Errorf("LoadBaseRepo: %v", err)
+	}
+
+	diffCount, err := GetDiverging(pull)
+	if err != nil {
+		return err
+	} else if diffCount.Behind == 0 {
+		return fmt.Errorf("HeadBranch of PR %d is up to date", pull.Index)
+	}
+
+	defer func() {
+		go AddTestPullRequestTask(doer, pull.HeadRepo.ID, pull.HeadBranch, false, "", "")
+	}()
+
+	return rebaseHeadOntoBase(pull, doer)
+}
+
+// rebaseHeadOntoBase rebases the head branch of the pull request onto its base
+// branch and force pushes the result back to the head repository
+func rebaseHeadOntoBase(pr *models.PullRequest, doer *models.User) error {
+	tmpBasePath, err := createTemporaryRepo(pr)
+	if err != nil {
+		log.Error("CreateTemporaryPath: %v", err)
+		return err
+	}
+	defer func() {
+		if err := models.RemoveTemporaryPath(tmpBasePath); err != nil {
+			log.Error("Update: RemoveTemporaryPath: %s", err)
+		}
+	}()
+
+	baseBranch := "base"
+	trackingBranch := "tracking"
+	stagingBranch := "staging"
+
+	headCommitID, err := git.GetFullCommitID(tmpBasePath, trackingBranch)
+	if err != nil {
+		return fmt.Errorf("Failed to get full commit id for %s: %v", trackingBranch, err)
+	}
+
+	sig := doer.NewGitSig()
+	commitTimeStr := time.Now().Format(time.RFC3339)
+	env := append(os.Environ(),
+		"GIT_COMMITTER_NAME="+sig.Name,
+		"GIT_COMMITTER_EMAIL="+sig.Email,
+		"GIT_COMMITTER_DATE="+commitTimeStr,
+	)
+
+	var outbuf, errbuf strings.Builder
+	if err := git.NewCommand("checkout", "-b", stagingBranch, trackingBranch).RunInDirPipeline(tmpBasePath, &outbuf, &errbuf); err != nil {
+		log.Error("git checkout staging [%s:%s]: %v\n%s\n%s", pr.HeadRepo.FullName(), pr.HeadBranch, err, outbuf.String(), errbuf.String())
+		return fmt.Errorf("git checkout staging [%s:%s]: %v\n%s\n%s", pr.HeadRepo.FullName(), pr.HeadBranch, err, outbuf.String(), errbuf.String())
+	}
+	outbuf.Reset()
+	errbuf.Reset()
+
+	if err := git.NewCommand("rebase", baseBranch).RunInDirTimeoutEnvPipeline(env, -1, tmpBasePath, &outbuf, &errbuf); err != nil {
+		// Rebase will leave a REBASE_HEAD file in .git if there is a conflict
+		if _, statErr := os.Stat(filepath.Join(tmpBasePath, ".git", "REBASE_HEAD")); statErr == nil {
+			conflictErr := models.ErrMergeConflicts{
+				Style:           models.MergeStyleRebase,
+				ConflictedFiles: getConflictedFiles(tmpBasePath),
+				StdOut:          outbuf.String(),
+				StdErr:          errbuf.String(),
+				Err:             err,
+			}
+			if _, abortErr := git.NewCommand("rebase", "--abort").RunInDir(tmpBasePath); abortErr != nil {
+				log.Error("git rebase --abort in %s: %v", tmpBasePath, abortErr)
+			}
+			log.Debug("RebaseConflict [%s:%s onto %s:%s]: %v\n%s\n%s", pr.HeadRepo.FullName(), pr.HeadBranch, pr.BaseRepo.FullName(), pr.BaseBranch, err, conflictErr.StdOut, conflictErr.StdErr)
+			return conflictErr
+		}
+		log.Error("git rebase staging on to base [%s:%s onto %s:%s]: %v\n%s\n%s", pr.HeadRepo.FullName(), pr.HeadBranch, pr.BaseRepo.FullName(), pr.BaseBranch, err, outbuf.String(), errbuf.String())
+		return fmt.Errorf("git rebase staging on to base [%s:%s onto %s:%s]: %v\n%s\n%s", pr.HeadRepo.FullName(), pr.HeadBranch, pr.BaseRepo.FullName(), pr.BaseBranch, err, outbuf.String(), errbuf.String())
+	}
+	outbuf.Reset()
+	errbuf.Reset()
+
+	if setting.LFS.StartServer {
+		stagingCommitID, err := git.GetFullCommitID(tmpBasePath, stagingBranch)
+		if err != nil {
+			return fmt.Errorf("Failed to get full commit id for %s: %v", stagingBranch, err)
+		}
+		// LFSPush pushes into the base repository of the given pull request, so swap it around
+		headPR := &models.PullRequest{
+			Index:      pr.Index,
+			HeadRepoID: pr.BaseRepoID,
+			HeadRepo:   pr.BaseRepo,
+			BaseRepoID: pr.HeadRepoID,
+			BaseRepo:   pr.HeadRepo,
+		}
+		if err := LFSPush(tmpBasePath, stagingCommitID, headCommitID, headPR); err != nil {
+			return err
+		}
+	}
+
+	pushEnv := models.FullPushingEnvironment(
+		doer,
+		doer,
+		pr.HeadRepo,
+		pr.HeadRepo.Name,
+		pr.ID,
+	)
+
+	// Push back to the head repository, refusing to clobber commits pushed in the meantime
+	if err := git.NewCommand("push", "--force-with-lease="+pr.HeadBranch+":"+headCommitID, "head_repo", stagingBranch+":"+git.BranchPrefix+pr.HeadBranch).RunInDirTimeoutEnvPipeline(pushEnv, -1, tmpBasePath, &outbuf, &errbuf); err != nil {
+		if strings.Contains(errbuf.String(), "stale info") || strings.Contains(errbuf.String(), "non-fast-forward") {
+			return models.ErrMergePushOutOfDate{
+				Style:  models.MergeStyleRebase,
+				StdOut: outbuf.String(),
+				StdErr: errbuf.String(),
+				Err:    err,
+			}
+		}
+		return fmt.Errorf("git push: %s", errbuf.String())
+	}
+
+	return nil
 }
 
 // IsUserAllowedToUpdate check if user is allowed to update PR with given permissions and branch protections